@@ -8,6 +8,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type affiliatesRepository struct {
@@ -42,3 +43,69 @@ func (repository *affiliatesRepository) GetAffiliateByUserId(ctx context.Context
 
 	return res, nil
 }
+
+func (repository *affiliatesRepository) GetAffiliates(ctx context.Context, filter affiliate.AffiliateFilter) (res []affiliate.AffiliateEntity, total int64, err error) {
+	query := bson.M{}
+	if filter.Status != 0 {
+		query["status"] = filter.Status
+	}
+
+	if filter.ProvinceID != 0 {
+		query["province_id"] = filter.ProvinceID
+	}
+
+	if filter.CityID != 0 {
+		query["city_id"] = filter.CityID
+	}
+
+	total, err = repository.affiliatesCollection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((filter.Page - 1) * filter.Size)).
+		SetLimit(int64(filter.Size))
+
+	result, err := repository.affiliatesCollection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	res = []affiliate.AffiliateEntity{}
+	err = result.All(ctx, &res)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return res, total, nil
+}
+
+func (repository *affiliatesRepository) UpdateAffiliateStatus(ctx context.Context, userID string, fromStatus int, toStatus int, rejectionReason *string, now int64) (updated bool, err error) {
+	filter := bson.M{
+		"user_id": userID,
+		"status":  fromStatus,
+	}
+
+	update := bson.M{"$set": bson.M{
+		"status":           toStatus,
+		"rejection_reason": rejectionReason,
+		"reviewed_at":      now,
+		"updated_at":       now,
+	}}
+
+	result, err := repository.affiliatesCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (repository *affiliatesRepository) DeleteAffiliate(ctx context.Context, userID string) (err error) {
+	filter := bson.M{"user_id": userID}
+
+	_, err = repository.affiliatesCollection.DeleteOne(ctx, filter)
+	return err
+}
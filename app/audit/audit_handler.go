@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"mini-wallet/domain"
+	"mini-wallet/domain/audit"
+	_auth "mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/schema"
+)
+
+type auditHandler struct {
+	auditUsecase audit.AuditUsecase
+	decoder      *schema.Decoder
+}
+
+func SetAuditHandler(router *chi.Mux, usecases domain.Usecases, middleware _auth.AuthMiddleware) {
+	auditHandler := auditHandler{
+		auditUsecase: usecases.AuditUsecase,
+		decoder:      schema.NewDecoder(),
+	}
+
+	router.Route("/admin/audit-events", func(r chi.Router) {
+		r.Use(middleware.AllowApiKey)
+		r.Use(middleware.AuthMiddleware)
+		r.Use(middleware.RequirePermission(_auth.PERMISSION_AUDIT_READ))
+		r.Get("/", auditHandler.GetAuditEvents)
+	})
+}
+
+func (handler *auditHandler) GetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	var params audit.GetAuditEventsRequest
+	if err := handler.decoder.Decode(&params, r.URL.Query()); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := params.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	res := handler.auditUsecase.GetAuditEvents(r.Context(), params)
+	res.Writer = w
+	res.WriteResponse()
+}
@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"context"
+	"mini-wallet/domain"
+	"mini-wallet/domain/audit"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type auditRepository struct {
+	auditCollection *mongo.Collection
+}
+
+func NewAuditRepository(repositoryParam domain.RepositoryParam) audit.AuditRepository {
+	return &auditRepository{
+		auditCollection: repositoryParam.Mongo.Collection("audit_event"),
+	}
+}
+
+func (repository *auditRepository) InsertEvent(ctx context.Context, entity audit.AuditEventEntity) (err error) {
+	_, err = repository.auditCollection.InsertOne(ctx, entity)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *auditRepository) GetEvents(ctx context.Context, req audit.GetAuditEventsRequest) (res []audit.AuditEventEntity, total int64, err error) {
+	filter := bson.M{}
+	if req.UserID != "" {
+		filter["user_id"] = req.UserID
+	}
+
+	if req.Type != "" {
+		filter["type"] = req.Type
+	}
+
+	if req.Outcome != "" {
+		filter["outcome"] = req.Outcome
+	}
+
+	createdAt := bson.M{}
+	if req.From != 0 {
+		createdAt["$gte"] = req.From
+	}
+
+	if req.To != 0 {
+		createdAt["$lte"] = req.To
+	}
+
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	total, err = repository.auditCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((req.Page - 1) * req.Size)).
+		SetLimit(int64(req.Size))
+
+	result, err := repository.auditCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	res = []audit.AuditEventEntity{}
+	err = result.All(ctx, &res)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return res, total, nil
+}
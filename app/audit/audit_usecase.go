@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"context"
+	"mini-wallet/domain"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/common/response"
+)
+
+type auditUsecase struct {
+	auditRepository audit.AuditRepository
+}
+
+func NewAuditUsecase(repositories domain.Repositories) audit.AuditUsecase {
+	return &auditUsecase{
+		auditRepository: repositories.AuditRepository,
+	}
+}
+
+func (usecase *auditUsecase) GetAuditEvents(ctx context.Context, req audit.GetAuditEventsRequest) (res response.Response[audit.AuditEventPageDTO]) {
+	events, total, err := usecase.auditRepository.GetEvents(ctx, req)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	page := audit.AuditEventPageDTO{
+		Events: []audit.AuditEventDTO{},
+		Total:  total,
+		Page:   req.Page,
+		Size:   req.Size,
+	}
+	for _, event := range events {
+		page.Events = append(page.Events, event.ToAuditEventDTO())
+	}
+
+	res.Success(page)
+	return
+}
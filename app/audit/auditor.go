@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mini-wallet/domain"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/utils"
+	"net/http"
+)
+
+// Auditor writes the security audit log, it is shared by the usecases that record
+// events. A failed write is printed and never fails the request it describes.
+type Auditor struct {
+	auditRepository audit.AuditRepository
+}
+
+func NewAuditor(repositories domain.Repositories) *Auditor {
+	return &Auditor{
+		auditRepository: repositories.AuditRepository,
+	}
+}
+
+// NewAuditEvent starts an event of the request, the caller fills in the user once
+// known. On authenticated routes the signed in user is the actor and, unless the
+// caller says otherwise, the user, except while impersonating.
+func NewAuditEvent(ctx context.Context, eventType string, method string) *audit.AuditEventEntity {
+	clientInfo := auth.GetClientInfo(ctx)
+	sessionID, _ := ctx.Value(auth.SessionIDContext{}).(string)
+
+	event := &audit.AuditEventEntity{
+		Type:      eventType,
+		Method:    method,
+		SessionID: sessionID,
+		IPAddress: clientInfo.IPAddress,
+		UserAgent: clientInfo.UserAgent,
+	}
+
+	if userID, ok := ctx.Value(auth.UserIDContext{}).(*string); ok && userID != nil {
+		event.ActorID = *userID
+		event.UserID = *userID
+	}
+
+	// while impersonating the staff member acts on the user
+	if actorID := auth.GetImpersonatorID(ctx); actorID != "" {
+		event.ActorID = actorID
+	}
+
+	return event
+}
+
+func (auditor *Auditor) Record(ctx context.Context, event *audit.AuditEventEntity) {
+	now, err := utils.GetJktTime()
+	if err != nil {
+		fmt.Println("error recording audit event:", err.Error())
+		return
+	}
+
+	event.ID = utils.GenerateUniqueId()
+	event.CreatedAt = now.Unix()
+
+	err = auditor.auditRepository.InsertEvent(context.WithoutCancel(ctx), *event)
+	if err != nil {
+		fmt.Println("error recording audit event:", event.Type, err.Error())
+	}
+}
+
+// RecordError records the outcome of the helpers that return an error rather
+// than a response, the session manager's
+func (auditor *Auditor) RecordError(ctx context.Context, event *audit.AuditEventEntity, err error) {
+	event.Outcome = audit.OUTCOME_SUCCESS
+	event.StatusCode = http.StatusOK
+	if err != nil {
+		event.Outcome = audit.OUTCOME_FAILURE
+		event.Reason = err.Error()
+		event.StatusCode = http.StatusUnauthorized
+		if !errors.Is(err, auth.ErrInvalidRefreshToken) && !errors.Is(err, auth.ErrSessionRevoked) && !errors.Is(err, auth.ErrRefreshTokenReused) {
+			event.StatusCode = http.StatusInternalServerError
+		}
+	}
+
+	auditor.Record(ctx, event)
+}
+
+// RecordOutcome is deferred by the usecases, the outcome is taken from the
+// response: 2xx is a success, a 2FA challenge is challenged, anything else a failure
+func RecordOutcome[T any](auditor *Auditor, ctx context.Context, event *audit.AuditEventEntity, res *response.Response[T]) {
+	event.StatusCode = res.StatusCode
+	event.Outcome = audit.OUTCOME_FAILURE
+	if res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices {
+		event.Outcome = audit.OUTCOME_SUCCESS
+	}
+
+	if authenticationResponse, ok := any(res.Data).(*auth.AuthenticationResponse); ok && authenticationResponse != nil && authenticationResponse.TwoFactorRequired {
+		event.Outcome = audit.OUTCOME_CHALLENGED
+	}
+
+	if event.Outcome == audit.OUTCOME_FAILURE && res.Message != nil {
+		event.Reason = *res.Message
+	}
+
+	auditor.Record(ctx, event)
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	_auth "mini-wallet/domain/auth"
+	"mini-wallet/domain/serviceaccount"
+	"mini-wallet/utils"
+	"net/http"
+	"strings"
+)
+
+// apiKeyPrincipal is who a request made with an API key acts as
+type apiKeyPrincipal struct {
+	userID           string
+	serviceAccountID string
+	roles            []string
+	scopes           []string
+}
+
+// apiKeyAllowedContext is set by AllowApiKey on the routes API keys may call
+type apiKeyAllowedContext struct {
+}
+
+// AllowApiKey lets AuthMiddleware accept API keys. A key acts as the owner of its
+// business, so keys are refused unless the route checks their scopes.
+func (middleware *authMiddleware) AllowApiKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), apiKeyAllowedContext{}, true)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// getBearerApiKey returns the API key of the Authorization header, other bearer
+// tokens are not API keys
+func getBearerApiKey(r *http.Request) (string, bool) {
+	token, found := getBearerToken(r)
+	if !found || !strings.HasPrefix(token, serviceaccount.API_KEY_PREFIX) {
+		return "", false
+	}
+
+	return token, true
+}
+
+// authenticateApiKey fills the context like authenticate does for cookies. There
+// is no session nor refresh, an invalid key is 401 unless the route is optional.
+// Routes without AllowApiKey answer 403 to any key.
+func (middleware *authMiddleware) authenticateApiKey(w http.ResponseWriter, r *http.Request, next http.Handler, apiKey string, optional bool) {
+	if allowed, _ := r.Context().Value(apiKeyAllowedContext{}).(bool); !allowed {
+		http.Error(w, "ApiKeyNotAllowed", http.StatusForbidden)
+		return
+	}
+
+	principal, err := middleware.getApiKeyPrincipal(r.Context(), apiKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if principal == nil && !optional {
+		http.Error(w, "InvalidApiKey", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	if principal == nil {
+		ctx = context.WithValue(ctx, _auth.UserIDContext{}, (*string)(nil))
+		ctx = context.WithValue(ctx, _auth.SessionIDContext{}, "")
+		ctx = context.WithValue(ctx, _auth.RolesContext{}, []string(nil))
+		ctx = context.WithValue(ctx, _auth.TokenStatus{}, _auth.ERROR_INVALID_TOKEN)
+	} else {
+		ctx = context.WithValue(ctx, _auth.UserIDContext{}, &principal.userID)
+		ctx = context.WithValue(ctx, _auth.SessionIDContext{}, "")
+		ctx = context.WithValue(ctx, _auth.RolesContext{}, principal.roles)
+		ctx = context.WithValue(ctx, _auth.ScopesContext{}, principal.scopes)
+		ctx = context.WithValue(ctx, _auth.ServiceAccountContext{}, principal.serviceAccountID)
+		ctx = context.WithValue(ctx, _auth.TokenStatus{}, 0)
+	}
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// getApiKeyPrincipal returns nil for unknown, revoked and expired keys. A key of
+// a business acts as the current owner of the business with the owner's current
+// roles, its scopes only narrow them down.
+func (middleware *authMiddleware) getApiKeyPrincipal(ctx context.Context, key string) (*apiKeyPrincipal, error) {
+	id, _, ok := serviceaccount.ParseApiKey(key)
+	if !ok {
+		return nil, nil
+	}
+
+	apiKey, err := middleware.serviceAccountRepository.GetApiKeyByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey == nil || subtle.ConstantTimeCompare([]byte(utils.HashToken(key)), []byte(apiKey.KeyHash)) != 1 {
+		return nil, nil
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return nil, err
+	}
+
+	if !apiKey.IsActive(now.Unix()) {
+		return nil, nil
+	}
+
+	serviceAccount, err := middleware.serviceAccountRepository.GetServiceAccountByID(ctx, apiKey.ServiceAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if serviceAccount == nil {
+		return nil, nil
+	}
+
+	principal := &apiKeyPrincipal{
+		userID:           serviceAccount.ID,
+		serviceAccountID: serviceAccount.ID,
+		roles:            serviceAccount.Roles,
+		scopes:           apiKey.Scopes,
+	}
+
+	if !serviceAccount.IsInternal() {
+		businessEntity, err := middleware.businessRepository.GetBusinessById(ctx, *serviceAccount.BusinessID)
+		if err != nil {
+			return nil, err
+		}
+
+		if businessEntity == nil {
+			return nil, nil
+		}
+
+		owner, err := middleware.userRepository.GetUserByUserID(ctx, businessEntity.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		if owner == nil {
+			return nil, nil
+		}
+
+		principal.userID = owner.UID
+		principal.roles = _auth.GetUserRoles(*owner)
+	}
+
+	if apiKey.LastUsedAt == nil || now.Unix()-*apiKey.LastUsedAt > serviceaccount.API_KEY_LAST_USED_INTERVAL {
+		// usage tracking must not fail the request
+		err = middleware.serviceAccountRepository.TouchApiKey(ctx, apiKey.ID, _auth.GetClientInfo(ctx).IPAddress, now.Unix())
+		if err != nil {
+			fmt.Println("error tracking api key usage:", err.Error())
+		}
+	}
+
+	return principal, nil
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"mini-wallet/domain"
+	_auth "mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	authpb "mini-wallet/infrastructure/proto/generated/auth"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authGrpcServer lets internal services validate tokens without copying
+// ValidateToken. Callers are authenticated by GrpcAuthInterceptor.
+type authGrpcServer struct {
+	authpb.UnimplementedAuthServiceServer
+	authUsecase _auth.AuthUsecase
+}
+
+func SetAuthGrpcServer(server *grpc.Server, usecases domain.Usecases) {
+	authpb.RegisterAuthServiceServer(server, &authGrpcServer{
+		authUsecase: usecases.AuthUsecase,
+	})
+}
+
+// GrpcAuthInterceptor only lets through the calls carrying the shared service
+// token, "authorization: Bearer <token>". GetUser and CheckPermission tell about
+// any user, so the port alone is never trusted.
+func GrpcAuthInterceptor(token string) grpc.UnaryServerInterceptor {
+	expected := []byte("Bearer " + token)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) != 1 || subtle.ConstantTimeCompare([]byte(values[0]), expected) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid service token")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (server *authGrpcServer) ValidateToken(ctx context.Context, req *authpb.TokenRequest) (*authpb.TokenInfo, error) {
+	tokenInfo, err := server.IntrospectToken(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !tokenInfo.Active {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	return tokenInfo, nil
+}
+
+func (server *authGrpcServer) IntrospectToken(ctx context.Context, req *authpb.TokenRequest) (*authpb.TokenInfo, error) {
+	res := server.authUsecase.IntrospectToken(ctx, req.GetToken())
+	if res.StatusCode != http.StatusOK {
+		return nil, grpcError(res)
+	}
+
+	return &authpb.TokenInfo{
+		Active:    res.Data.Active,
+		UserId:    res.Data.Subject,
+		SessionId: res.Data.SessionID,
+		TokenId:   res.Data.TokenID,
+		Roles:     res.Data.Roles,
+		Scope:     res.Data.Scope,
+		ClientId:  res.Data.ClientID,
+		IssuedAt:  res.Data.IssuedAt,
+		ExpiresAt: res.Data.ExpiresAt,
+	}, nil
+}
+
+func (server *authGrpcServer) GetUser(ctx context.Context, req *authpb.GetUserRequest) (*authpb.User, error) {
+	res := server.authUsecase.GetUserSummary(ctx, req.GetUserId())
+	if res.StatusCode != http.StatusOK {
+		return nil, grpcError(res)
+	}
+
+	return &authpb.User{
+		Id:                  res.Data.ID,
+		Name:                res.Data.Name,
+		Email:               res.Data.Email,
+		PhoneNumber:         res.Data.PhoneNumber,
+		EmailVerified:       res.Data.EmailVerified,
+		PhoneNumberVerified: res.Data.PhoneNumberVerified,
+		Roles:               res.Data.Roles,
+	}, nil
+}
+
+func (server *authGrpcServer) CheckPermission(ctx context.Context, req *authpb.CheckPermissionRequest) (*authpb.CheckPermissionResult, error) {
+	res := server.authUsecase.CheckPermission(ctx, req.GetUserId(), req.GetPermission())
+	if res.StatusCode != http.StatusOK {
+		return nil, grpcError(res)
+	}
+
+	return &authpb.CheckPermissionResult{
+		Allowed: res.Data.Allowed,
+	}, nil
+}
+
+// grpcError maps a failed usecase response to the closest gRPC status
+func grpcError[T any](res response.Response[T]) error {
+	message := ""
+	if res.Message != nil {
+		message = *res.Message
+	}
+
+	code := codes.Internal
+	switch res.StatusCode {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	}
+
+	return status.Error(code, message)
+}
@@ -1,27 +1,20 @@
 package auth
 
 import (
-	"context"
 	"log"
 	"mini-wallet/domain"
 	_auth "mini-wallet/domain/auth"
 	"mini-wallet/domain/common/response"
 	"mini-wallet/utils"
 	"net/http"
-	"time"
 
-	firebaseAdmin "firebase.google.com/go/v4"
-	"firebase.google.com/go/v4/auth"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
-	"github.com/go-playground/validator"
-	"google.golang.org/api/option"
 )
 
 type authHandler struct {
-	authUsecase         _auth.AuthUsecase
-	firebaseAdminClient *auth.Client
-	config              *utils.AppConfig
+	authUsecase    _auth.AuthUsecase
+	tokenTransport *tokenTransport
 }
 
 func SetAuthHandler(
@@ -30,35 +23,27 @@ func SetAuthHandler(
 	middleware _auth.AuthMiddleware,
 	config *utils.AppConfig,
 ) {
-	app, err := firebaseAdmin.NewApp(context.Background(), nil, option.WithCredentialsFile(
-		config.GoogleCredentialsPath,
-	))
+	tokenTransport, err := newTokenTransport(config)
 	if err != nil {
-		log.Fatalf("error initializing app: %v\n", err)
-		return
-	}
-
-	client, err := app.Auth(context.Background())
-	if err != nil {
-		log.Fatalf("error getting Auth client: %v\n", err)
-		return
+		log.Fatalf("error initializing token transport: %v\n", err)
 	}
 
 	authHandler := authHandler{
-		authUsecase:         usecases.AuthUsecase,
-		firebaseAdminClient: client,
-		config:              config,
+		authUsecase:    usecases.AuthUsecase,
+		tokenTransport: tokenTransport,
 	}
 
+	router.Get("/.well-known/jwks.json", authHandler.GetJWKS)
+
 	router.Route("/auth/verify", func(r chi.Router) {
 		r.Use(middleware.AuthMiddleware)
 		r.Get("/", authHandler.VerifyAccessToken)
 	})
 
 	router.Route("/auth/", func(r chi.Router) {
-		// google handler
-		r.Post("/register/google", authHandler.RegisterWithGoogle)
-		r.Post("/login/google", authHandler.AuthenticateByGoogle)
+		// external identity providers (google, apple, facebook)
+		r.Post("/register/{provider}", authHandler.RegisterWithProvider)
+		r.Post("/login/{provider}", authHandler.AuthenticateWithProvider)
 
 		// from inquiry
 		r.Post("/register/inquiry", authHandler.RegisterUserFromInquiry)
@@ -67,10 +52,19 @@ func SetAuthHandler(
 		// pre authenticated
 		r.Post("/check-identifier", authHandler.CheckIndentifier)
 		r.Post("/login", authHandler.AuthenticateRegularUser)
+		r.Post("/login/2fa", authHandler.AuthenticateTwoFactor)
+		r.Post("/login/passkey/begin", authHandler.BeginPasskeyAuthentication)
+		r.Post("/login/passkey/finish", authHandler.FinishPasskeyAuthentication)
+		r.Post("/login/whatsapp", authHandler.RequestWhatsAppCode)
+		r.Post("/login/whatsapp/verify", authHandler.AuthenticateWithWhatsAppCode)
+		r.Post("/login/email", authHandler.RequestMagicLink)
+		r.Post("/login/email/verify", authHandler.AuthenticateWithMagicLink)
+		r.Post("/login/report", authHandler.ReportUnrecognizedLogin)
 		r.Post("/register", authHandler.RegisterUser)
+		r.Post("/register/resend", authHandler.ResendVerification)
 
-		// authenticated
-		r.Get("/logout", authHandler.Logout)
+		// authenticated, a POST so another site can not sign the user out past CsrfMiddleware
+		r.Post("/logout", authHandler.Logout)
 
 		// verifications
 		r.Post("/verify-reset-password-token", authHandler.VerifyResetPasswordToken)
@@ -82,12 +76,87 @@ func SetAuthHandler(
 
 	})
 
-	router.Route("/auth/refresh", func(r chi.Router) {
+	router.Route("/auth/2fa", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Use(middleware.DenyImpersonation)
+		r.Post("/enroll", authHandler.EnrollTwoFactor)
+		r.Post("/confirm", authHandler.ConfirmTwoFactor)
+		r.Post("/disable", authHandler.DisableTwoFactor)
+		r.Post("/recovery-codes", authHandler.RegenerateRecoveryCodes)
+	})
+
+	router.Route("/auth/passkeys", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Get("/", authHandler.GetPasskeys)
+		r.With(middleware.DenyImpersonation).Post("/register/begin", authHandler.BeginPasskeyRegistration)
+		r.With(middleware.DenyImpersonation).Post("/register/finish", authHandler.FinishPasskeyRegistration)
+		r.With(middleware.DenyImpersonation).Delete("/{passkeyId}", authHandler.DeletePasskey)
+	})
+
+	router.Route("/auth/identities", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Get("/", authHandler.GetLoginMethods)
+		r.With(middleware.DenyImpersonation).Post("/{provider}", authHandler.LinkIdentity)
+		r.With(middleware.DenyImpersonation).Delete("/{provider}", authHandler.UnlinkIdentity)
+	})
+
+	router.Route("/auth/password", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Use(middleware.DenyImpersonation)
+		r.Post("/", authHandler.SetPassword)
+		r.Post("/change", authHandler.ChangePassword)
+	})
+
+	router.Route("/auth/csrf", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Get("/", authHandler.GetCsrfToken)
+	})
+
+	router.Route("/auth/sessions", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Get("/", authHandler.GetSessions)
+		r.With(middleware.DenyImpersonation).Post("/revoke-others", authHandler.RevokeOtherSessions)
+		r.With(middleware.DenyImpersonation).Delete("/{sessionId}", authHandler.RevokeSession)
+	})
+
+	router.Route("/auth/devices", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Get("/", authHandler.GetKnownDevices)
+		r.With(middleware.DenyImpersonation).Delete("/{deviceId}", authHandler.DeleteKnownDevice)
+	})
+
+	// impersonation tokens are short-lived bearer tokens, they can not start another one
+	router.Route("/admin/users/{userId}/impersonation", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Use(middleware.DenyImpersonation)
+		r.Use(middleware.RequirePermission(_auth.PERMISSION_USER_IMPERSONATE))
+		r.Post("/", authHandler.StartImpersonation)
+	})
+
+	router.Route("/auth/impersonation", func(r chi.Router) {
 		r.Use(middleware.AuthMiddleware)
+		r.Delete("/", authHandler.EndImpersonation)
+	})
+
+	router.Route("/auth/roles", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.With(middleware.RequirePermission(_auth.PERMISSION_USER_READ)).Get("/{userId}", authHandler.GetUserRoles)
+		r.With(middleware.RequirePermission(_auth.PERMISSION_ROLE_MANAGE)).Put("/{userId}", authHandler.SetUserRoles)
+	})
+
+	// authenticated by the refresh token cookie itself, running AuthMiddleware
+	// here would rotate the token before this handler gets to use it
+	router.Route("/auth/refresh", func(r chi.Router) {
 		r.Get("/", authHandler.RefreshAccess)
 	})
 }
 
+func (handler *authHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	// served as a bare JWK set, verifiers expect the RFC 7517 shape
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	render.JSON(w, r, _auth.GetJWKS())
+}
+
 func (handler *authHandler) AuthenticateFromInquiry(w http.ResponseWriter, r *http.Request) {
 	resp := &response.Response[string]{
 		Writer: w,
@@ -107,7 +176,7 @@ func (handler *authHandler) AuthenticateFromInquiry(w http.ResponseWriter, r *ht
 		return
 	}
 
-	res := handler.authUsecase.AuthenticateFromInquiry(context.Background(), req)
+	res := handler.authUsecase.AuthenticateFromInquiry(r.Context(), req)
 	res.Writer = w
 	res.WriteResponse()
 }
@@ -131,13 +200,24 @@ func (handler *authHandler) RegisterUserFromInquiry(w http.ResponseWriter, r *ht
 		return
 	}
 
-	res := handler.authUsecase.RegisterUserFromInquiry(context.Background(), req)
+	res := handler.authUsecase.RegisterUserFromInquiry(r.Context(), req)
 	res.Writer = w
 	res.WriteResponse()
 }
 
 func (handler *authHandler) RefreshAccess(w http.ResponseWriter, r *http.Request) {
-	res := handler.authUsecase.RefreshAccess(r.Context())
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	refreshToken := handler.getRefreshToken(r)
+	if refreshToken == "" {
+		resp.Unauthorized("No refreshToken found")
+		resp.WriteResponse()
+		return
+	}
+
+	res := handler.authUsecase.RefreshAccess(r.Context(), refreshToken)
 
 	res.Writer = w
 	res.WriteResponse()
@@ -161,7 +241,7 @@ func (handler *authHandler) CheckIndentifier(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	res := handler.authUsecase.CheckIdentifier(context.Background(), req)
+	res := handler.authUsecase.CheckIdentifier(r.Context(), req)
 	res.Writer = w
 	res.WriteResponse()
 }
@@ -184,7 +264,7 @@ func (handler *authHandler) VerifyResetPasswordToken(w http.ResponseWriter, r *h
 		return
 	}
 
-	res := handler.authUsecase.VerifyResetPasswordToken(context.Background(), req)
+	res := handler.authUsecase.VerifyResetPasswordToken(r.Context(), req)
 	res.Writer = w
 	res.WriteResponse()
 }
@@ -207,7 +287,7 @@ func (handler *authHandler) SubmitPasswordReset(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	res := handler.authUsecase.ResetUserPassword(context.Background(), req)
+	res := handler.authUsecase.ResetUserPassword(r.Context(), req)
 	res.Writer = w
 	res.WriteResponse()
 }
@@ -230,7 +310,7 @@ func (handler *authHandler) VerifyPhoneNumber(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	res := handler.authUsecase.VerifyPhoneNumber(context.Background(), req)
+	res := handler.authUsecase.VerifyPhoneNumber(r.Context(), req)
 	res.Writer = w
 	res.WriteResponse()
 }
@@ -253,7 +333,7 @@ func (handler *authHandler) SendPasswordResetLink(w http.ResponseWriter, r *http
 		return
 	}
 
-	res := handler.authUsecase.SendPasswordResetLink(context.Background(), req)
+	res := handler.authUsecase.SendPasswordResetLink(r.Context(), req)
 	res.Writer = w
 	res.WriteResponse()
 }
@@ -276,45 +356,64 @@ func (handler *authHandler) AuthenticateRegularUser(w http.ResponseWriter, r *ht
 		return
 	}
 
-	res := handler.authUsecase.AuthenticateRegularUser(context.Background(), req)
+	res := handler.authUsecase.AuthenticateRegularUser(r.Context(), req)
 	res.Writer = w
 	res.WriteResponse()
 }
 
-func (handler *authHandler) AuthenticateByGoogle(w http.ResponseWriter, r *http.Request) {
+func (handler *authHandler) AuthenticateWithProvider(w http.ResponseWriter, r *http.Request) {
 	resp := &response.Response[string]{
 		Writer: w,
 	}
 
-	req := _auth.GoogleRegisterDTO{}
+	req := _auth.ProviderAuthenticationDTO{}
 	if err := render.DecodeJSON(r.Body, &req); err != nil {
 		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
 		return
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
+	res := handler.authUsecase.AuthenticateWithProvider(r.Context(), chi.URLParam(r, "provider"), req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.UserRegistrationDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
 		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
 		return
 	}
 
-	err := handler.verifyToken(req.StsTokenManager.AccessToken)
+	err := req.Validate()
 	if err != nil {
-		resp.Unauthorized(err.Error())
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
 		return
 	}
 
-	res := handler.authUsecase.AuthenticateByGoogle(context.Background(), req)
+	res := handler.authUsecase.RegisterUser(r.Context(), req)
 	res.Writer = w
 	res.WriteResponse()
 }
 
-func (handler *authHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
+func (handler *authHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
 	resp := &response.Response[string]{
 		Writer: w,
 	}
 
-	req := _auth.UserRegistrationDTO{}
+	req := _auth.ResendVerificationDTO{}
 	if err := render.DecodeJSON(r.Body, &req); err != nil {
 		resp.BadRequest(err.Error(), nil)
 		resp.WriteResponse()
@@ -328,7 +427,7 @@ func (handler *authHandler) RegisterUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	res := handler.authUsecase.RegisterUser(context.Background(), req)
+	res := handler.authUsecase.ResendVerification(r.Context(), req)
 	res.Writer = w
 	res.WriteResponse()
 }
@@ -352,72 +451,581 @@ func (handler *authHandler) VerifyAccessToken(w http.ResponseWriter, r *http.Req
 }
 
 func (handler *authHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	res := response.Response[_auth.AuthenticationResponse]{
-		Writer: w,
-	}
-	now, _ := utils.GetJktTime()
-
-	res.SuccessWithCookie("success", _auth.AuthenticationResponse{
-		AccessToken:  "",
-		RefreshToken: "",
-	}, []*http.Cookie{
-		{
-			Name:     handler.config.AccessTokenKey,
-			Value:    "",
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Minute * -30),
-		},
-		{
-			Name:     handler.config.RefreshTokenKey,
-			Value:    "",
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Minute * -30),
-		},
-	})
+	res := handler.authUsecase.Logout(r.Context(), handler.getRefreshToken(r))
+	res.Writer = w
+	res.WriteResponse()
+}
+
+// getRefreshToken reads the cookie of browsers or the body of clients without cookies
+func (handler *authHandler) getRefreshToken(r *http.Request) string {
+	if refreshToken, found := handler.tokenTransport.refreshToken(r); found {
+		return refreshToken
+	}
 
+	req := _auth.RefreshTokenDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		return ""
+	}
+
+	return req.RefreshToken
+}
+
+// GetCsrfToken hands out a new CSRF token, for sessions started before CSRF
+// protection or browsers that lost the cookie
+func (handler *authHandler) GetCsrfToken(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.Context().Value(_auth.SessionIDContext{}).(string)
+
+	res := handler.authUsecase.GetCsrfToken(r.Context(), sessionID)
+	res.Writer = w
 	res.WriteResponse()
 }
 
-func (handler *authHandler) RegisterWithGoogle(w http.ResponseWriter, r *http.Request) {
+func (handler *authHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+	sessionID := r.Context().Value(_auth.SessionIDContext{}).(string)
+
+	res := handler.authUsecase.GetSessions(r.Context(), *userID, sessionID)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
 	resp := &response.Response[string]{
 		Writer: w,
 	}
 
-	req := _auth.GoogleRegisterDTO{}
+	sessionID := chi.URLParam(r, "sessionId")
+	if sessionID == "" {
+		resp.BadRequest("invalid session id", nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.RevokeSession(r.Context(), *userID, sessionID)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+	sessionID := r.Context().Value(_auth.SessionIDContext{}).(string)
+
+	res := handler.authUsecase.RevokeOtherSessions(r.Context(), *userID, sessionID)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.EnrollTwoFactor(r.Context(), *userID)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.TwoFactorConfirmationDTO{}
 	if err := render.DecodeJSON(r.Body, &req); err != nil {
 		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
 		return
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
+	err := req.Validate()
+	if err != nil {
 		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
 		return
 	}
 
-	err := handler.verifyToken(req.StsTokenManager.AccessToken)
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.ConfirmTwoFactor(r.Context(), *userID, req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.TwoFactorDisableDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	err := req.Validate()
 	if err != nil {
-		resp.Unauthorized(err.Error())
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
 		return
 	}
 
-	res := handler.authUsecase.RegisterByGoogle(context.Background(), req)
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.DisableTwoFactor(r.Context(), *userID, req)
 	res.Writer = w
 	res.WriteResponse()
 }
 
-func (handler *authHandler) verifyToken(token string) (err error) {
-	_, err = handler.firebaseAdminClient.VerifyIDToken(context.Background(), token)
+func (handler *authHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.TwoFactorConfirmationDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	err := req.Validate()
 	if err != nil {
-		log.Fatalf("error verifying ID token: %v\n", err)
-		return err
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.RegenerateRecoveryCodes(r.Context(), *userID, req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) AuthenticateTwoFactor(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.TwoFactorAuthenticationDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	err := req.Validate()
+	if err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	res := handler.authUsecase.AuthenticateTwoFactor(r.Context(), req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) BeginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.BeginPasskeyRegistration(r.Context(), *userID)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) FinishPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.PasskeyRegistrationDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	err := req.Validate()
+	if err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.FinishPasskeyRegistration(r.Context(), *userID, req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) BeginPasskeyAuthentication(w http.ResponseWriter, r *http.Request) {
+	res := handler.authUsecase.BeginPasskeyAuthentication(r.Context())
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) FinishPasskeyAuthentication(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.PasskeyAuthenticationDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	err := req.Validate()
+	if err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	res := handler.authUsecase.FinishPasskeyAuthentication(r.Context(), req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) GetPasskeys(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.GetPasskeys(r.Context(), *userID)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) DeletePasskey(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	passkeyID := chi.URLParam(r, "passkeyId")
+	if passkeyID == "" {
+		resp.BadRequest("invalid passkey id", nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.DeletePasskey(r.Context(), *userID, passkeyID)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) RegisterWithProvider(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.ProviderAuthenticationDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	res := handler.authUsecase.RegisterWithProvider(r.Context(), chi.URLParam(r, "provider"), req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) GetLoginMethods(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.GetLoginMethods(r.Context(), *userID)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.ProviderAuthenticationDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.LinkIdentity(r.Context(), *userID, chi.URLParam(r, "provider"), req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.UnlinkIdentity(r.Context(), *userID, chi.URLParam(r, "provider"))
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) SetPassword(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.SetPasswordDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.SetPassword(r.Context(), *userID, req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
 	}
 
-	return nil
+	req := _auth.ChangePasswordDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+	sessionID := r.Context().Value(_auth.SessionIDContext{}).(string)
+
+	res := handler.authUsecase.ChangePassword(r.Context(), *userID, sessionID, req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) RequestWhatsAppCode(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.WhatsAppCodeRequestDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	res := handler.authUsecase.RequestWhatsAppCode(r.Context(), req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) AuthenticateWithWhatsAppCode(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.WhatsAppCodeVerificationDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	res := handler.authUsecase.AuthenticateWithWhatsAppCode(r.Context(), req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.MagicLinkRequestDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	res := handler.authUsecase.RequestMagicLink(r.Context(), req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) AuthenticateWithMagicLink(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.MagicLinkVerificationDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if nonce, found := handler.tokenTransport.cookieValue(r, _auth.MAGIC_LINK_NONCE_COOKIE); found {
+		req.Nonce = nonce
+	}
+
+	res := handler.authUsecase.AuthenticateWithMagicLink(r.Context(), req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) GetUserRoles(w http.ResponseWriter, r *http.Request) {
+	res := handler.authUsecase.GetUserRoles(r.Context(), chi.URLParam(r, "userId"))
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.RoleAssignmentDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	res := handler.authUsecase.SetUserRoles(r.Context(), chi.URLParam(r, "userId"), req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) StartImpersonation(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.ImpersonationRequestDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	actorID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.StartImpersonation(r.Context(), *actorID, chi.URLParam(r, "userId"), req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) EndImpersonation(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.Context().Value(_auth.SessionIDContext{}).(string)
+
+	res := handler.authUsecase.EndImpersonation(r.Context(), sessionID)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) GetKnownDevices(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.GetKnownDevices(r.Context(), *userID)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) DeleteKnownDevice(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.authUsecase.DeleteKnownDevice(r.Context(), *userID, chi.URLParam(r, "deviceId"))
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *authHandler) ReportUnrecognizedLogin(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := _auth.LoginReportDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	res := handler.authUsecase.ReportUnrecognizedLogin(r.Context(), req)
+	res.Writer = w
+	res.WriteResponse()
 }
@@ -6,7 +6,9 @@ import (
 	"encoding/base64"
 	"fmt"
 	"log"
+	_audit "mini-wallet/app/audit"
 	"mini-wallet/domain"
+	"mini-wallet/domain/audit"
 	"mini-wallet/domain/auth"
 	"mini-wallet/domain/common/response"
 	"mini-wallet/domain/inquiry"
@@ -14,29 +16,62 @@ import (
 	"mini-wallet/infrastructure"
 	"mini-wallet/integration"
 	"mini-wallet/utils"
-	"net/http"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
 type authUsecase struct {
-	userRepository      user.UserRepository
-	inquiryRepository   inquiry.InquiryRepository
-	notificationService integration.NotificationService
-	config              *utils.AppConfig
+	userRepository        user.UserRepository
+	inquiryRepository     inquiry.InquiryRepository
+	sessionRepository     auth.SessionRepository
+	passkeyRepository     auth.PasskeyRepository
+	oneTimeCodeRepository auth.OneTimeCodeRepository
+	knownDeviceRepository auth.KnownDeviceRepository
+	notificationService   integration.NotificationService
+	identityProviders     map[string]integration.IdentityProvider
+	sessionManager        *sessionManager
+	tokenTransport        *tokenTransport
+	attemptLimiter        *attemptLimiter
+	auditor               *_audit.Auditor
+	webAuthn              *webauthn.WebAuthn
+	config                *utils.AppConfig
 }
 
 func NewAuthUsecase(repositories domain.Repositories, integrations domain.Infrastructure, config *utils.AppConfig) auth.AuthUsecase {
+	webAuthn, err := newWebAuthn(config)
+	if err != nil {
+		log.Fatalf("error initializing webauthn: %v\n", err)
+	}
+
+	tokenTransport, err := newTokenTransport(config)
+	if err != nil {
+		log.Fatalf("error initializing token transport: %v\n", err)
+	}
+
 	return &authUsecase{
-		userRepository:      repositories.UserRepository,
-		inquiryRepository:   repositories.InquiryRepository,
-		notificationService: integrations.NotificationService,
-		config:              config,
+		userRepository:        repositories.UserRepository,
+		inquiryRepository:     repositories.InquiryRepository,
+		sessionRepository:     repositories.SessionRepository,
+		passkeyRepository:     repositories.PasskeyRepository,
+		oneTimeCodeRepository: repositories.OneTimeCodeRepository,
+		knownDeviceRepository: repositories.KnownDeviceRepository,
+		notificationService:   integrations.NotificationService,
+		identityProviders:     integrations.IdentityProviders,
+		sessionManager:        newSessionManager(repositories),
+		tokenTransport:        tokenTransport,
+		attemptLimiter:        newAttemptLimiter(integrations.Cache),
+		auditor:               _audit.NewAuditor(repositories),
+		webAuthn:              webAuthn,
+		config:                config,
 	}
 }
 
 func (usecase *authUsecase) RegisterUserFromInquiry(ctx context.Context, req auth.AuthFromInquiryDTO) (res response.Response[string]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_REGISTER, audit.METHOD_INQUIRY)
+	event.Identifier = req.InquiryID
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
 	inquiryEntity, err := usecase.inquiryRepository.GetInquiryById(ctx, req.InquiryID)
 	if err != nil {
 		res.InternalServerError(err.Error())
@@ -71,22 +106,19 @@ func (usecase *authUsecase) RegisterUserFromInquiry(ctx context.Context, req aut
 		res.InternalServerError(err.Error())
 		return
 	}
-	salt, err := utils.GenerateSalt(16)
+
+	err = checkPasswordPolicy(req.Password, inquiryEntity.FullName, inquiryEntity.Email, &inquiryEntity.PhoneNumber)
 	if err != nil {
-		res.InternalServerError(err.Error())
+		res.BadRequest(err.Error(), nil)
 		return
 	}
 
-	saltedPassword := req.Password + salt
-	hash, err := bcrypt.GenerateFromPassword([]byte(saltedPassword), bcrypt.DefaultCost)
+	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
 		res.InternalServerError(err.Error())
 		return
 	}
 
-	stringHashedPassword := string(hash)
-	stringSalt := string(salt)
-
 	VerificationToken, err := utils.GenerateRandomString(32)
 	if err != nil {
 		res.InternalServerError(err.Error())
@@ -103,15 +135,18 @@ func (usecase *authUsecase) RegisterUserFromInquiry(ctx context.Context, req aut
 		Name:              inquiryEntity.FullName,
 		PhoneNumber:       &inquiryEntity.PhoneNumber,
 		Email:             inquiryEntity.Email,
-		HashedPassword:    &stringHashedPassword,
-		PasswordSalt:      &stringSalt,
+		HashedPassword:    &hashedPassword,
 		VerificationToken: VerificationToken,
 		CreatedAt:         now.Format(time.RFC3339),
-		ExpiredAt:         int(now.Add(time.Minute * 15).Unix()),
+		ExpiredAt:         int(now.Add(user.TEMPORARY_USER_LIFETIME).Unix()),
+		InquiryID:         &inquiryEntity.ID,
+		PurgeAt:           now.Add(user.TEMPORARY_USER_LIFETIME),
 	}
 
-	// insert to temporary user, expiring in 15 min
-	err = usecase.userRepository.InsertTemporaryUser(ctx, temporaryUser)
+	event.UserID = temporaryUser.UID
+
+	// supersedes an earlier registration of the same email or phone number
+	err = usecase.userRepository.ReplaceTemporaryUser(ctx, temporaryUser)
 	if err != nil {
 		res.InternalServerError(err.Error())
 		return
@@ -123,8 +158,8 @@ func (usecase *authUsecase) RegisterUserFromInquiry(ctx context.Context, req aut
 		res.InternalServerError(err.Error())
 		return
 	}
-	err = usecase.notificationService.SendWhatsAppMessage(ctx,
-		fmt.Sprintf("Halo %s,\nBerikut adalah link verifikasi akun Anda, %s", inquiryEntity.FullName, "https://"+usecase.config.AppDomain+"/verify-account?token="+VerificationToken+"&redirect=inquiry&inquiry_id="+inquiryEntity.ID), inquiryEntity.PhoneNumber)
+
+	err = usecase.sendVerificationLink(ctx, temporaryUser)
 	if err != nil {
 		res.InternalServerError(err.Error())
 		return
@@ -135,6 +170,10 @@ func (usecase *authUsecase) RegisterUserFromInquiry(ctx context.Context, req aut
 }
 
 func (usecase *authUsecase) AuthenticateFromInquiry(ctx context.Context, req auth.AuthFromInquiryDTO) (res response.Response[auth.AuthenticationResponse]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_LOGIN, audit.METHOD_INQUIRY)
+	event.Identifier = req.InquiryID
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
 	inquiryEntity, err := usecase.inquiryRepository.GetInquiryById(ctx, req.InquiryID)
 	if err != nil {
 		res.InternalServerError(err.Error())
@@ -146,94 +185,144 @@ func (usecase *authUsecase) AuthenticateFromInquiry(ctx context.Context, req aut
 		return
 	}
 
+	if inquiryEntity.UserID == nil {
+		res.BadRequest("Pengguna tidak ditemukan", nil)
+		return
+	}
+
 	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, *inquiryEntity.UserID)
-	if existingUser.VerifyPassword(req.Password) != nil {
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil || existingUser.HashedPassword == nil {
+		res.BadRequest("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	event.UserID = existingUser.UID
+
+	if existingUser.PasswordResetRequired {
+		res.Forbidden(auth.PASSWORD_RESET_REQUIRED_MESSAGE, nil)
+		return
+	}
+
+	// shares the counter with email logins, it is the same password
+	identifierAttempt := newAttempt(loginIdentifierPolicy, existingUser.Email)
+	ipAttempt := newAttempt(loginIPPolicy, auth.GetClientInfo(ctx).IPAddress)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, identifierAttempt, ipAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
+	matched, err := usecase.verifyPassword(ctx, existingUser, req.Password)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !matched {
+		err = usecase.recordFailedLogin(ctx, existingUser, identifierAttempt, ipAttempt)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
 		res.BadRequest("Kata sandi salah", nil)
 		return
 	}
 
-	now, _ := utils.GetJktTime()
+	err = usecase.attemptLimiter.reset(ctx, identifierAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
 
-	accessToken, _ := auth.GenerateJWT(*existingUser, "ACCESS")
-	refreshToken, _ := auth.GenerateJWT(*existingUser, "REFRESH")
-	res.SuccessWithCookie("success", auth.AuthenticationResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, []*http.Cookie{
-		{
-			Name:     usecase.config.AccessTokenKey,
-			Value:    accessToken,
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Hour * 24 * 31),
-		},
-		{
-			Name:     usecase.config.RefreshTokenKey,
-			Value:    refreshToken,
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Hour * 24 * 31),
-		},
-	})
+	if existingUser.IsTwoFactorEnabled() {
+		return usecase.twoFactorChallenge(*existingUser)
+	}
+
+	tokens, err := usecase.signIn(ctx, *existingUser)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithCookie("success", *tokens, usecase.tokenTransport.sessionCookies(*tokens))
 
 	return
 }
 
-func (usecase *authUsecase) RefreshAccess(ctx context.Context) (res response.Response[auth.AuthenticationResponse]) {
-	claims, status := auth.ValidateToken(ctx.Value("refreshToken").(string))
-
-	if status == auth.ERROR_EXPIRED_TOKEN {
-		res.BadRequest("token is expired", nil)
+func (usecase *authUsecase) RefreshAccess(ctx context.Context, refreshToken string) (res response.Response[auth.AuthenticationResponse]) {
+	_, tokens, err := usecase.sessionManager.rotateSession(ctx, refreshToken)
+	if err == auth.ErrInvalidRefreshToken || err == auth.ErrSessionRevoked || err == auth.ErrRefreshTokenReused {
+		res.Unauthorized(err.Error())
 		return
 	}
 
-	userId := claims.Subject
-	user, err := usecase.userRepository.GetUserByEmail(ctx, userId)
 	if err != nil {
 		res.InternalServerError(err.Error())
 		return
 	}
 
-	if user == nil {
-		res.NotFound("Pengguna tidak ditemukan", nil)
-		return
+	res.SuccessWithCookie("success", *tokens, usecase.tokenTransport.sessionCookies(*tokens))
+
+	return
+}
+
+func (usecase *authUsecase) Logout(ctx context.Context, refreshToken string) (res response.Response[auth.AuthenticationResponse]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_LOGOUT, "")
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	if refreshToken != "" {
+		claims, status := auth.ValidateToken(refreshToken)
+		if status != auth.ERROR_INVALID_TOKEN {
+			event.UserID = claims.Subject
+			event.SessionID = claims.SessionID
+		}
+
+		err := usecase.sessionManager.revokeSession(ctx, refreshToken, auth.SESSION_REVOKED_LOGOUT)
+		if err != nil && err != auth.ErrInvalidRefreshToken {
+			res.InternalServerError(err.Error())
+			return
+		}
 	}
 
-	now, _ := utils.GetJktTime()
-	accessToken, _ := auth.GenerateJWT(*user, "ACCESS")
-	refreshToken, _ := auth.GenerateJWT(*user, "REFRESH")
 	res.SuccessWithCookie("success", auth.AuthenticationResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, []*http.Cookie{
-		{
-			Name:     usecase.config.AccessTokenKey,
-			Value:    accessToken,
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Hour * 24 * 31),
-		},
-		{
-			Name:     usecase.config.RefreshTokenKey,
-			Value:    refreshToken,
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Hour * 24 * 31),
-		},
-	})
+		AccessToken:  "",
+		RefreshToken: "",
+	}, usecase.tokenTransport.expiredSessionCookies())
 
 	return
 }
 
 func (usecase *authUsecase) CheckIdentifier(ctx context.Context, req auth.CheckIndentifierDTO) (res response.Response[string]) {
+	// this tells whether an account exists, so every lookup is throttled, not only misses
+	ipAttempt := newAttempt(checkIdentifierIPPolicy, auth.GetClientInfo(ctx).IPAddress)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, ipAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
+	_, err = usecase.attemptLimiter.record(ctx, ipAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
 	user, err := usecase.userRepository.GetUserByIdentifier(ctx, req.Identifier)
 	if err != nil {
 		res.InternalServerError(err.Error())
@@ -267,6 +356,9 @@ func (usecase *authUsecase) VerifyResetPasswordToken(ctx context.Context, req au
 }
 
 func (usecase *authUsecase) ResetUserPassword(ctx context.Context, req auth.PasswordResetSubmissionDTO) (res response.Response[string]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_PASSWORD_RESET, "")
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
 	now, _ := utils.GetJktTime()
 	passwordReset, err := usecase.userRepository.GetUserPasswordResetEntity(ctx, req.PasswordResetToken, now.Unix())
 	if err != nil {
@@ -280,6 +372,7 @@ func (usecase *authUsecase) ResetUserPassword(ctx context.Context, req auth.Pass
 		return
 	}
 
+	event.Identifier = passwordReset.Email
 	user, err := usecase.userRepository.GetUserByEmail(ctx, passwordReset.Email)
 	if err != nil {
 		res.InternalServerError(err.Error())
@@ -292,7 +385,21 @@ func (usecase *authUsecase) ResetUserPassword(ctx context.Context, req auth.Pass
 		return
 	}
 
-	user.ChangePassword(req.Password)
+	event.UserID = user.UID
+
+	err = checkPasswordPolicy(req.Password, user.Name, user.Email, user.PhoneNumber)
+	if err != nil {
+		res.BadRequest(err.Error(), nil)
+		return
+	}
+
+	err = user.ChangePassword(req.Password)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	user.PasswordResetRequired = false
 
 	err = usecase.userRepository.UpsertUser(ctx, *user)
 	if err != nil {
@@ -301,13 +408,30 @@ func (usecase *authUsecase) ResetUserPassword(ctx context.Context, req auth.Pass
 		return
 	}
 
-	_ = usecase.userRepository.DeleteUserPasswordResetEntity(ctx, user.Email)
+	// the link is gone with every other pending link of the email
+	err = usecase.userRepository.DeleteUserPasswordResetEntity(ctx, user.Email)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	// whoever knew the old password is signed out everywhere
+	err = usecase.sessionRepository.RevokeUserSessions(ctx, user.UID, "", auth.SESSION_REVOKED_PASSWORD_RESET, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	go usecase.notifyPasswordChanged(*user, *now)
 
 	res.SuccessWithMessage("Kata sandi diubah, silakan masuk")
 	return
 }
 
 func (usecase *authUsecase) VerifyPhoneNumber(ctx context.Context, req auth.VerifyEmailDTO) (res response.Response[auth.AuthenticationResponse]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_VERIFY, "")
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
 	now, err := utils.GetJktTime()
 	if err != nil {
 		res.InternalServerError(err.Error())
@@ -320,6 +444,9 @@ func (usecase *authUsecase) VerifyPhoneNumber(ctx context.Context, req auth.Veri
 		return
 	}
 
+	event.UserID = temporaryUser.UID
+	event.Identifier = temporaryUser.Email
+
 	userEntity, err := temporaryUser.ToUserEntity()
 	if err != nil {
 		res.InternalServerError(err.Error())
@@ -338,36 +465,43 @@ func (usecase *authUsecase) VerifyPhoneNumber(ctx context.Context, req auth.Veri
 		return
 	}
 
-	accessToken, _ := auth.GenerateJWT(*userEntity, "ACCESS")
-	refreshToken, _ := auth.GenerateJWT(*userEntity, "REFRESH")
-	res.SuccessWithCookie("success", auth.AuthenticationResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, []*http.Cookie{
-		{
-			Name:     usecase.config.AccessTokenKey,
-			Value:    accessToken,
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Hour * 24 * 31),
-		},
-		{
-			Name:     usecase.config.RefreshTokenKey,
-			Value:    refreshToken,
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Hour * 24 * 31),
-		},
-	})
+	tokens, err := usecase.signIn(ctx, *userEntity)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithCookie("success", *tokens, usecase.tokenTransport.sessionCookies(*tokens))
 
 	return
 }
 
 func (usecase *authUsecase) SendPasswordResetLink(ctx context.Context, req auth.PasswordResetDTO) (res response.Response[string]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_PASSWORD_RESET_REQUEST, "")
+	event.Identifier = req.Email
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	emailAttempt := newAttempt(passwordResetEmailPolicy, req.Email)
+	ipAttempt := newAttempt(passwordResetIPPolicy, auth.GetClientInfo(ctx).IPAddress)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, emailAttempt, ipAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
+	for _, a := range []attempt{emailAttempt, ipAttempt} {
+		_, err = usecase.attemptLimiter.record(ctx, a)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+	}
+
 	existingUser, err := usecase.userRepository.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		res.InternalServerError(err.Error())
@@ -379,24 +513,36 @@ func (usecase *authUsecase) SendPasswordResetLink(ctx context.Context, req auth.
 		return
 	}
 
+	event.UserID = existingUser.UID
+
+	err = usecase.issuePasswordResetLink(ctx, *existingUser)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithMessage("Instruksi atur ulang kata sandi terkirim")
+	return res
+}
+
+func (usecase *authUsecase) issuePasswordResetLink(ctx context.Context, existingUser user.UserEntity) error {
 	now, _ := utils.GetJktTime()
 	passwordResetToken, _ := GenerateRandomString(32)
 	userPasswordResetEntity := user.UserPasswordResetEntity{
 		Email:              existingUser.Email,
-		ExpiredAt:          now.Add(time.Minute * 15).Unix(),
+		ExpiredAt:          now.Add(user.PASSWORD_RESET_LIFETIME).Unix(),
 		PasswordResetToken: passwordResetToken,
+		PurgeAt:            now.Add(user.PASSWORD_RESET_LIFETIME),
 	}
 
-	err = usecase.userRepository.InsertUserPasswordResetEntity(ctx, userPasswordResetEntity)
+	err := usecase.userRepository.InsertUserPasswordResetEntity(ctx, userPasswordResetEntity)
 	if err != nil {
-		res.InternalServerError(err.Error())
-		return
+		return err
 	}
 
 	go infrastructure.SendPasswordResetLink(existingUser.Email, existingUser.Name, passwordResetToken, usecase.config.AppDomain)
 
-	res.SuccessWithMessage("Instruksi atur ulang kata sandi terkirim")
-	return res
+	return nil
 }
 
 func GenerateRandomString(n int) (string, error) {
@@ -412,12 +558,29 @@ func GenerateRandomString(n int) (string, error) {
 }
 
 func (usecase *authUsecase) AuthenticateRegularUser(ctx context.Context, req auth.AuthenticationDTO) (res response.Response[auth.AuthenticationResponse]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_LOGIN, audit.METHOD_PASSWORD)
+	event.Identifier = req.Identifier
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
 	now, err := utils.GetJktTime()
 	if err != nil {
 		res.InternalServerError(err.Error())
 		return
 	}
 
+	identifierAttempt := newAttempt(loginIdentifierPolicy, req.Identifier)
+	ipAttempt := newAttempt(loginIPPolicy, auth.GetClientInfo(ctx).IPAddress)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, identifierAttempt, ipAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
 	temporaryUser, err := usecase.userRepository.GetTemporaryUserByIdentifier(ctx, req.Identifier, now.Unix())
 	if err != nil {
 		res.InternalServerError(err.Error())
@@ -443,98 +606,72 @@ func (usecase *authUsecase) AuthenticateRegularUser(ctx context.Context, req aut
 		}
 
 		if existingUser == nil {
+			err = usecase.recordFailedLogin(ctx, nil, identifierAttempt, ipAttempt)
+			if err != nil {
+				res.InternalServerError(err.Error())
+				return
+			}
+
 			res.BadRequest("Pengguna tidak ditemukan", nil)
 			return
 		}
 	}
 
+	event.UserID = existingUser.UID
+
 	if existingUser.HashedPassword == nil {
-		res.BadRequest("Silakan masuk menggunakan Google", nil)
+		res.BadRequest(noPasswordMessage(*existingUser), nil)
 		return
 	}
 
-	if existingUser.VerifyPassword(req.Password) != nil {
-		res.BadRequest("Kata sandi salah", nil)
+	if existingUser.PasswordResetRequired {
+		res.Forbidden(auth.PASSWORD_RESET_REQUIRED_MESSAGE, nil)
 		return
 	}
 
-	accessToken, _ := auth.GenerateJWT(*existingUser, "ACCESS")
-	refreshToken, _ := auth.GenerateJWT(*existingUser, "REFRESH")
-	res.SuccessWithCookie("success", auth.AuthenticationResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, []*http.Cookie{
-		{
-			Name:     usecase.config.AccessTokenKey,
-			Value:    accessToken,
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Hour * 24 * 31),
-		},
-		{
-			Name:     usecase.config.RefreshTokenKey,
-			Value:    refreshToken,
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Hour * 24 * 31),
-		},
-	})
+	matched, err := usecase.verifyPassword(ctx, existingUser, req.Password)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
 
-	return res
-}
+	if !matched {
+		err = usecase.recordFailedLogin(ctx, existingUser, identifierAttempt, ipAttempt)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		res.BadRequest("Kata sandi salah", nil)
+		return
+	}
 
-func (usecase *authUsecase) AuthenticateByGoogle(ctx context.Context, req auth.GoogleRegisterDTO) (res response.Response[auth.AuthenticationResponse]) {
-	_, err := req.ToUserEntity()
+	err = usecase.attemptLimiter.reset(ctx, identifierAttempt)
 	if err != nil {
 		res.InternalServerError(err.Error())
 		return
 	}
 
-	existingUser, err := usecase.userRepository.GetUserByEmail(ctx, req.Email)
+	if existingUser.IsTwoFactorEnabled() {
+		return usecase.twoFactorChallenge(*existingUser)
+	}
+
+	tokens, err := usecase.signIn(ctx, *existingUser)
 	if err != nil {
 		res.InternalServerError(err.Error())
 		return
 	}
 
-	accessToken, _ := auth.GenerateJWT(*existingUser, "ACCESS")
-	refreshToken, _ := auth.GenerateJWT(*existingUser, "REFRESH")
-	now, _ := utils.GetJktTime()
-	if existingUser != nil {
-		res.SuccessWithCookie("success", auth.AuthenticationResponse{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-		}, []*http.Cookie{
-			{
-				Name:     usecase.config.AccessTokenKey,
-				Value:    accessToken,
-				Domain:   ".sebia.id",
-				Path:     "/",
-				HttpOnly: true,
-				Secure:   true,
-				Expires:  now.Add(time.Hour * 24 * 31),
-			},
-			{
-				Name:     usecase.config.RefreshTokenKey,
-				Value:    refreshToken,
-				Domain:   ".sebia.id",
-				Path:     "/",
-				HttpOnly: true,
-				Secure:   true,
-				Expires:  now.Add(time.Hour * 24 * 31),
-			},
-		})
-		return
-	}
-
-	res.BadRequest("Akun belum terdaftar, lanjutkan pendaftaran", nil)
+	res.SuccessWithCookie("success", *tokens, usecase.tokenTransport.sessionCookies(*tokens))
+
 	return res
 }
 
 func (usecase *authUsecase) RegisterUser(ctx context.Context, req auth.UserRegistrationDTO) (res response.Response[interface{}]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_REGISTER, audit.METHOD_PASSWORD)
+	event.Identifier = req.Email
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
 	userEntity, err := req.ToTemporaryUserEntity()
 	if err != nil {
 		res.InternalServerError(err.Error())
@@ -563,94 +700,167 @@ func (usecase *authUsecase) RegisterUser(ctx context.Context, req auth.UserRegis
 		return
 	}
 
-	// insert to temporary user, expiring in 15 min
-	err = usecase.userRepository.InsertTemporaryUser(ctx, *userEntity)
+	event.UserID = userEntity.UID
+
+	// supersedes an earlier registration of the same email or phone number
+	err = usecase.userRepository.ReplaceTemporaryUser(ctx, *userEntity)
 	if err != nil {
 		res.InternalServerError(err.Error())
 		return
 	}
 
-	go infrastructure.SendEmailVerificationLink(userEntity.Email, userEntity.Name, userEntity.VerificationToken, usecase.config.AppDomain)
+	err = usecase.sendVerificationLink(ctx, *userEntity)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
 
 	res.SuccessWithMessage("Link verifikasi dikirimkan ke email Anda")
 	return res
 }
 
-func (usecase *authUsecase) RegisterByGoogle(ctx context.Context, req auth.GoogleRegisterDTO) (res response.Response[auth.AuthenticationResponse]) {
-	userEntity, err := req.ToUserEntity()
+func (usecase *authUsecase) ResendVerification(ctx context.Context, req auth.ResendVerificationDTO) (res response.Response[string]) {
+	identifierAttempt := newAttempt(verificationResendIdentifierPolicy, req.Identifier)
+	ipAttempt := newAttempt(verificationResendIPPolicy, auth.GetClientInfo(ctx).IPAddress)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, identifierAttempt, ipAttempt)
 	if err != nil {
 		res.InternalServerError(err.Error())
 		return
 	}
 
-	existingUser, err := usecase.userRepository.GetUserByEmail(ctx, req.Email)
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
+	for _, a := range []attempt{identifierAttempt, ipAttempt} {
+		_, err = usecase.attemptLimiter.record(ctx, a)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+	}
+
+	now, err := utils.GetJktTime()
 	if err != nil {
 		res.InternalServerError(err.Error())
 		return
 	}
 
-	accessToken, _ := auth.GenerateJWT(*userEntity, "ACCESS")
-	refreshToken, _ := auth.GenerateJWT(*userEntity, "REFRESH")
-	now, _ := utils.GetJktTime()
-	if existingUser != nil {
-		res.SuccessWithCookie("success", auth.AuthenticationResponse{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-		}, []*http.Cookie{
-			{
-				Name:     usecase.config.AccessTokenKey,
-				Value:    accessToken,
-				Domain:   ".sebia.id",
-				Path:     "/",
-				HttpOnly: true,
-				Secure:   true,
-				Expires:  now.Add(time.Hour * 24 * 31),
-			},
-			{
-				Name:     usecase.config.RefreshTokenKey,
-				Value:    accessToken,
-				Domain:   ".sebia.id",
-				Path:     "/",
-				HttpOnly: true,
-				Secure:   true,
-				Expires:  now.Add(time.Hour * 24 * 31),
-			},
-		})
+	temporaryUser, err := usecase.userRepository.GetTemporaryUserByIdentifier(ctx, req.Identifier, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
 		return
 	}
 
-	err = usecase.userRepository.InsertUser(ctx, *userEntity)
+	if temporaryUser == nil {
+		res.BadRequest("Pendaftaran tidak ditemukan atau kedaluwarsa, silakan daftar ulang", nil)
+		return
+	}
+
+	verificationToken, err := utils.GenerateRandomString(32)
 	if err != nil {
 		res.InternalServerError(err.Error())
 		return
 	}
 
-	accessToken, _ = auth.GenerateJWT(*userEntity, "ACCESS")
-	refreshToken, _ = auth.GenerateJWT(*userEntity, "REFRESH")
+	temporaryUser.VerificationToken = verificationToken
+	temporaryUser.ExpiredAt = int(now.Add(user.TEMPORARY_USER_LIFETIME).Unix())
+	temporaryUser.PurgeAt = now.Add(user.TEMPORARY_USER_LIFETIME)
 
-	res.SuccessWithCookie("success", auth.AuthenticationResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, []*http.Cookie{
-		{
-			Name:     usecase.config.AccessTokenKey,
-			Value:    accessToken,
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Hour * 24 * 31),
-		},
-		{
-			Name:     usecase.config.RefreshTokenKey,
-			Value:    accessToken,
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Hour * 24 * 31),
-		},
-	})
+	err = usecase.userRepository.RenewTemporaryUserVerification(ctx, temporaryUser.UID, temporaryUser.VerificationToken, temporaryUser.ExpiredAt, temporaryUser.PurgeAt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
 
-	return res
+	err = usecase.sendVerificationLink(ctx, *temporaryUser)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if temporaryUser.InquiryID != nil {
+		res.SuccessWithMessage("Link verifikasi dikirimkan ke WhatsApp Anda")
+		return
+	}
+
+	res.SuccessWithMessage("Link verifikasi dikirimkan ke email Anda")
+	return
+}
+
+// sendVerificationLink sends the link of a pending registration, on WhatsApp when
+// it was made from an inquiry and by email otherwise
+func (usecase *authUsecase) sendVerificationLink(ctx context.Context, temporaryUser user.TemporaryUserEntity) error {
+	if temporaryUser.InquiryID != nil && temporaryUser.PhoneNumber != nil {
+		return usecase.notificationService.SendWhatsAppMessage(ctx,
+			fmt.Sprintf("Halo %s,\nBerikut adalah link verifikasi akun Anda, %s", temporaryUser.Name, "https://"+usecase.config.AppDomain+"/verify-account?token="+temporaryUser.VerificationToken+"&redirect=inquiry&inquiry_id="+*temporaryUser.InquiryID), *temporaryUser.PhoneNumber)
+	}
+
+	go infrastructure.SendEmailVerificationLink(temporaryUser.Email, temporaryUser.Name, temporaryUser.VerificationToken, usecase.config.AppDomain)
+	return nil
+}
+
+func (usecase *authUsecase) GetSessions(ctx context.Context, userID string, currentSessionID string) (res response.Response[[]auth.SessionDTO]) {
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	sessions, err := usecase.sessionRepository.GetActiveSessionsByUserID(ctx, userID, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	result := []auth.SessionDTO{}
+	for _, session := range sessions {
+		result = append(result, session.ToSessionDTO(currentSessionID))
+	}
+
+	res.Success(result)
+	return
+}
+
+func (usecase *authUsecase) RevokeSession(ctx context.Context, userID string, sessionID string) (res response.Response[string]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_SESSION_REVOKE, "")
+	event.Identifier = sessionID
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	session, err := usecase.sessionRepository.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if session == nil || session.UserID != userID {
+		res.NotFound("Sesi tidak ditemukan", nil)
+		return
+	}
+
+	now, _ := utils.GetJktTime()
+	err = usecase.sessionRepository.RevokeSession(ctx, session.ID, auth.SESSION_REVOKED_BY_USER, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithMessage("Sesi diakhiri")
+	return
+}
+
+func (usecase *authUsecase) RevokeOtherSessions(ctx context.Context, userID string, currentSessionID string) (res response.Response[string]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_SESSION_REVOKE, "")
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	now, _ := utils.GetJktTime()
+	err := usecase.sessionRepository.RevokeUserSessions(ctx, userID, currentSessionID, auth.SESSION_REVOKED_BY_USER, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithMessage("Semua sesi lain diakhiri")
+	return
 }
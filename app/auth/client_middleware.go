@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	_auth "mini-wallet/domain/auth"
+	"mini-wallet/utils"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	MAX_USER_AGENT_LENGTH = 512
+)
+
+// clientIPPolicy resolves the caller IP. Sessions, audit events, known devices and
+// the per IP limits all rely on it, so client supplied headers are only read when
+// the connection comes from a trusted proxy.
+type clientIPPolicy struct {
+	trustedProxies []*net.IPNet
+}
+
+func newClientIPPolicy(config *utils.AppConfig) (*clientIPPolicy, error) {
+	policy := &clientIPPolicy{
+		trustedProxies: []*net.IPNet{},
+	}
+
+	for _, proxy := range splitConfigList(config.TrustedProxies) {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+
+			policy.trustedProxies = append(policy.trustedProxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+
+		policy.trustedProxies = append(policy.trustedProxies, network)
+	}
+
+	return policy, nil
+}
+
+func (policy *clientIPPolicy) isTrusted(ip net.IP) bool {
+	for _, network := range policy.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP walks X-Forwarded-For from the right, the hops appended by the trusted
+// proxies, and stops at the first address none of them is. Anything left of it was
+// written by the client.
+func (policy *clientIPPolicy) clientIP(r *http.Request) string {
+	ipAddress, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ipAddress = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(ipAddress)
+	if ip == nil || !policy.isTrusted(ip) {
+		return ipAddress
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+
+		ipAddress = hop.String()
+		if !policy.isTrusted(hop) {
+			break
+		}
+	}
+
+	return ipAddress
+}
+
+// ClientInfoMiddleware stores the caller IP & user agent in the request context.
+// Behind a reverse proxy the proxy has to be listed in TRUSTED_PROXIES.
+func ClientInfoMiddleware(config *utils.AppConfig) (func(next http.Handler) http.Handler, error) {
+	policy, err := newClientIPPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userAgent := r.UserAgent()
+			if len(userAgent) > MAX_USER_AGENT_LENGTH {
+				userAgent = userAgent[:MAX_USER_AGENT_LENGTH]
+			}
+
+			ctx := context.WithValue(r.Context(), _auth.ClientInfoContext{}, _auth.ClientInfo{
+				IPAddress: policy.clientIP(r),
+				UserAgent: userAgent,
+			})
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}, nil
+}
+
+// DeviceMiddleware adds the device id to the client info, a value that is not a
+// device id given by signIn is ignored
+func (middleware *authMiddleware) DeviceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deviceID, found := middleware.tokenTransport.deviceID(r)
+		if !found || len(deviceID) != _auth.DEVICE_ID_SIZE {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		clientInfo := _auth.GetClientInfo(r.Context())
+		clientInfo.DeviceID = deviceID
+		ctx := context.WithValue(r.Context(), _auth.ClientInfoContext{}, clientInfo)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
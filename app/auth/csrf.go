@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	_auth "mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/utils"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// csrfPolicy decides which requests are checked and where they may come from
+type csrfPolicy struct {
+	trustedOrigins []string
+	exemptPaths    []string
+}
+
+func newCsrfPolicy(config *utils.AppConfig) *csrfPolicy {
+	policy := &csrfPolicy{
+		trustedOrigins: splitConfigList(config.CsrfTrustedOrigins),
+		exemptPaths:    splitConfigList(config.CsrfExemptPaths),
+	}
+
+	if len(policy.trustedOrigins) == 0 {
+		policy.trustedOrigins = []string{"https://" + config.AppDomain}
+	}
+
+	if len(policy.exemptPaths) == 0 {
+		// called by midtrans, not by a browser
+		policy.exemptPaths = []string{"/payments/callback"}
+	}
+
+	return policy
+}
+
+func splitConfigList(value string) []string {
+	result := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) != "" {
+			result = append(result, strings.TrimSpace(item))
+		}
+	}
+
+	return result
+}
+
+func (policy *csrfPolicy) isExempt(path string) bool {
+	for _, exemptPath := range policy.exemptPaths {
+		if path == exemptPath || strings.HasPrefix(path, strings.TrimSuffix(exemptPath, "/")+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isTrustedOrigin checks the Origin header, or the origin of the Referer when
+// the browser sent no Origin. A request with neither is not trusted.
+func (policy *csrfPolicy) isTrustedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		referer, err := url.Parse(r.Header.Get("Referer"))
+		if err != nil || referer.Scheme == "" || referer.Host == "" {
+			return false
+		}
+
+		origin = referer.Scheme + "://" + referer.Host
+	}
+
+	return slices.Contains(policy.trustedOrigins, origin)
+}
+
+// CsrfMiddleware guards every state changing request authenticated by the session
+// cookies: it must come from a trusted origin and repeat the CSRF cookie in
+// CSRF_HEADER, signed by the secret of its session. Bearer requests (access tokens,
+// API keys) can not be forged by another site and are not checked, neither are the
+// cookies of an ended session: they authenticate nothing and are cleared, so the
+// browser can sign in again.
+func (middleware *authMiddleware) CsrfMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if _, found := getBearerToken(r); found || middleware.csrfPolicy.isExempt(r.URL.Path) || !middleware.tokenTransport.hasSessionCookie(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		resp := &response.Response[string]{
+			Writer: w,
+		}
+
+		csrfSecret := ""
+		if sessionID := middleware.getCookieSessionID(r); sessionID != "" {
+			var err error
+			csrfSecret, err = middleware.sessionManager.getCsrfSecret(r.Context(), sessionID)
+			if err != nil {
+				resp.InternalServerError(err.Error())
+				resp.WriteResponse()
+				return
+			}
+		}
+
+		if csrfSecret == "" {
+			for _, cookie := range middleware.tokenTransport.expiredSessionCookies() {
+				http.SetCookie(w, cookie)
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !middleware.verifyCsrf(r, csrfSecret) {
+			resp.Forbidden(_auth.CSRF_REJECTED_MESSAGE, nil)
+			resp.WriteResponse()
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// verifyCsrf checks the request against the CSRF secret of its active session
+func (middleware *authMiddleware) verifyCsrf(r *http.Request, csrfSecret string) bool {
+	if !middleware.csrfPolicy.isTrustedOrigin(r) {
+		return false
+	}
+
+	csrfToken := r.Header.Get(_auth.CSRF_HEADER)
+	csrfCookie, found := middleware.tokenTransport.cookieValue(r, _auth.CSRF_COOKIE)
+	if !found || csrfToken == "" || subtle.ConstantTimeCompare([]byte(csrfToken), []byte(csrfCookie)) != 1 {
+		return false
+	}
+
+	return _auth.VerifyCsrfToken(csrfSecret, csrfToken)
+}
+
+// getCookieSessionID reads the session of the access token cookie, expired ones
+// included, or of the refresh token cookie
+func (middleware *authMiddleware) getCookieSessionID(r *http.Request) string {
+	if accessToken, found := middleware.tokenTransport.cookieValue(r, middleware.tokenTransport.accessTokenName); found {
+		claims, status := _auth.ValidateToken(accessToken)
+		if status != _auth.ERROR_INVALID_TOKEN && claims.TokenType == _auth.TOKEN_TYPE_ACCESS {
+			return claims.SessionID
+		}
+	}
+
+	if refreshToken, found := middleware.tokenTransport.refreshToken(r); found {
+		claims, status := _auth.ValidateToken(refreshToken)
+		if status != _auth.ERROR_INVALID_TOKEN && claims.TokenType == _auth.TOKEN_TYPE_REFRESH {
+			return claims.SessionID
+		}
+	}
+
+	return ""
+}
+
+func (usecase *authUsecase) GetCsrfToken(ctx context.Context, sessionID string) (res response.Response[_auth.CsrfTokenDTO]) {
+	if sessionID == "" {
+		res.BadRequest("Token CSRF hanya untuk sesi browser", nil)
+		return
+	}
+
+	csrfSecret, err := usecase.sessionManager.getCsrfSecret(ctx, sessionID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if csrfSecret == "" {
+		res.Unauthorized(_auth.ErrSessionRevoked.Error())
+		return
+	}
+
+	csrfToken, err := _auth.GenerateCsrfToken(csrfSecret)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithCookie("success", _auth.CsrfTokenDTO{
+		CsrfToken: csrfToken,
+	}, []*http.Cookie{usecase.tokenTransport.csrfCookie(csrfToken)})
+	return
+}
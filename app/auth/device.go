@@ -0,0 +1,300 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	_audit "mini-wallet/app/audit"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/user"
+	"mini-wallet/infrastructure"
+	"mini-wallet/utils"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// signIn weighs the risk signals of a login and starts its session, every login
+// method ends here. The device is tracked first so a failure leaves no session
+// behind.
+func (usecase *authUsecase) signIn(ctx context.Context, userEntity user.UserEntity) (*auth.AuthenticationResponse, error) {
+	deviceID, err := usecase.trackDevice(ctx, userEntity)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := usecase.sessionManager.startSession(ctx, userEntity)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens.DeviceID = deviceID
+	return tokens, nil
+}
+
+// trackDevice compares the login with the known devices of the user and
+// remembers the device, giving a device id to devices without one. The user is
+// alerted of a device they never signed in on.
+func (usecase *authUsecase) trackDevice(ctx context.Context, userEntity user.UserEntity) (string, error) {
+	clientInfo := auth.GetClientInfo(ctx)
+	deviceID := clientInfo.DeviceID
+	if deviceID == "" {
+		var err error
+		deviceID, err = utils.GenerateRandomString(auth.DEVICE_ID_SIZE)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return "", err
+	}
+
+	devices, err := usecase.knownDeviceRepository.GetKnownDevicesByUserID(ctx, userEntity.UID)
+	if err != nil {
+		return "", err
+	}
+
+	deviceHash := utils.HashToken(deviceID)
+	knownDevice := auth.FindKnownDevice(devices, deviceHash)
+	signals := auth.EvaluateLoginSignals(devices, knownDevice, clientInfo, now.Unix())
+
+	if knownDevice != nil {
+		err = usecase.knownDeviceRepository.TouchKnownDevice(ctx, knownDevice.ID, clientInfo, now.Unix())
+		if err != nil {
+			return "", err
+		}
+
+		return deviceID, nil
+	}
+
+	device := auth.KnownDeviceEntity{
+		ID:          utils.GenerateUniqueId(),
+		UserID:      userEntity.UID,
+		DeviceHash:  deviceHash,
+		UserAgent:   clientInfo.UserAgent,
+		IPAddress:   clientInfo.IPAddress,
+		CreatedAt:   now.Unix(),
+		LastLoginAt: now.Unix(),
+	}
+
+	err = usecase.knownDeviceRepository.InsertKnownDevice(ctx, device)
+	if err != nil {
+		return "", err
+	}
+
+	if signals.ShouldAlert() {
+		err = usecase.alertNewDevice(ctx, userEntity, device, signals, *now)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return deviceID, nil
+}
+
+// alertNewDevice audits the login and sends the owner a "this wasn't me" link
+// through email and WhatsApp
+func (usecase *authUsecase) alertNewDevice(ctx context.Context, userEntity user.UserEntity, device auth.KnownDeviceEntity, signals auth.LoginSignals, loginAt time.Time) error {
+	eventType := audit.EVENT_NEW_DEVICE_LOGIN
+	title := "Login dari Perangkat Baru"
+	if signals.Suspicious() {
+		eventType = audit.EVENT_SUSPICIOUS_LOGIN
+		title = "Aktivitas Masuk Mencurigakan"
+	}
+
+	event := _audit.NewAuditEvent(ctx, eventType, "")
+	event.UserID = userEntity.UID
+	event.ActorID = userEntity.UID
+	event.Identifier = device.ID
+	event.Outcome = audit.OUTCOME_SUCCESS
+	event.StatusCode = http.StatusOK
+	usecase.auditor.Record(ctx, event)
+
+	secret, err := utils.GenerateRandomString(auth.MAGIC_LINK_SECRET_SIZE)
+	if err != nil {
+		return err
+	}
+
+	report := auth.OneTimeCodeEntity{
+		ID:          utils.GenerateUniqueId(),
+		Purpose:     auth.ONE_TIME_CODE_PURPOSE_LOGIN_REPORT,
+		Destination: device.ID,
+		Target:      userEntity.UID,
+		CreatedAt:   loginAt.Unix(),
+		ExpiredAt:   loginAt.Add(auth.LOGIN_REPORT_LIFETIME).Unix(),
+	}
+	report.CodeHash = auth.HashOneTimeCode(report.ID, secret)
+
+	err = usecase.oneTimeCodeRepository.UpsertCode(ctx, report)
+	if err != nil {
+		return err
+	}
+
+	link := "https://" + usecase.config.AppDomain + "/login/report?token=" + url.QueryEscape(report.ID+"."+secret)
+	go usecase.notifyNewDevice(userEntity, device, title, link, loginAt)
+
+	return nil
+}
+
+func (usecase *authUsecase) notifyNewDevice(userEntity user.UserEntity, device auth.KnownDeviceEntity, title string, reportLink string, loginAt time.Time) {
+	formattedTime := loginAt.Format("02-01-2006 15:04 WIB")
+	infrastructure.SendNewDeviceAlert(userEntity.Email, userEntity.Name, title, device.UserAgent, device.IPAddress, formattedTime, reportLink, usecase.config.AppDomain)
+
+	if userEntity.PhoneNumber != nil {
+		err := usecase.notificationService.SendWhatsAppMessage(context.Background(),
+			fmt.Sprintf("Halo %s,\n%s: akun Sebia Anda masuk dari perangkat baru (%s, IP %s) pada %s. Jika ini bukan Anda, buka link berikut untuk mengeluarkan semua sesi dan mengatur ulang kata sandi: %s", userEntity.Name, title, device.UserAgent, device.IPAddress, formattedTime, reportLink), *userEntity.PhoneNumber)
+		if err != nil {
+			fmt.Println("error sending new device notification:", err.Error())
+		}
+	}
+}
+
+func (usecase *authUsecase) GetKnownDevices(ctx context.Context, userID string) (res response.Response[[]auth.KnownDeviceDTO]) {
+	devices, err := usecase.knownDeviceRepository.GetKnownDevicesByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	currentDeviceHash := ""
+	if deviceID := auth.GetClientInfo(ctx).DeviceID; deviceID != "" {
+		currentDeviceHash = utils.HashToken(deviceID)
+	}
+
+	result := []auth.KnownDeviceDTO{}
+	for _, device := range devices {
+		result = append(result, device.ToKnownDeviceDTO(currentDeviceHash))
+	}
+
+	res.Success(result)
+	return
+}
+
+func (usecase *authUsecase) DeleteKnownDevice(ctx context.Context, userID string, deviceID string) (res response.Response[string]) {
+	deleted, err := usecase.knownDeviceRepository.DeleteKnownDevice(ctx, userID, deviceID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !deleted {
+		res.NotFound("Perangkat tidak ditemukan", nil)
+		return
+	}
+
+	res.SuccessWithMessage("Perangkat dihapus")
+	return
+}
+
+// ReportUnrecognizedLogin signs the user out everywhere and forgets the reported
+// device. Users with a password can only sign in with it again after resetting
+// it, the reset link is sent right away.
+func (usecase *authUsecase) ReportUnrecognizedLogin(ctx context.Context, req auth.LoginReportDTO) (res response.Response[string]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_LOGIN_REPORT, "")
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	ipAttempt := newAttempt(loginIPPolicy, auth.GetClientInfo(ctx).IPAddress)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, ipAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	id, secret, found := strings.Cut(req.Token, ".")
+	if !found {
+		res.BadRequest("Link sudah digunakan atau kedaluwarsa", nil)
+		return
+	}
+
+	report, err := usecase.oneTimeCodeRepository.GetCodeByID(ctx, id, auth.ONE_TIME_CODE_PURPOSE_LOGIN_REPORT, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if report == nil || subtle.ConstantTimeCompare([]byte(auth.HashOneTimeCode(report.ID, secret)), []byte(report.CodeHash)) != 1 {
+		err = usecase.recordFailedLogin(ctx, nil, ipAttempt)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		res.BadRequest("Link sudah digunakan atau kedaluwarsa", nil)
+		return
+	}
+
+	deleted, err := usecase.oneTimeCodeRepository.DeleteCode(ctx, report.ID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !deleted {
+		res.BadRequest("Link sudah digunakan atau kedaluwarsa", nil)
+		return
+	}
+
+	event.UserID = report.Target
+	event.ActorID = report.Target
+	event.Identifier = report.Destination
+
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, report.Target)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	err = usecase.sessionRepository.RevokeUserSessions(ctx, existingUser.UID, "", auth.SESSION_REVOKED_LOGIN_REPORTED, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	// a later login on the device is alerted again
+	_, err = usecase.knownDeviceRepository.DeleteKnownDevice(ctx, existingUser.UID, report.Destination)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser.HashedPassword == nil {
+		res.SuccessWithMessage("Semua sesi Anda telah dikeluarkan")
+		return
+	}
+
+	err = usecase.userRepository.RequirePasswordReset(ctx, existingUser.UID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	err = usecase.issuePasswordResetLink(ctx, *existingUser)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithMessage("Semua sesi Anda telah dikeluarkan, atur ulang kata sandi melalui link yang kami kirimkan ke email Anda")
+	return
+}
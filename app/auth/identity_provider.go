@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	_audit "mini-wallet/app/audit"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/user"
+	"mini-wallet/integration"
+	"mini-wallet/utils"
+	"strings"
+	"time"
+)
+
+var (
+	errUnsupportedProvider     = errors.New("unsupported identity provider")
+	errUnverifiedProviderEmail = errors.New("identity provider email not verified")
+)
+
+func (usecase *authUsecase) AuthenticateWithProvider(ctx context.Context, provider string, req auth.ProviderAuthenticationDTO) (res response.Response[auth.AuthenticationResponse]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_LOGIN, provider)
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	identity, err := usecase.verifyIdentity(ctx, provider, req)
+	if err != nil {
+		identityErrorResponse(&res, provider, err)
+		return
+	}
+
+	event.Identifier = identity.Email
+
+	existingUser, res := usecase.findUserByIdentity(ctx, *identity)
+	if res.StatusCode != 0 {
+		return
+	}
+
+	if existingUser == nil {
+		res.BadRequest("Akun belum terdaftar, lanjutkan pendaftaran", nil)
+		return
+	}
+
+	event.UserID = existingUser.UID
+	return usecase.completeSignIn(ctx, *existingUser)
+}
+
+func (usecase *authUsecase) RegisterWithProvider(ctx context.Context, provider string, req auth.ProviderAuthenticationDTO) (res response.Response[auth.AuthenticationResponse]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_REGISTER, provider)
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	identity, err := usecase.verifyIdentity(ctx, provider, req)
+	if err != nil {
+		identityErrorResponse(&res, provider, err)
+		return
+	}
+
+	event.Identifier = identity.Email
+
+	existingUser, res := usecase.findUserByIdentity(ctx, *identity)
+	if res.StatusCode != 0 {
+		return
+	}
+
+	if existingUser != nil {
+		event.UserID = existingUser.UID
+		return usecase.completeSignIn(ctx, *existingUser)
+	}
+
+	userEntity, err := newUserFromIdentity(*identity, req.Name)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	event.UserID = userEntity.UID
+	err = usecase.userRepository.InsertUser(ctx, *userEntity)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	return usecase.completeSignIn(ctx, *userEntity)
+}
+
+// findUserByIdentity returns the user the identity is linked to. An account that
+// merely shares the email is refused, the owner has to sign in and link it first.
+// Accounts created by the former google sign in predate linking and are linked here.
+func (usecase *authUsecase) findUserByIdentity(ctx context.Context, identity integration.ExternalIdentity) (userEntity *user.UserEntity, res response.Response[auth.AuthenticationResponse]) {
+	userEntity, err := usecase.userRepository.GetUserByIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if userEntity != nil {
+		return
+	}
+
+	userEntity, err = usecase.userRepository.GetUserByEmail(ctx, identity.Email)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if userEntity == nil {
+		return
+	}
+
+	isLegacyGoogleAccount := identity.Provider == integration.PROVIDER_GOOGLE &&
+		userEntity.HashedPassword == nil &&
+		len(userEntity.Identities) == 0
+	if !isLegacyGoogleAccount {
+		res.BadRequest("Email sudah terdaftar, masuk dengan metode lain lalu hubungkan akun "+identity.Provider+" dari pengaturan akun", nil)
+		return nil, res
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return nil, res
+	}
+
+	_, err = usecase.userRepository.LinkIdentity(ctx, userEntity.UID, toLinkedIdentityEntity(identity, now.Unix()))
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return nil, res
+	}
+
+	return userEntity, res
+}
+
+// verifyIdentity checks the credential with the provider, see identityErrorResponse for the errors
+func (usecase *authUsecase) verifyIdentity(ctx context.Context, provider string, req auth.ProviderAuthenticationDTO) (*integration.ExternalIdentity, error) {
+	identityProvider, ok := usecase.identityProviders[provider]
+	if !ok {
+		return nil, errUnsupportedProvider
+	}
+
+	identity, err := identityProvider.Verify(ctx, req.ToProviderCredential())
+	if err != nil {
+		return nil, err
+	}
+
+	// the email is what ties the identity to a user, an unverified one could belong to anybody
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errUnverifiedProviderEmail
+	}
+
+	identity.Email = strings.ToLower(identity.Email)
+	return identity, nil
+}
+
+func identityErrorResponse[T any](res *response.Response[T], provider string, err error) {
+	switch err {
+	case errUnsupportedProvider:
+		res.NotFound("Metode masuk tidak didukung", nil)
+	case integration.ErrInvalidProviderCredential:
+		res.Unauthorized("Kredensial " + provider + " tidak valid")
+	case errUnverifiedProviderEmail:
+		res.BadRequest("Email akun "+provider+" belum terverifikasi", nil)
+	default:
+		res.InternalServerError(err.Error())
+	}
+}
+
+// completeSignIn asks for the second factor when enabled, otherwise starts the session
+func (usecase *authUsecase) completeSignIn(ctx context.Context, userEntity user.UserEntity) (res response.Response[auth.AuthenticationResponse]) {
+	if userEntity.IsTwoFactorEnabled() {
+		return usecase.twoFactorChallenge(userEntity)
+	}
+
+	tokens, err := usecase.signIn(ctx, userEntity)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithCookie("success", *tokens, usecase.tokenTransport.sessionCookies(*tokens))
+
+	return res
+}
+
+func newUserFromIdentity(identity integration.ExternalIdentity, fallbackName string) (*user.UserEntity, error) {
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return nil, err
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = strings.TrimSpace(fallbackName)
+	}
+
+	if name == "" {
+		name = strings.Split(identity.Email, "@")[0]
+	}
+
+	nowString := now.Format(time.RFC3339)
+	return &user.UserEntity{
+		UID:             utils.GenerateUniqueId(),
+		Name:            name,
+		Email:           identity.Email,
+		EmailVerifiedAt: nowString,
+		CreatedAt:       nowString,
+		UpdatedAt:       nowString,
+		Identities: []user.LinkedIdentityEntity{
+			toLinkedIdentityEntity(identity, now.Unix()),
+		},
+	}, nil
+}
+
+func toLinkedIdentityEntity(identity integration.ExternalIdentity, now int64) user.LinkedIdentityEntity {
+	return user.LinkedIdentityEntity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+		LinkedAt: now,
+	}
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	_audit "mini-wallet/app/audit"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/utils"
+	"slices"
+)
+
+// StartImpersonation lets an admin see the app as the user does. The reason is
+// kept on the audit event, the impersonation session is its identifier.
+func (usecase *authUsecase) StartImpersonation(ctx context.Context, actorID string, userID string, req auth.ImpersonationRequestDTO) (res response.Response[auth.ImpersonationDTO]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_IMPERSONATION_START, "")
+	event.UserID = userID
+	event.Reason = req.Reason
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	if actorID == userID {
+		res.BadRequest("Tidak dapat menyamar sebagai diri sendiri", nil)
+		return
+	}
+
+	actor, err := usecase.userRepository.GetUserByUserID(ctx, actorID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if actor == nil {
+		res.Unauthorized(response.ERROR_UNAUTHORIZED)
+		return
+	}
+
+	subject, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if subject == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	// the token carries the roles of the subject, staff roles are never handed over
+	roles := auth.GetUserRoles(*subject)
+	if slices.Contains(roles, auth.ROLE_ADMIN) || slices.Contains(roles, auth.ROLE_SUPPORT) {
+		res.Forbidden("Akun staf tidak dapat disamarkan", nil)
+		return
+	}
+
+	impersonation, err := usecase.sessionManager.startImpersonation(ctx, *actor, *subject)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	event.Identifier = impersonation.SessionID
+	res.Success(*impersonation)
+	return
+}
+
+func (usecase *authUsecase) EndImpersonation(ctx context.Context, sessionID string) (res response.Response[string]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_IMPERSONATION_END, "")
+	event.Identifier = sessionID
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	if auth.GetImpersonatorID(ctx) == "" {
+		res.BadRequest("Tidak sedang menyamar sebagai pengguna", nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	err = usecase.sessionRepository.RevokeSession(ctx, sessionID, auth.SESSION_REVOKED_IMPERSONATION, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithMessage("Penyamaran berakhir")
+	return
+}
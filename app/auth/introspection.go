@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/oauth"
+	"mini-wallet/domain/user"
+	"mini-wallet/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// introspectedClaims decodes the access tokens of users as well as the ones
+// given to OAuth clients, both are bound to a session
+type introspectedClaims struct {
+	jwt.RegisteredClaims
+	SessionID string           `json:"sid,omitempty"`
+	TokenType string           `json:"token_type,omitempty"`
+	Scope     string           `json:"scope,omitempty"`
+	ClientID  string           `json:"client_id,omitempty"`
+	Actor     *auth.TokenActor `json:"act,omitempty"`
+}
+
+func (usecase *authUsecase) IntrospectToken(ctx context.Context, token string) (res response.Response[auth.TokenIntrospectionDTO]) {
+	inactive := auth.TokenIntrospectionDTO{Active: false}
+
+	claims := &introspectedClaims{}
+	status := auth.ParseClaims(token, claims)
+	if status != 0 || claims.SessionID == "" || (claims.TokenType != auth.TOKEN_TYPE_ACCESS && claims.TokenType != oauth.TOKEN_TYPE_OAUTH_ACCESS) {
+		res.Success(inactive)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	// unlike AuthMiddleware this does not touch the session, the caller is a service, not the user
+	session, err := usecase.sessionRepository.GetSessionByID(ctx, claims.SessionID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if session == nil || session.UserID != claims.Subject || !session.IsActive(now.Unix()) {
+		res.Success(inactive)
+		return
+	}
+
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, claims.Subject)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.Success(inactive)
+		return
+	}
+
+	result := auth.TokenIntrospectionDTO{
+		Active:    true,
+		TokenType: "Bearer",
+		Subject:   claims.Subject,
+		Issuer:    claims.Issuer,
+		TokenID:   claims.ID,
+		SessionID: claims.SessionID,
+		Actor:     claims.Actor,
+	}
+
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Unix()
+	}
+
+	if claims.ExpiresAt != nil {
+		result.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+
+	if claims.TokenType == oauth.TOKEN_TYPE_OAUTH_ACCESS {
+		result.Scope = claims.Scope
+		result.ClientID = claims.ClientID
+	} else {
+		// the current roles, the ones in the token may be outdated
+		result.Roles = auth.GetUserRoles(*existingUser)
+	}
+
+	res.Success(result)
+	return
+}
+
+func (usecase *authUsecase) GetUserSummary(ctx context.Context, userID string) (res response.Response[auth.UserSummaryDTO]) {
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	res.Success(newUserSummary(*existingUser))
+	return
+}
+
+func (usecase *authUsecase) CheckPermission(ctx context.Context, userID string, permission string) (res response.Response[auth.PermissionCheckDTO]) {
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	res.Success(auth.PermissionCheckDTO{
+		Allowed: auth.HasPermission(auth.GetUserRoles(*existingUser), permission),
+	})
+	return
+}
+
+func newUserSummary(userEntity user.UserEntity) auth.UserSummaryDTO {
+	summary := auth.UserSummaryDTO{
+		ID:                  userEntity.UID,
+		Name:                userEntity.Name,
+		Email:               userEntity.Email,
+		EmailVerified:       userEntity.EmailVerifiedAt != "",
+		PhoneNumberVerified: userEntity.PhoneNumberVerifiedAt != nil,
+		Roles:               auth.GetUserRoles(userEntity),
+	}
+
+	if userEntity.PhoneNumber != nil {
+		summary.PhoneNumber = *userEntity.PhoneNumber
+	}
+
+	return summary
+}
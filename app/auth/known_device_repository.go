@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"mini-wallet/domain"
+	"mini-wallet/domain/auth"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type knownDeviceRepository struct {
+	knownDeviceCollection *mongo.Collection
+}
+
+func NewKnownDeviceRepository(repositoryParam domain.RepositoryParam) auth.KnownDeviceRepository {
+	return &knownDeviceRepository{
+		knownDeviceCollection: repositoryParam.Mongo.Collection("known_device"),
+	}
+}
+
+func (repository *knownDeviceRepository) InsertKnownDevice(ctx context.Context, device auth.KnownDeviceEntity) (err error) {
+	_, err = repository.knownDeviceCollection.InsertOne(ctx, device)
+	return err
+}
+
+func (repository *knownDeviceRepository) GetKnownDevicesByUserID(ctx context.Context, userID string) (res []auth.KnownDeviceEntity, err error) {
+	filter := bson.M{"user_id": userID}
+	opts := options.Find().SetSort(bson.D{
+		{
+			Key:   "last_login_at",
+			Value: -1,
+		},
+	})
+
+	result, err := repository.knownDeviceCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res = []auth.KnownDeviceEntity{}
+	err = result.All(ctx, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (repository *knownDeviceRepository) TouchKnownDevice(ctx context.Context, id string, clientInfo auth.ClientInfo, now int64) (err error) {
+	filter := bson.M{"id": id}
+
+	update := bson.M{"$set": bson.M{
+		"user_agent":    clientInfo.UserAgent,
+		"ip_address":    clientInfo.IPAddress,
+		"last_login_at": now,
+	}}
+
+	_, err = repository.knownDeviceCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (repository *knownDeviceRepository) DeleteKnownDevice(ctx context.Context, userID string, id string) (deleted bool, err error) {
+	filter := bson.M{
+		"id":      id,
+		"user_id": userID,
+	}
+
+	result, err := repository.knownDeviceCollection.DeleteOne(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount == 1, nil
+}
+
+func (repository *knownDeviceRepository) DeleteKnownDevicesByUserID(ctx context.Context, userID string) (err error) {
+	_, err = repository.knownDeviceCollection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
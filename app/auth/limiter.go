@@ -0,0 +1,321 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"mini-wallet/domain/user"
+	"mini-wallet/infrastructure"
+	"mini-wallet/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// attemptPolicy throttles repeated attempts made against one key (an
+// identifier, an IP address, a user). Attempts are counted over window as they
+// start, a failed one from backoffAfter on makes the next wait baseBackoff * 2^n,
+// and beyond lockoutAfter the key is blocked for lockoutDuration.
+type attemptPolicy struct {
+	name            string
+	window          time.Duration
+	backoffAfter    int64
+	baseBackoff     time.Duration
+	maxBackoff      time.Duration
+	lockoutAfter    int64
+	lockoutDuration time.Duration
+}
+
+var (
+	loginIdentifierPolicy = attemptPolicy{
+		name:            "login:identifier",
+		window:          time.Hour,
+		backoffAfter:    3,
+		baseBackoff:     time.Second,
+		maxBackoff:      5 * time.Minute,
+		lockoutAfter:    10,
+		lockoutDuration: 30 * time.Minute,
+	}
+	loginIPPolicy = attemptPolicy{
+		name:            "login:ip",
+		window:          time.Hour,
+		backoffAfter:    20,
+		baseBackoff:     time.Second,
+		maxBackoff:      5 * time.Minute,
+		lockoutAfter:    100,
+		lockoutDuration: time.Hour,
+	}
+	twoFactorPolicy = attemptPolicy{
+		name:            "2fa:user",
+		window:          15 * time.Minute,
+		backoffAfter:    3,
+		baseBackoff:     time.Second,
+		maxBackoff:      time.Minute,
+		lockoutAfter:    10,
+		lockoutDuration: 30 * time.Minute,
+	}
+	// every lookup counts, the endpoint answers whether an account exists
+	checkIdentifierIPPolicy = attemptPolicy{
+		name:            "check-identifier:ip",
+		window:          15 * time.Minute,
+		backoffAfter:    10,
+		baseBackoff:     time.Second,
+		maxBackoff:      time.Minute,
+		lockoutAfter:    30,
+		lockoutDuration: 30 * time.Minute,
+	}
+	passwordResetEmailPolicy = attemptPolicy{
+		name:            "password-reset:email",
+		window:          time.Hour,
+		backoffAfter:    1,
+		baseBackoff:     time.Minute,
+		maxBackoff:      15 * time.Minute,
+		lockoutAfter:    5,
+		lockoutDuration: time.Hour,
+	}
+	passwordResetIPPolicy = attemptPolicy{
+		name:            "password-reset:ip",
+		window:          time.Hour,
+		backoffAfter:    5,
+		baseBackoff:     10 * time.Second,
+		maxBackoff:      5 * time.Minute,
+		lockoutAfter:    20,
+		lockoutDuration: time.Hour,
+	}
+	// every request counts, each one sends a paid WhatsApp message
+	whatsAppCodePhonePolicy = attemptPolicy{
+		name:            "whatsapp-code:phone",
+		window:          time.Hour,
+		backoffAfter:    1,
+		baseBackoff:     30 * time.Second,
+		maxBackoff:      10 * time.Minute,
+		lockoutAfter:    6,
+		lockoutDuration: time.Hour,
+	}
+	magicLinkEmailPolicy = attemptPolicy{
+		name:            "magic-link:email",
+		window:          time.Hour,
+		backoffAfter:    1,
+		baseBackoff:     30 * time.Second,
+		maxBackoff:      10 * time.Minute,
+		lockoutAfter:    6,
+		lockoutDuration: time.Hour,
+	}
+	magicLinkIPPolicy = attemptPolicy{
+		name:            "magic-link:ip",
+		window:          time.Hour,
+		backoffAfter:    5,
+		baseBackoff:     10 * time.Second,
+		maxBackoff:      5 * time.Minute,
+		lockoutAfter:    20,
+		lockoutDuration: time.Hour,
+	}
+	// a resent link has to wait a minute, at most 5 are sent a day
+	verificationResendIdentifierPolicy = attemptPolicy{
+		name:            "verification-resend:identifier",
+		window:          24 * time.Hour,
+		backoffAfter:    1,
+		baseBackoff:     time.Minute,
+		maxBackoff:      time.Minute,
+		lockoutAfter:    5,
+		lockoutDuration: 24 * time.Hour,
+	}
+	verificationResendIPPolicy = attemptPolicy{
+		name:            "verification-resend:ip",
+		window:          time.Hour,
+		backoffAfter:    5,
+		baseBackoff:     10 * time.Second,
+		maxBackoff:      5 * time.Minute,
+		lockoutAfter:    20,
+		lockoutDuration: time.Hour,
+	}
+	whatsAppCodeIPPolicy = attemptPolicy{
+		name:            "whatsapp-code:ip",
+		window:          time.Hour,
+		backoffAfter:    5,
+		baseBackoff:     10 * time.Second,
+		maxBackoff:      5 * time.Minute,
+		lockoutAfter:    20,
+		lockoutDuration: time.Hour,
+	}
+)
+
+// delay is how long the key is blocked after its n-th attempt
+func (policy attemptPolicy) delay(attempts int64) time.Duration {
+	if attempts >= policy.lockoutAfter {
+		return policy.lockoutDuration
+	}
+
+	if attempts < policy.backoffAfter {
+		return 0
+	}
+
+	delay := float64(policy.baseBackoff) * math.Pow(2, float64(attempts-policy.backoffAfter))
+	if delay > float64(policy.maxBackoff) {
+		return policy.maxBackoff
+	}
+
+	return time.Duration(delay)
+}
+
+type attempt struct {
+	policy attemptPolicy
+	key    string
+}
+
+// keys are hashed, identifiers are personal data
+func (a attempt) counterKey() string {
+	return "attempt:" + a.policy.name + ":" + utils.HashToken(a.key)
+}
+
+func (a attempt) blockKey() string {
+	return "attempt-block:" + a.policy.name + ":" + utils.HashToken(a.key)
+}
+
+func newAttempt(policy attemptPolicy, key string) attempt {
+	return attempt{
+		policy: policy,
+		key:    strings.ToLower(strings.TrimSpace(key)),
+	}
+}
+
+type attemptLimiter struct {
+	cache infrastructure.Cache
+}
+
+func newAttemptLimiter(cache infrastructure.Cache) *attemptLimiter {
+	return &attemptLimiter{
+		cache: cache,
+	}
+}
+
+// check counts the attempts and returns how long the caller still has to wait, 0
+// when every attempt may go on. The counter is incremented before anything is
+// decided, so parallel attempts each get their own count and no more than
+// lockoutAfter of them ever go on, whatever the block says at that moment.
+func (limiter *attemptLimiter) check(ctx context.Context, attempts ...attempt) (time.Duration, error) {
+	var retryAfter time.Duration
+	for _, a := range attempts {
+		if a.key == "" {
+			continue
+		}
+
+		ttl, err := limiter.cache.TTL(ctx, a.blockKey())
+		if err != nil {
+			return 0, err
+		}
+
+		if wait := time.Duration(ttl) * time.Second; wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return retryAfter, nil
+	}
+
+	for _, a := range attempts {
+		if a.key == "" {
+			continue
+		}
+
+		count, err := limiter.cache.Incr(ctx, a.counterKey(), int(a.policy.window.Seconds()))
+		if err != nil {
+			return 0, err
+		}
+
+		if count <= a.policy.lockoutAfter {
+			continue
+		}
+
+		// the attempt that reached lockoutAfter may not have set the block yet
+		err = limiter.cache.SetString(ctx, a.blockKey(), "1", int(math.Ceil(a.policy.lockoutDuration.Seconds())))
+		if err != nil {
+			return 0, err
+		}
+
+		if a.policy.lockoutDuration > retryAfter {
+			retryAfter = a.policy.lockoutDuration
+		}
+	}
+
+	return retryAfter, nil
+}
+
+// record makes the key wait after a failed attempt counted by check, lockedOut is
+// true only for the attempt that reached the lockout
+func (limiter *attemptLimiter) record(ctx context.Context, a attempt) (lockedOut bool, err error) {
+	if a.key == "" {
+		return false, nil
+	}
+
+	value, err := limiter.cache.GetString(ctx, a.counterKey())
+	if err == infrastructure.ErrCacheMiss {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	count, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, err
+	}
+
+	delay := a.policy.delay(count)
+	if delay > 0 {
+		err = limiter.cache.SetString(ctx, a.blockKey(), "1", int(math.Ceil(delay.Seconds())))
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return count == a.policy.lockoutAfter, nil
+}
+
+func (limiter *attemptLimiter) reset(ctx context.Context, a attempt) error {
+	err := limiter.cache.Del(ctx, a.counterKey())
+	if err != nil {
+		return err
+	}
+
+	return limiter.cache.Del(ctx, a.blockKey())
+}
+
+func retryAfterSeconds(retryAfter time.Duration) int {
+	return int(math.Ceil(retryAfter.Seconds()))
+}
+
+func tooManyAttemptsMessage(retryAfter time.Duration) string {
+	return fmt.Sprintf("Terlalu banyak percobaan, silakan coba lagi dalam %d detik", retryAfterSeconds(retryAfter))
+}
+
+// recordFailedLogin counts a failed password or 2FA step, the owner of the
+// account (when it exists) is told once the identifier gets locked out
+func (usecase *authUsecase) recordFailedLogin(ctx context.Context, userEntity *user.UserEntity, attempts ...attempt) error {
+	for _, a := range attempts {
+		lockedOut, err := usecase.attemptLimiter.record(ctx, a)
+		if err != nil {
+			return err
+		}
+
+		if lockedOut && userEntity != nil && a.policy.name != loginIPPolicy.name {
+			go usecase.notifyLockout(*userEntity, a.policy.lockoutDuration)
+		}
+	}
+
+	return nil
+}
+
+func (usecase *authUsecase) notifyLockout(userEntity user.UserEntity, lockedFor time.Duration) {
+	infrastructure.SendAccountLockedNotice(userEntity.Email, userEntity.Name, lockedFor, usecase.config.AppDomain)
+
+	if userEntity.PhoneNumber != nil {
+		err := usecase.notificationService.SendWhatsAppMessage(context.Background(),
+			fmt.Sprintf("Halo %s,\nKami mendeteksi beberapa percobaan masuk yang gagal ke akun Anda, proses masuk dikunci selama %d menit. Jika ini bukan Anda, segera atur ulang kata sandi Anda.", userEntity.Name, int(lockedFor.Minutes())), *userEntity.PhoneNumber)
+		if err != nil {
+			fmt.Println("error sending lockout notification:", err.Error())
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"context"
+	_audit "mini-wallet/app/audit"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/user"
+	"mini-wallet/utils"
+	"strings"
+	"time"
+)
+
+const (
+	LAST_LOGIN_METHOD_MESSAGE = "Tidak dapat menghapus satu-satunya metode masuk, tambahkan metode lain terlebih dahulu"
+)
+
+func (usecase *authUsecase) GetLoginMethods(ctx context.Context, userID string) (res response.Response[auth.LoginMethodsDTO]) {
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	passkeys, err := usecase.passkeyRepository.GetPasskeysByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	identities := []auth.LinkedIdentityDTO{}
+	for _, identity := range existingUser.Identities {
+		identities = append(identities, auth.ToLinkedIdentityDTO(identity))
+	}
+
+	res.Success(auth.LoginMethodsDTO{
+		HasPassword: existingUser.HashedPassword != nil,
+		Identities:  identities,
+		Passkeys:    len(passkeys),
+		WhatsApp:    existingUser.PhoneNumber != nil,
+	})
+	return
+}
+
+func (usecase *authUsecase) LinkIdentity(ctx context.Context, userID string, provider string, req auth.ProviderAuthenticationDTO) (res response.Response[auth.LinkedIdentityDTO]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_IDENTITY_LINK, provider)
+	event.UserID = userID
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	identity, err := usecase.verifyIdentity(ctx, provider, req)
+	if err != nil {
+		identityErrorResponse(&res, provider, err)
+		return
+	}
+
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	owner, err := usecase.userRepository.GetUserByIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if owner != nil && owner.UID != userID {
+		res.BadRequest("Akun "+provider+" sudah terhubung dengan pengguna lain", nil)
+		return
+	}
+
+	if existingUser.GetIdentity(provider) != nil {
+		res.BadRequest("Akun "+provider+" sudah terhubung", nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	linkedIdentity := toLinkedIdentityEntity(*identity, now.Unix())
+	linked, err := usecase.userRepository.LinkIdentity(ctx, userID, linkedIdentity)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !linked {
+		res.BadRequest("Akun "+provider+" sudah terhubung", nil)
+		return
+	}
+
+	res.Success(auth.ToLinkedIdentityDTO(linkedIdentity))
+	return
+}
+
+func (usecase *authUsecase) UnlinkIdentity(ctx context.Context, userID string, provider string) (res response.Response[string]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_IDENTITY_UNLINK, provider)
+	event.UserID = userID
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	if existingUser.GetIdentity(provider) == nil {
+		res.NotFound("Akun "+provider+" tidak terhubung", nil)
+		return
+	}
+
+	loginMethods, err := usecase.countLoginMethods(ctx, *existingUser)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if loginMethods <= 1 {
+		res.BadRequest(LAST_LOGIN_METHOD_MESSAGE, nil)
+		return
+	}
+
+	unlinked, err := usecase.userRepository.UnlinkIdentity(ctx, userID, provider)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !unlinked {
+		res.NotFound("Akun "+provider+" tidak terhubung", nil)
+		return
+	}
+
+	res.SuccessWithMessage("Akun " + provider + " tidak lagi terhubung")
+	return
+}
+
+// SetPassword adds a password to an account that signs in with other methods only
+func (usecase *authUsecase) SetPassword(ctx context.Context, userID string, req auth.SetPasswordDTO) (res response.Response[string]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_PASSWORD_SET, audit.METHOD_PASSWORD)
+	event.UserID = userID
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	if existingUser.HashedPassword != nil {
+		res.BadRequest("Akun sudah memiliki kata sandi", nil)
+		return
+	}
+
+	err = checkPasswordPolicy(req.Password, existingUser.Name, existingUser.Email, existingUser.PhoneNumber)
+	if err != nil {
+		res.BadRequest(err.Error(), nil)
+		return
+	}
+
+	err = existingUser.ChangePassword(req.Password)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	set, err := usecase.userRepository.SetFirstPassword(ctx, userID, *existingUser.HashedPassword, now.Format(time.RFC3339))
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !set {
+		res.BadRequest("Akun sudah memiliki kata sandi", nil)
+		return
+	}
+
+	res.SuccessWithMessage("Kata sandi dibuat")
+	return
+}
+
+// countLoginMethods counts the password, every linked identity, every passkey
+// and the phone number (WhatsApp codes)
+func (usecase *authUsecase) countLoginMethods(ctx context.Context, userEntity user.UserEntity) (int, error) {
+	passkeys, err := usecase.passkeyRepository.GetPasskeysByUserID(ctx, userEntity.UID)
+	if err != nil {
+		return 0, err
+	}
+
+	count := len(userEntity.Identities) + len(passkeys)
+	if userEntity.HashedPassword != nil {
+		count++
+	}
+
+	if userEntity.PhoneNumber != nil {
+		count++
+	}
+
+	return count, nil
+}
+
+// noPasswordMessage points a user without a password to the methods they do have
+func noPasswordMessage(userEntity user.UserEntity) string {
+	providers := []string{}
+	for _, identity := range userEntity.Identities {
+		providers = append(providers, identity.Provider)
+	}
+
+	if len(providers) == 0 {
+		return "Akun belum memiliki kata sandi, silakan masuk dengan metode lain"
+	}
+
+	return "Akun belum memiliki kata sandi, silakan masuk menggunakan " + strings.Join(providers, " atau ")
+}
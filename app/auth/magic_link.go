@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	_audit "mini-wallet/app/audit"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/infrastructure"
+	"mini-wallet/utils"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RequestMagicLink emails a single use sign in link. The link only works in the
+// browser that asked for it, which gets a nonce cookie along with the response.
+func (usecase *authUsecase) RequestMagicLink(ctx context.Context, req auth.MagicLinkRequestDTO) (res response.Response[string]) {
+	emailAttempt := newAttempt(magicLinkEmailPolicy, req.Email)
+	ipAttempt := newAttempt(magicLinkIPPolicy, auth.GetClientInfo(ctx).IPAddress)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, emailAttempt, ipAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
+	for _, a := range []attempt{emailAttempt, ipAttempt} {
+		_, err = usecase.attemptLimiter.record(ctx, a)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+	}
+
+	existingUser, err := usecase.userRepository.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.BadRequest("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	secret, err := utils.GenerateRandomString(auth.MAGIC_LINK_SECRET_SIZE)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	nonce, err := utils.GenerateRandomString(auth.MAGIC_LINK_SECRET_SIZE)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	magicLink := auth.OneTimeCodeEntity{
+		ID:          utils.GenerateUniqueId(),
+		Purpose:     auth.ONE_TIME_CODE_PURPOSE_EMAIL_LOGIN,
+		Destination: existingUser.Email,
+		BindingHash: utils.HashToken(nonce),
+		CreatedAt:   now.Unix(),
+		ExpiredAt:   now.Add(auth.MAGIC_LINK_LIFETIME).Unix(),
+	}
+	magicLink.CodeHash = auth.HashOneTimeCode(magicLink.ID, secret)
+
+	// a new link replaces the previous one of the same email
+	err = usecase.oneTimeCodeRepository.UpsertCode(ctx, magicLink)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	link := "https://" + usecase.config.AppDomain + "/login/email?token=" + url.QueryEscape(magicLink.ID+"."+secret)
+	go infrastructure.SendMagicLink(existingUser.Email, existingUser.Name, link, auth.MAGIC_LINK_LIFETIME, usecase.config.AppDomain)
+
+	res.SuccessWithMessage("Link masuk dikirimkan ke email Anda")
+	res.Cookies = []*http.Cookie{
+		usecase.tokenTransport.cookie(auth.MAGIC_LINK_NONCE_COOKIE, nonce, now.Add(auth.MAGIC_LINK_LIFETIME)),
+	}
+	return
+}
+
+func (usecase *authUsecase) AuthenticateWithMagicLink(ctx context.Context, req auth.MagicLinkVerificationDTO) (res response.Response[auth.AuthenticationResponse]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_LOGIN, audit.METHOD_EMAIL_LINK)
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	ipAttempt := newAttempt(loginIPPolicy, auth.GetClientInfo(ctx).IPAddress)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, ipAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	id, secret, found := strings.Cut(req.Token, ".")
+	if !found {
+		res.BadRequest("Link sudah digunakan atau kedaluwarsa", nil)
+		return
+	}
+
+	magicLink, err := usecase.oneTimeCodeRepository.GetCodeByID(ctx, id, auth.ONE_TIME_CODE_PURPOSE_EMAIL_LOGIN, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if magicLink == nil || subtle.ConstantTimeCompare([]byte(auth.HashOneTimeCode(magicLink.ID, secret)), []byte(magicLink.CodeHash)) != 1 {
+		err = usecase.recordFailedLogin(ctx, nil, ipAttempt)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		res.BadRequest("Link sudah digunakan atau kedaluwarsa", nil)
+		return
+	}
+
+	event.Identifier = magicLink.Destination
+
+	// the link stays usable, the user may still open it in the right browser
+	if req.Nonce == "" || subtle.ConstantTimeCompare([]byte(utils.HashToken(req.Nonce)), []byte(magicLink.BindingHash)) != 1 {
+		res.BadRequest("Buka link di browser yang sama dengan tempat Anda memintanya", nil)
+		return
+	}
+
+	deleted, err := usecase.oneTimeCodeRepository.DeleteCode(ctx, magicLink.ID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !deleted {
+		res.BadRequest("Link sudah digunakan atau kedaluwarsa", nil)
+		return
+	}
+
+	existingUser, err := usecase.userRepository.GetUserByEmail(ctx, magicLink.Destination)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.BadRequest("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	event.UserID = existingUser.UID
+	res = usecase.completeSignIn(ctx, *existingUser)
+	res.Cookies = append(res.Cookies, usecase.tokenTransport.expiredCookie(auth.MAGIC_LINK_NONCE_COOKIE))
+	return
+}
@@ -2,117 +2,268 @@ package auth
 
 import (
 	"context"
-	"errors"
+	"log"
+	_audit "mini-wallet/app/audit"
 	"mini-wallet/domain"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/business"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/serviceaccount"
 	"mini-wallet/domain/user"
 	"mini-wallet/utils"
 	"net/http"
-	"time"
 
 	_auth "mini-wallet/domain/auth"
+
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 )
 
 type authMiddleware struct {
-	userRepository user.UserRepository
-	config         *utils.AppConfig
+	userRepository           user.UserRepository
+	businessRepository       business.BusinessRepository
+	serviceAccountRepository serviceaccount.ServiceAccountRepository
+	sessionManager           *sessionManager
+	tokenTransport           *tokenTransport
+	csrfPolicy               *csrfPolicy
+	auditor                  *_audit.Auditor
 }
 
 func NewAuthMiddleware(repositories domain.Repositories, config *utils.AppConfig) _auth.AuthMiddleware {
+	tokenTransport, err := newTokenTransport(config)
+	if err != nil {
+		log.Fatalf("error initializing token transport: %v\n", err)
+	}
+
 	return &authMiddleware{
-		userRepository: repositories.UserRepository,
-		config:         config,
+		userRepository:           repositories.UserRepository,
+		businessRepository:       repositories.BusinessRepository,
+		serviceAccountRepository: repositories.ServiceAccountRepository,
+		sessionManager:           newSessionManager(repositories),
+		tokenTransport:           tokenTransport,
+		csrfPolicy:               newCsrfPolicy(config),
+		auditor:                  _audit.NewAuditor(repositories),
 	}
 }
 
 func (middleware *authMiddleware) AuthMiddleware(next http.Handler) http.Handler {
+	return middleware.authenticate(next, false)
+}
+
+// OptionalAuthMiddleware resolves the user like AuthMiddleware but never writes
+// an error, a missing, invalid, expired or revoked token leaves UserIDContext nil.
+// AuthMiddleware answers 401 instead, its handlers always get a user.
+func (middleware *authMiddleware) OptionalAuthMiddleware(next http.Handler) http.Handler {
+	return middleware.authenticate(next, true)
+}
+
+func (middleware *authMiddleware) authenticate(next http.Handler, optional bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// machine clients send an API key instead of the cookies
+		if apiKey, found := getBearerApiKey(r); found {
+			middleware.authenticateApiKey(w, r, next, apiKey, optional)
+			return
+		}
+
 		// processing access token
-		tokenStatus, userId := middleware.processAccessToken(&w, r)
-		if tokenStatus == _auth.ERROR_INVALID_TOKEN {
-			// http.Error(w, "invalid access", http.StatusUnauthorized)
-			// return
+		tokenStatus, claims, bearer, found := middleware.processAccessToken(r)
+		if !found && !optional {
+			http.Error(w, "No accessToken found", http.StatusUnauthorized)
+			return
 		}
 
-		// refresh access
-		if tokenStatus == _auth.ERROR_EXPIRED_TOKEN {
-			err := middleware.refreshAccess(r.Context(), *userId, &w)
+		// forged tokens, refresh tokens and any other token type are not access tokens
+		if tokenStatus == _auth.ERROR_INVALID_TOKEN && !optional {
+			http.Error(w, "InvalidToken", http.StatusUnauthorized)
+			return
+		}
+
+		// revoked sessions are rejected even though the access token has not expired yet
+		if tokenStatus == 0 {
+			active, stale, err := middleware.sessionManager.checkSession(r.Context(), claims)
 			if err != nil {
-				// http.Error(w, err.Error(), http.StatusInternalServerError)
-				// return
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if !active && optional {
+				tokenStatus = _auth.ERROR_INVALID_TOKEN
+				claims = nil
+			} else if !active {
+				if !bearer {
+					for _, cookie := range middleware.tokenTransport.expiredSessionCookies() {
+						http.SetCookie(w, cookie)
+					}
+				}
+
+				http.Error(w, "SessionRevoked", http.StatusUnauthorized)
+				return
+			} else if stale {
+				// roles changed since the token was issued, refreshed below like an expired one
+				tokenStatus = _auth.ERROR_EXPIRED_TOKEN
+			}
+		}
+
+		// bearer clients hold their refresh token themselves and call /auth/refresh
+		if tokenStatus == _auth.ERROR_EXPIRED_TOKEN && bearer && !optional {
+			http.Error(w, "ExpiredToken", http.StatusUnauthorized)
+			return
+		} else if tokenStatus == _auth.ERROR_EXPIRED_TOKEN && bearer {
+			claims = nil
+		}
+
+		// refresh access
+		if tokenStatus == _auth.ERROR_EXPIRED_TOKEN && !bearer {
+			refreshedClaims, err := middleware.refreshAccess(r.Context(), r, &w)
+			if err != nil && claims == nil && !optional {
+				// only a refresh cookie was sent, same as sending nothing
+				http.Error(w, "No accessToken found", http.StatusUnauthorized)
+				return
+			} else if err != nil && !optional {
+				http.Error(w, "ExpiredToken", http.StatusUnauthorized)
+				return
+			} else if err != nil {
+				tokenStatus = _auth.ERROR_INVALID_TOKEN
+				claims = nil
+			} else {
+				tokenStatus = 0
+				claims = refreshedClaims
 			}
-			tokenStatus = 0
+		}
+
+		var userId *string
+		var roles []string
+		sessionId := ""
+		if claims != nil {
+			userId = &claims.Subject
+			sessionId = claims.SessionID
+			roles = claims.Roles
 		}
 
 		ctx := context.WithValue(r.Context(), _auth.UserIDContext{}, userId)
+		ctx = context.WithValue(ctx, _auth.SessionIDContext{}, sessionId)
+		ctx = context.WithValue(ctx, _auth.RolesContext{}, roles)
 		ctx = context.WithValue(ctx, _auth.TokenStatus{}, tokenStatus)
 
+		if claims != nil && claims.Actor != nil {
+			ctx = context.WithValue(ctx, _auth.ImpersonatorContext{}, claims.Actor.Subject)
+			middleware.serveImpersonated(w, r.WithContext(ctx), next)
+			return
+		}
+
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func (middleware *authMiddleware) processAccessToken(w *http.ResponseWriter, r *http.Request) (int, *string) {
-	accessToken, err := r.Cookie(middleware.config.AccessTokenKey)
-	if err != nil {
-		if err == http.ErrNoCookie {
-			http.Error(*w, "No accessToken found", http.StatusUnauthorized)
-			return _auth.ERROR_INVALID_TOKEN, nil
-		}
-		http.Error(*w, "Error retrieving accessToken", http.StatusInternalServerError)
-		return _auth.ERROR_INVALID_TOKEN, nil
+// serveImpersonated writes every request made with an impersonation token to the
+// audit log, refused ones included
+func (middleware *authMiddleware) serveImpersonated(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	event := _audit.NewAuditEvent(r.Context(), audit.EVENT_IMPERSONATED_REQUEST, "")
+	event.Identifier = r.Method + " " + r.URL.Path
+
+	recorder := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	next.ServeHTTP(recorder, r)
+
+	event.StatusCode = recorder.Status()
+	if event.StatusCode == 0 {
+		event.StatusCode = http.StatusOK
 	}
 
-	token := accessToken.Value
-	userId, status := _auth.ExtractUserIDFromToken(token)
-	if status != 0 {
-		print(status)
-		if status == _auth.ERROR_EXPIRED_TOKEN {
-			return _auth.ERROR_EXPIRED_TOKEN, &userId
+	event.Outcome = audit.OUTCOME_FAILURE
+	if event.StatusCode >= http.StatusOK && event.StatusCode < http.StatusMultipleChoices {
+		event.Outcome = audit.OUTCOME_SUCCESS
+	}
+
+	middleware.auditor.Record(r.Context(), event)
+}
+
+// processAccessToken reads the bearer token or the cookie. The access cookie
+// expires with its token, so a refresh cookie alone counts as an expired token.
+// found is false when the request carries no token at all.
+func (middleware *authMiddleware) processAccessToken(r *http.Request) (status int, claims *_auth.AcessTokenClaims, bearer bool, found bool) {
+	token, bearer, found := middleware.tokenTransport.accessToken(r)
+	if !found {
+		if _, found := middleware.tokenTransport.refreshToken(r); found {
+			return _auth.ERROR_EXPIRED_TOKEN, nil, false, true
 		}
-		return _auth.ERROR_INVALID_TOKEN, nil
+
+		return _auth.ERROR_INVALID_TOKEN, nil, false, false
 	}
 
-	return 0, &userId
+	claims, status = _auth.ValidateToken(token)
+	if status == _auth.ERROR_INVALID_TOKEN || claims.TokenType != _auth.TOKEN_TYPE_ACCESS {
+		return _auth.ERROR_INVALID_TOKEN, nil, bearer, true
+	}
+
+	return status, claims, bearer, true
 }
 
-// writing  a new cookie of access token & refresh token to the response
-func (middleware *authMiddleware) refreshAccess(ctx context.Context, userId string, w *http.ResponseWriter) error {
-	user, err := middleware.userRepository.GetUserByUserID(ctx, userId)
+// rotating the refresh token cookie & writing a new cookie of access token & refresh token to the response
+func (middleware *authMiddleware) refreshAccess(ctx context.Context, r *http.Request, w *http.ResponseWriter) (*_auth.AcessTokenClaims, error) {
+	refreshToken, found := middleware.tokenTransport.refreshToken(r)
+	if !found {
+		return nil, _auth.ErrInvalidRefreshToken
+	}
+
+	_, tokens, err := middleware.sessionManager.rotateSession(ctx, refreshToken)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if user == nil {
-		return errors.New("user not found")
+	for _, cookie := range middleware.tokenTransport.sessionCookies(*tokens) {
+		http.SetCookie(*w, cookie)
 	}
 
-	accessToken, _ := _auth.GenerateJWT(*user, "ACCESS")
-	refreshToken, _ := _auth.GenerateJWT(*user, "REFRESH")
-
-	now, _ := utils.GetJktTime()
-	cookies := []*http.Cookie{
-		{
-			Name:     middleware.config.AccessTokenKey,
-			Value:    accessToken,
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Hour * 24 * 31),
-		},
-		{
-			Name:     middleware.config.RefreshTokenKey,
-			Value:    refreshToken,
-			Domain:   ".sebia.id",
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			Expires:  now.Add(time.Hour * 24 * 31),
-		},
+	claims, status := _auth.ValidateToken(tokens.AccessToken)
+	if status != 0 {
+		return nil, _auth.ErrInvalidRefreshToken
 	}
 
-	for _, cookie := range cookies {
-		http.SetCookie(*w, cookie)
+	return claims, nil
+}
+
+// RequirePermission answers 401 without a user and 403 as soon as one of the
+// permissions is not granted by any role of the access token, or is not a scope
+// of the API key
+func (middleware *authMiddleware) RequirePermission(permissions ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resp := &response.Response[string]{
+				Writer: w,
+			}
+
+			userID, _ := r.Context().Value(_auth.UserIDContext{}).(*string)
+			if userID == nil {
+				resp.Unauthorized(response.ERROR_UNAUTHORIZED)
+				resp.WriteResponse()
+				return
+			}
+
+			for _, permission := range permissions {
+				if !_auth.IsPermitted(r.Context(), permission) {
+					resp.Forbidden(_auth.FORBIDDEN_MESSAGE, nil)
+					resp.WriteResponse()
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
 	}
+}
+
+// DenyImpersonation answers 403 to requests made while impersonating
+func (middleware *authMiddleware) DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _auth.GetImpersonatorID(r.Context()) != "" {
+			resp := &response.Response[string]{
+				Writer: w,
+			}
 
-	return nil
+			resp.Forbidden(_auth.IMPERSONATION_FORBIDDEN_MESSAGE, nil)
+			resp.WriteResponse()
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
 }
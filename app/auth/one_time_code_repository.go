@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"mini-wallet/domain"
+	"mini-wallet/domain/auth"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type oneTimeCodeRepository struct {
+	oneTimeCodeCollection *mongo.Collection
+}
+
+func NewOneTimeCodeRepository(repositoryParam domain.RepositoryParam) auth.OneTimeCodeRepository {
+	return &oneTimeCodeRepository{
+		oneTimeCodeCollection: repositoryParam.Mongo.Collection("one_time_code"),
+	}
+}
+
+func (repository *oneTimeCodeRepository) EnsureExpiryIndex(ctx context.Context, now int64) (err error) {
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "purge_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	_, err = repository.oneTimeCodeCollection.Indexes().CreateOne(ctx, index)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"purge_at": bson.M{
+			"$exists": false,
+		},
+		"expired_at": bson.M{
+			"$lte": now,
+		},
+	}
+
+	_, err = repository.oneTimeCodeCollection.DeleteMany(ctx, filter)
+	return err
+}
+
+func (repository *oneTimeCodeRepository) UpsertCode(ctx context.Context, code auth.OneTimeCodeEntity) (err error) {
+	code.PurgeAt = time.Unix(code.ExpiredAt, 0)
+
+	opts := options.Replace().SetUpsert(true)
+	filter := bson.M{
+		"purpose":     code.Purpose,
+		"destination": code.Destination,
+	}
+
+	_, err = repository.oneTimeCodeCollection.ReplaceOne(ctx, filter, code, opts)
+	return err
+}
+
+func (repository *oneTimeCodeRepository) GetCode(ctx context.Context, purpose string, destination string, now int64) (res *auth.OneTimeCodeEntity, err error) {
+	filter := bson.M{
+		"purpose":     purpose,
+		"destination": destination,
+		"expired_at": bson.M{
+			"$gt": now,
+		},
+	}
+
+	result := repository.oneTimeCodeCollection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		return nil, err
+	}
+
+	result.Decode(&res)
+
+	return res, nil
+}
+
+func (repository *oneTimeCodeRepository) GetCodeByID(ctx context.Context, id string, purpose string, now int64) (res *auth.OneTimeCodeEntity, err error) {
+	filter := bson.M{
+		"id":      id,
+		"purpose": purpose,
+		"expired_at": bson.M{
+			"$gt": now,
+		},
+	}
+
+	result := repository.oneTimeCodeCollection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		return nil, err
+	}
+
+	result.Decode(&res)
+
+	return res, nil
+}
+
+func (repository *oneTimeCodeRepository) ClaimAttempt(ctx context.Context, id string) (claimed bool, attempts int, err error) {
+	filter := bson.M{
+		"id": id,
+		"attempts": bson.M{
+			"$lt": auth.ONE_TIME_CODE_MAX_ATTEMPTS,
+		},
+	}
+	update := bson.M{"$inc": bson.M{
+		"attempts": 1,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	result := repository.oneTimeCodeCollection.FindOneAndUpdate(ctx, filter, update, opts)
+	if result.Err() == mongo.ErrNoDocuments {
+		return false, 0, nil
+	}
+
+	if result.Err() != nil {
+		return false, 0, result.Err()
+	}
+
+	res := auth.OneTimeCodeEntity{}
+	err = result.Decode(&res)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return true, res.Attempts, nil
+}
+
+func (repository *oneTimeCodeRepository) DeleteCode(ctx context.Context, id string) (deleted bool, err error) {
+	result, err := repository.oneTimeCodeCollection.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount == 1, nil
+}
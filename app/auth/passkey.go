@@ -0,0 +1,480 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	_audit "mini-wallet/app/audit"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/user"
+	"mini-wallet/utils"
+	"strings"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// passkeyUser adapts a user and their passkeys to webauthn.User. The user
+// handle is the UID, so discoverable logins can find the user from it.
+type passkeyUser struct {
+	user     user.UserEntity
+	passkeys []auth.PasskeyEntity
+}
+
+func (p *passkeyUser) WebAuthnID() []byte {
+	return []byte(p.user.UID)
+}
+
+func (p *passkeyUser) WebAuthnName() string {
+	return p.user.Email
+}
+
+func (p *passkeyUser) WebAuthnDisplayName() string {
+	return p.user.Name
+}
+
+func (p *passkeyUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (p *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := []webauthn.Credential{}
+	for _, passkey := range p.passkeys {
+		credentials = append(credentials, toWebAuthnCredential(passkey))
+	}
+
+	return credentials
+}
+
+func newWebAuthn(config *utils.AppConfig) (*webauthn.WebAuthn, error) {
+	rpID := config.WebAuthnRPID
+	if rpID == "" {
+		rpID = config.AppDomain
+	}
+
+	origins := []string{}
+	for _, origin := range strings.Split(config.WebAuthnRPOrigins, ",") {
+		if strings.TrimSpace(origin) != "" {
+			origins = append(origins, strings.TrimSpace(origin))
+		}
+	}
+
+	if len(origins) == 0 {
+		origins = append(origins, "https://"+config.AppDomain)
+	}
+
+	timeout := webauthn.TimeoutConfig{
+		Enforce: true,
+		Timeout: auth.PASSKEY_CEREMONY_LIFETIME,
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: auth.PASSKEY_RP_DISPLAY_NAME,
+		RPOrigins:     origins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+}
+
+func (usecase *authUsecase) BeginPasskeyRegistration(ctx context.Context, userID string) (res response.Response[auth.PasskeyCeremonyDTO]) {
+	webAuthnUser, err := usecase.getPasskeyUser(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if webAuthnUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	exclusions := []protocol.CredentialDescriptor{}
+	for _, credential := range webAuthnUser.WebAuthnCredentials() {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	options, session, err := usecase.webAuthn.BeginRegistration(webAuthnUser,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			UserVerification:   protocol.VerificationRequired,
+		}),
+	)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	ceremonyID, err := usecase.startPasskeyCeremony(ctx, auth.PASSKEY_CEREMONY_REGISTRATION, &userID, session)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.Success(auth.PasskeyCeremonyDTO{
+		CeremonyID: ceremonyID,
+		Options:    options,
+	})
+	return
+}
+
+func (usecase *authUsecase) FinishPasskeyRegistration(ctx context.Context, userID string, req auth.PasskeyRegistrationDTO) (res response.Response[auth.PasskeyDTO]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_PASSKEY_REGISTER, audit.METHOD_PASSKEY)
+	event.UserID = userID
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	session, err := usecase.takePasskeyCeremony(ctx, req.CeremonyID, auth.PASSKEY_CEREMONY_REGISTRATION, &userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if session == nil {
+		res.BadRequest("Sesi pendaftaran passkey kedaluwarsa, silakan ulangi", nil)
+		return
+	}
+
+	webAuthnUser, err := usecase.getPasskeyUser(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if webAuthnUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	parsedResponse, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		res.BadRequest("Passkey tidak valid", nil)
+		return
+	}
+
+	credential, err := usecase.webAuthn.CreateCredential(webAuthnUser, *session, parsedResponse)
+	if err != nil {
+		res.BadRequest("Passkey tidak valid", nil)
+		return
+	}
+
+	existingPasskey, err := usecase.passkeyRepository.GetPasskeyByCredentialID(ctx, credential.ID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingPasskey != nil {
+		res.BadRequest("Passkey sudah terdaftar", nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	passkey := toPasskeyEntity(*credential)
+	passkey.ID = utils.GenerateUniqueId()
+	passkey.UserID = userID
+	passkey.Name = req.Name
+	passkey.CreatedAt = now.Unix()
+
+	err = usecase.passkeyRepository.InsertPasskey(ctx, passkey)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.Success(passkey.ToPasskeyDTO())
+	return
+}
+
+func (usecase *authUsecase) BeginPasskeyAuthentication(ctx context.Context) (res response.Response[auth.PasskeyCeremonyDTO]) {
+	// discoverable login, the authenticator tells which user it holds a passkey for
+	options, session, err := usecase.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	ceremonyID, err := usecase.startPasskeyCeremony(ctx, auth.PASSKEY_CEREMONY_LOGIN, nil, session)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.Success(auth.PasskeyCeremonyDTO{
+		CeremonyID: ceremonyID,
+		Options:    options,
+	})
+	return
+}
+
+func (usecase *authUsecase) FinishPasskeyAuthentication(ctx context.Context, req auth.PasskeyAuthenticationDTO) (res response.Response[auth.AuthenticationResponse]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_LOGIN, audit.METHOD_PASSKEY)
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	session, err := usecase.takePasskeyCeremony(ctx, req.CeremonyID, auth.PASSKEY_CEREMONY_LOGIN, nil)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if session == nil {
+		res.BadRequest("Sesi masuk kedaluwarsa, silakan ulangi", nil)
+		return
+	}
+
+	parsedResponse, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		res.BadRequest("Passkey tidak valid", nil)
+		return
+	}
+
+	var webAuthnUser *passkeyUser
+	credential, err := usecase.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		webAuthnUser, err = usecase.getPasskeyUser(ctx, string(userHandle))
+		if err != nil {
+			return nil, err
+		}
+
+		if webAuthnUser == nil {
+			return nil, errors.New("user not found")
+		}
+
+		return webAuthnUser, nil
+	}, *session, parsedResponse)
+	if err != nil {
+		res.Unauthorized("Passkey tidak valid")
+		return
+	}
+
+	event.UserID = webAuthnUser.user.UID
+
+	// the signature counter went backwards, the credential may have been cloned
+	if credential.Authenticator.CloneWarning {
+		res.Unauthorized("Passkey tidak valid")
+		return
+	}
+
+	passkey, err := usecase.passkeyRepository.GetPasskeyByCredentialID(ctx, credential.ID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if passkey == nil || passkey.UserID != webAuthnUser.user.UID {
+		res.Unauthorized("Passkey tidak valid")
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	err = usecase.passkeyRepository.UpdatePasskeyUsage(ctx, passkey.ID, credential.Authenticator.SignCount, credential.Flags.BackupState, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	tokens, err := usecase.signIn(ctx, webAuthnUser.user)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithCookie("success", *tokens, usecase.tokenTransport.sessionCookies(*tokens))
+
+	return
+}
+
+func (usecase *authUsecase) GetPasskeys(ctx context.Context, userID string) (res response.Response[[]auth.PasskeyDTO]) {
+	passkeys, err := usecase.passkeyRepository.GetPasskeysByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	result := []auth.PasskeyDTO{}
+	for _, passkey := range passkeys {
+		result = append(result, passkey.ToPasskeyDTO())
+	}
+
+	res.Success(result)
+	return
+}
+
+func (usecase *authUsecase) DeletePasskey(ctx context.Context, userID string, passkeyID string) (res response.Response[string]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_PASSKEY_DELETE, audit.METHOD_PASSKEY)
+	event.UserID = userID
+	event.Identifier = passkeyID
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	loginMethods, err := usecase.countLoginMethods(ctx, *existingUser)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if loginMethods <= 1 {
+		res.BadRequest(LAST_LOGIN_METHOD_MESSAGE, nil)
+		return
+	}
+
+	deleted, err := usecase.passkeyRepository.DeletePasskey(ctx, userID, passkeyID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !deleted {
+		res.NotFound("Passkey tidak ditemukan", nil)
+		return
+	}
+
+	res.SuccessWithMessage("Passkey dihapus")
+	return
+}
+
+func (usecase *authUsecase) getPasskeyUser(ctx context.Context, userID string) (*passkeyUser, error) {
+	userEntity, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userEntity == nil {
+		return nil, nil
+	}
+
+	passkeys, err := usecase.passkeyRepository.GetPasskeysByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &passkeyUser{
+		user:     *userEntity,
+		passkeys: passkeys,
+	}, nil
+}
+
+func (usecase *authUsecase) startPasskeyCeremony(ctx context.Context, ceremonyType string, userID *string, session *webauthn.SessionData) (string, error) {
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return "", err
+	}
+
+	sessionData, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+
+	ceremony := auth.PasskeyCeremonyEntity{
+		ID:          utils.GenerateUniqueId(),
+		Type:        ceremonyType,
+		UserID:      userID,
+		SessionData: sessionData,
+		ExpiredAt:   now.Add(auth.PASSKEY_CEREMONY_LIFETIME).Unix(),
+	}
+
+	err = usecase.passkeyRepository.InsertCeremony(ctx, ceremony)
+	if err != nil {
+		return "", err
+	}
+
+	return ceremony.ID, nil
+}
+
+// takePasskeyCeremony consumes the ceremony, registration ceremonies must be finished by the user who began them
+func (usecase *authUsecase) takePasskeyCeremony(ctx context.Context, ceremonyID string, ceremonyType string, userID *string) (*webauthn.SessionData, error) {
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return nil, err
+	}
+
+	ceremony, err := usecase.passkeyRepository.TakeCeremony(ctx, ceremonyID, ceremonyType, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	if ceremony == nil {
+		return nil, nil
+	}
+
+	if userID != nil && (ceremony.UserID == nil || *ceremony.UserID != *userID) {
+		return nil, nil
+	}
+
+	session := webauthn.SessionData{}
+	err = json.Unmarshal(ceremony.SessionData, &session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func toWebAuthnCredential(passkey auth.PasskeyEntity) webauthn.Credential {
+	transports := []protocol.AuthenticatorTransport{}
+	for _, transport := range passkey.Transports {
+		transports = append(transports, protocol.AuthenticatorTransport(transport))
+	}
+
+	return webauthn.Credential{
+		ID:              passkey.CredentialID,
+		PublicKey:       passkey.PublicKey,
+		AttestationType: passkey.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			UserVerified:   passkey.UserVerified,
+			BackupEligible: passkey.BackupEligible,
+			BackupState:    passkey.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    passkey.AAGUID,
+			SignCount: passkey.SignCount,
+		},
+	}
+}
+
+func toPasskeyEntity(credential webauthn.Credential) auth.PasskeyEntity {
+	transports := []string{}
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	return auth.PasskeyEntity{
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		UserVerified:    credential.Flags.UserVerified,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+}
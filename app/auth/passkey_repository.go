@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"mini-wallet/domain"
+	"mini-wallet/domain/auth"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type passkeyRepository struct {
+	passkeyCollection  *mongo.Collection
+	ceremonyCollection *mongo.Collection
+}
+
+func NewPasskeyRepository(repositoryParam domain.RepositoryParam) auth.PasskeyRepository {
+	return &passkeyRepository{
+		passkeyCollection:  repositoryParam.Mongo.Collection("user_passkey"),
+		ceremonyCollection: repositoryParam.Mongo.Collection("passkey_ceremony"),
+	}
+}
+
+func (repository *passkeyRepository) InsertPasskey(ctx context.Context, passkey auth.PasskeyEntity) (err error) {
+	_, err = repository.passkeyCollection.InsertOne(ctx, passkey)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *passkeyRepository) GetPasskeysByUserID(ctx context.Context, userID string) (res []auth.PasskeyEntity, err error) {
+	filter := bson.M{"user_id": userID}
+	opts := options.Find().SetSort(bson.D{
+		{
+			Key:   "created_at",
+			Value: 1,
+		},
+	})
+
+	result, err := repository.passkeyCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res = []auth.PasskeyEntity{}
+	err = result.All(ctx, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (repository *passkeyRepository) GetPasskeyByCredentialID(ctx context.Context, credentialID []byte) (res *auth.PasskeyEntity, err error) {
+	filter := bson.M{"credential_id": credentialID}
+
+	result := repository.passkeyCollection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		return nil, err
+	}
+
+	result.Decode(&res)
+
+	return res, nil
+}
+
+func (repository *passkeyRepository) UpdatePasskeyUsage(ctx context.Context, id string, signCount uint32, backupState bool, now int64) (err error) {
+	filter := bson.M{"id": id}
+
+	update := bson.M{"$set": bson.M{
+		"sign_count":   signCount,
+		"backup_state": backupState,
+		"last_used_at": now,
+	}}
+
+	_, err = repository.passkeyCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (repository *passkeyRepository) DeletePasskey(ctx context.Context, userID string, id string) (deleted bool, err error) {
+	filter := bson.M{
+		"id":      id,
+		"user_id": userID,
+	}
+
+	result, err := repository.passkeyCollection.DeleteOne(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount == 1, nil
+}
+
+func (repository *passkeyRepository) DeletePasskeysByUserID(ctx context.Context, userID string) (err error) {
+	filter := bson.M{"user_id": userID}
+
+	_, err = repository.passkeyCollection.DeleteMany(ctx, filter)
+	return err
+}
+
+func (repository *passkeyRepository) InsertCeremony(ctx context.Context, ceremony auth.PasskeyCeremonyEntity) (err error) {
+	_, err = repository.ceremonyCollection.InsertOne(ctx, ceremony)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *passkeyRepository) TakeCeremony(ctx context.Context, id string, ceremonyType string, now int64) (res *auth.PasskeyCeremonyEntity, err error) {
+	filter := bson.M{
+		"id":   id,
+		"type": ceremonyType,
+		"expired_at": bson.M{
+			"$gt": now,
+		},
+	}
+
+	result := repository.ceremonyCollection.FindOneAndDelete(ctx, filter)
+	if result.Err() != nil {
+		return nil, err
+	}
+
+	result.Decode(&res)
+
+	return res, nil
+}
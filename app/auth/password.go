@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	_audit "mini-wallet/app/audit"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/user"
+	"mini-wallet/infrastructure"
+	"mini-wallet/utils"
+	"time"
+)
+
+// verifyPassword checks the password and, once it matched, upgrades a hash of an
+// older scheme or older argon2id parameters. The upgrade is best effort, a failed
+// one is retried on the next sign in.
+func (usecase *authUsecase) verifyPassword(ctx context.Context, userEntity *user.UserEntity, password string) (matched bool, err error) {
+	needsRehash, err := userEntity.VerifyPassword(password)
+	if errors.Is(err, utils.ErrPasswordMismatch) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if !needsRehash {
+		return true, nil
+	}
+
+	currentHash := *userEntity.HashedPassword
+	err = userEntity.ChangePassword(password)
+	if err != nil {
+		return true, nil
+	}
+
+	_, _ = usecase.userRepository.UpdatePasswordHash(ctx, userEntity.UID, currentHash, *userEntity.HashedPassword)
+
+	return true, nil
+}
+
+// checkPasswordPolicy applies utils.CheckPasswordPolicy with the personal data of the owner
+func checkPasswordPolicy(password string, name string, email string, phoneNumber *string) error {
+	userInputs := []string{name, email}
+	if phoneNumber != nil {
+		userInputs = append(userInputs, *phoneNumber)
+	}
+
+	return utils.CheckPasswordPolicy(password, userInputs...)
+}
+
+func (usecase *authUsecase) ChangePassword(ctx context.Context, userID string, sessionID string, req auth.ChangePasswordDTO) (res response.Response[string]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_PASSWORD_CHANGE, audit.METHOD_PASSWORD)
+	event.UserID = userID
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	if existingUser.HashedPassword == nil {
+		res.BadRequest(noPasswordMessage(*existingUser), nil)
+		return
+	}
+
+	// the current password is what got reported, only a reset link replaces it
+	if existingUser.PasswordResetRequired {
+		res.Forbidden(auth.PASSWORD_RESET_REQUIRED_MESSAGE, nil)
+		return
+	}
+
+	// guessing the current password is throttled like signing in
+	identifierAttempt := newAttempt(loginIdentifierPolicy, existingUser.Email)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, identifierAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
+	// not verifyPassword, an upgraded hash would be replaced right away
+	_, err = existingUser.VerifyPassword(req.CurrentPassword)
+	if errors.Is(err, utils.ErrPasswordMismatch) {
+		err = usecase.recordFailedLogin(ctx, existingUser, identifierAttempt)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		res.BadRequest("Kata sandi salah", nil)
+		return
+	}
+
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	err = checkPasswordPolicy(req.NewPassword, existingUser.Name, existingUser.Email, existingUser.PhoneNumber)
+	if err != nil {
+		res.BadRequest(err.Error(), nil)
+		return
+	}
+
+	currentHash := *existingUser.HashedPassword
+	err = existingUser.ChangePassword(req.NewPassword)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	updated, err := usecase.userRepository.UpdatePasswordHash(ctx, existingUser.UID, currentHash, *existingUser.HashedPassword)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !updated {
+		res.BadRequest("Kata sandi baru saja diubah, silakan coba lagi", nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	// a reset link asked for before the change must not undo it
+	err = usecase.userRepository.DeleteUserPasswordResetEntity(ctx, existingUser.Email)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if req.SignOutOtherSessions {
+		err = usecase.sessionRepository.RevokeUserSessions(ctx, existingUser.UID, sessionID, auth.SESSION_REVOKED_PASSWORD_CHANGE, now.Unix())
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+	}
+
+	go usecase.notifyPasswordChanged(*existingUser, *now)
+
+	res.SuccessWithMessage("Kata sandi diubah")
+	return
+}
+
+// notifyPasswordChanged tells the owner through email and WhatsApp, so a change
+// they did not make does not go unnoticed
+func (usecase *authUsecase) notifyPasswordChanged(userEntity user.UserEntity, changedAt time.Time) {
+	formattedTime := changedAt.Format("02-01-2006 15:04 WIB")
+	infrastructure.SendPasswordChangedNotice(userEntity.Email, userEntity.Name, formattedTime, usecase.config.AppDomain)
+
+	if userEntity.PhoneNumber != nil {
+		err := usecase.notificationService.SendWhatsAppMessage(context.Background(),
+			fmt.Sprintf("Halo %s,\nKata sandi akun Sebia Anda diubah pada %s. Jika ini bukan Anda, segera atur ulang kata sandi Anda.", userEntity.Name, formattedTime), *userEntity.PhoneNumber)
+		if err != nil {
+			fmt.Println("error sending password change notification:", err.Error())
+		}
+	}
+}
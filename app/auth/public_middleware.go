@@ -8,6 +8,11 @@ import (
 
 func (middleware *authMiddleware) PublicMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims, status := middleware.getImpersonationClaims(r); claims != nil {
+			middleware.servePublicImpersonated(w, r, next, claims, status)
+			return
+		}
+
 		_, userId := middleware.getAccessTokenUserId(&w, r) // return empty string if no user id found
 
 		ctx := context.WithValue(r.Context(), _auth.UserIDContext{}, userId)
@@ -17,15 +22,54 @@ func (middleware *authMiddleware) PublicMiddleware(next http.Handler) http.Handl
 }
 
 func (middleware *authMiddleware) getAccessTokenUserId(w *http.ResponseWriter, r *http.Request) (int, *string) {
-	accessToken, err := r.Cookie(middleware.config.AccessTokenKey)
-	if err != nil {
-		if err == http.ErrNoCookie {
-			return _auth.ERROR_INVALID_TOKEN, nil
-		}
+	token, _, found := middleware.tokenTransport.accessToken(r)
+	if !found {
 		return _auth.ERROR_INVALID_TOKEN, nil
 	}
 
-	token := accessToken.Value
 	userId, _ := _auth.ExtractUserIDFromToken(token) // ignore status
 	return 0, &userId
 }
+
+// getImpersonationClaims returns the claims of an impersonation access token,
+// expired ones included, nil for any other request
+func (middleware *authMiddleware) getImpersonationClaims(r *http.Request) (*_auth.AcessTokenClaims, int) {
+	token, _, found := middleware.tokenTransport.accessToken(r)
+	if !found {
+		return nil, _auth.ERROR_INVALID_TOKEN
+	}
+
+	claims, status := _auth.ValidateToken(token)
+	if status == _auth.ERROR_INVALID_TOKEN || claims.TokenType != _auth.TOKEN_TYPE_ACCESS || claims.Actor == nil {
+		return nil, _auth.ERROR_INVALID_TOKEN
+	}
+
+	return claims, status
+}
+
+// servePublicImpersonated holds impersonation tokens to the rules of
+// AuthMiddleware on public routes too: the impersonation session has to be active
+// and every request is audited
+func (middleware *authMiddleware) servePublicImpersonated(w http.ResponseWriter, r *http.Request, next http.Handler, claims *_auth.AcessTokenClaims, tokenStatus int) {
+	if tokenStatus == _auth.ERROR_EXPIRED_TOKEN {
+		http.Error(w, "ExpiredToken", http.StatusUnauthorized)
+		return
+	}
+
+	active, _, err := middleware.sessionManager.checkSession(r.Context(), claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !active {
+		http.Error(w, "SessionRevoked", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), _auth.UserIDContext{}, &claims.Subject)
+	ctx = context.WithValue(ctx, _auth.SessionIDContext{}, claims.SessionID)
+	ctx = context.WithValue(ctx, _auth.RolesContext{}, claims.Roles)
+	ctx = context.WithValue(ctx, _auth.ImpersonatorContext{}, claims.Actor.Subject)
+	middleware.serveImpersonated(w, r.WithContext(ctx), next)
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	_audit "mini-wallet/app/audit"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/utils"
+	"slices"
+	"strings"
+)
+
+func (usecase *authUsecase) GetUserRoles(ctx context.Context, userID string) (res response.Response[auth.RoleAssignmentDTO]) {
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	res.Success(auth.RoleAssignmentDTO{
+		Roles: auth.GetUserRoles(*existingUser),
+	})
+	return
+}
+
+func (usecase *authUsecase) SetUserRoles(ctx context.Context, userID string, req auth.RoleAssignmentDTO) (res response.Response[auth.RoleAssignmentDTO]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_ROLES_CHANGE, "")
+	event.UserID = userID
+	event.Identifier = strings.Join(req.Roles, ",")
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	// guest is implicit, it is not stored
+	roles := []string{}
+	for _, role := range req.Roles {
+		if role != auth.ROLE_GUEST && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+
+	err = usecase.userRepository.SetRoles(ctx, userID, roles)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	err = usecase.sessionRepository.MarkClaimsChanged(ctx, userID, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	existingUser.Roles = roles
+	res.Success(auth.RoleAssignmentDTO{
+		Roles: auth.GetUserRoles(*existingUser),
+	})
+	return
+}
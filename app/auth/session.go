@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"context"
+	_audit "mini-wallet/app/audit"
+	"mini-wallet/domain"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/user"
+	"mini-wallet/utils"
+)
+
+// sessionManager issues and rotates refresh token families, shared by the
+// usecase (login, /auth/refresh, logout) and the middleware (silent refresh)
+type sessionManager struct {
+	sessionRepository auth.SessionRepository
+	userRepository    user.UserRepository
+	auditor           *_audit.Auditor
+}
+
+func newSessionManager(repositories domain.Repositories) *sessionManager {
+	return &sessionManager{
+		sessionRepository: repositories.SessionRepository,
+		userRepository:    repositories.UserRepository,
+		auditor:           _audit.NewAuditor(repositories),
+	}
+}
+
+// startSession opens a new token family for the user and returns its first token pair
+func (manager *sessionManager) startSession(ctx context.Context, userEntity user.UserEntity) (*auth.AuthenticationResponse, error) {
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return nil, err
+	}
+
+	csrfSecret, err := utils.GenerateRandomString(auth.CSRF_SECRET_SIZE)
+	if err != nil {
+		return nil, err
+	}
+
+	clientInfo := auth.GetClientInfo(ctx)
+	session := auth.SessionEntity{
+		ID:             utils.GenerateUniqueId(),
+		UserID:         userEntity.UID,
+		RefreshTokenID: utils.GenerateUniqueId(),
+		UserAgent:      clientInfo.UserAgent,
+		IPAddress:      clientInfo.IPAddress,
+		CreatedAt:      now.Unix(),
+		UpdatedAt:      now.Unix(),
+		LastSeenAt:     now.Unix(),
+		ExpiredAt:      now.Add(auth.REFRESH_TOKEN_LIFETIME).Unix(),
+		CsrfSecret:     csrfSecret,
+	}
+
+	err = manager.sessionRepository.InsertSession(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := manager.generateTokens(userEntity, session.ID, session.RefreshTokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens.CsrfToken, err = auth.GenerateCsrfToken(csrfSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// startImpersonation opens a session of the subject on behalf of the actor. It
+// has no refresh token nor CSRF secret, the access token is only sent as a bearer
+// token and the session ends with it.
+func (manager *sessionManager) startImpersonation(ctx context.Context, actor user.UserEntity, subject user.UserEntity) (*auth.ImpersonationDTO, error) {
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := now.Add(auth.IMPERSONATION_TOKEN_LIFETIME)
+	clientInfo := auth.GetClientInfo(ctx)
+	session := auth.SessionEntity{
+		ID:             utils.GenerateUniqueId(),
+		UserID:         subject.UID,
+		RefreshTokenID: utils.GenerateUniqueId(),
+		UserAgent:      clientInfo.UserAgent,
+		IPAddress:      clientInfo.IPAddress,
+		CreatedAt:      now.Unix(),
+		UpdatedAt:      now.Unix(),
+		LastSeenAt:     now.Unix(),
+		ExpiredAt:      expiresAt.Unix(),
+		ImpersonatorID: actor.UID,
+	}
+
+	err = manager.sessionRepository.InsertSession(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := auth.GenerateImpersonationJWT(subject, actor, session.ID, utils.GenerateUniqueId(), expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth.ImpersonationDTO{
+		AccessToken: accessToken,
+		SessionID:   session.ID,
+		UserID:      subject.UID,
+		ActorID:     actor.UID,
+		ExpiresAt:   expiresAt.Unix(),
+	}, nil
+}
+
+// rotateSession exchanges a refresh token for a new pair. Presenting a refresh
+// token that was already rotated means it leaked, so the whole family is revoked,
+// unless it was rotated within REFRESH_TOKEN_REUSE_GRACE by a concurrent request
+// of the same client, which then gets the current pair.
+// Every attempt is audited, for the silent refresh of the middleware as well.
+func (manager *sessionManager) rotateSession(ctx context.Context, refreshToken string) (userEntity *user.UserEntity, tokens *auth.AuthenticationResponse, err error) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_TOKEN_REFRESH, "")
+	defer func() {
+		manager.auditor.RecordError(ctx, event, err)
+	}()
+
+	claims, status := auth.ValidateToken(refreshToken)
+	if status != 0 || claims.TokenType != auth.TOKEN_TYPE_REFRESH || claims.SessionID == "" {
+		return nil, nil, auth.ErrInvalidRefreshToken
+	}
+
+	event.UserID = claims.Subject
+	event.SessionID = claims.SessionID
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newTokenID := utils.GenerateUniqueId()
+	rotated, err := manager.sessionRepository.RotateRefreshToken(ctx, claims.SessionID, claims.ID, newTokenID, now.Unix())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !rotated {
+		session, err := manager.sessionRepository.GetSessionByID(ctx, claims.SessionID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if session == nil || session.UserID != claims.Subject {
+			return nil, nil, auth.ErrInvalidRefreshToken
+		}
+
+		if !session.IsActive(now.Unix()) {
+			return nil, nil, auth.ErrSessionRevoked
+		}
+
+		if claims.ID != session.PreviousRefreshTokenID || now.Unix()-session.RotatedAt > auth.REFRESH_TOKEN_REUSE_GRACE {
+			err = manager.sessionRepository.RevokeSession(ctx, session.ID, auth.SESSION_REVOKED_REFRESH_REUSE, now.Unix())
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return nil, nil, auth.ErrRefreshTokenReused
+		}
+
+		// a concurrent request of the client rotated it a moment ago, both get the current pair
+		newTokenID = session.RefreshTokenID
+	}
+
+	err = manager.sessionRepository.TouchSession(ctx, claims.SessionID, auth.GetClientInfo(ctx), now.Unix())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userEntity, err = manager.userRepository.GetUserByUserID(ctx, claims.Subject)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if userEntity == nil {
+		return nil, nil, auth.ErrInvalidRefreshToken
+	}
+
+	tokens, err = manager.generateTokens(*userEntity, claims.SessionID, newTokenID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return userEntity, tokens, nil
+}
+
+// revokeSession ends the family the refresh token belongs to, expired tokens are accepted
+func (manager *sessionManager) revokeSession(ctx context.Context, refreshToken string, reason string) error {
+	claims, status := auth.ValidateToken(refreshToken)
+	if status == auth.ERROR_INVALID_TOKEN || claims.SessionID == "" {
+		return auth.ErrInvalidRefreshToken
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return err
+	}
+
+	return manager.sessionRepository.RevokeSession(ctx, claims.SessionID, reason, now.Unix())
+}
+
+// checkSession tells whether the session behind an access token is still active,
+// refreshing its last seen time along the way. stale means the claims (roles) of
+// the token are outdated and it should be refreshed.
+func (manager *sessionManager) checkSession(ctx context.Context, claims *auth.AcessTokenClaims) (active bool, stale bool, err error) {
+	if claims.SessionID == "" {
+		return false, false, nil
+	}
+
+	session, err := manager.sessionRepository.GetSessionByID(ctx, claims.SessionID)
+	if err != nil {
+		return false, false, err
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return false, false, err
+	}
+
+	if session == nil || session.UserID != claims.Subject || !session.IsActive(now.Unix()) {
+		return false, false, nil
+	}
+
+	if now.Unix()-session.LastSeenAt > auth.SESSION_LAST_SEEN_INTERVAL {
+		err = manager.sessionRepository.TouchSession(ctx, session.ID, auth.GetClientInfo(ctx), now.Unix())
+		if err != nil {
+			return false, false, err
+		}
+	}
+
+	stale = claims.IssuedAt != nil && session.ClaimsChangedAt > claims.IssuedAt.Unix()
+	return true, stale, nil
+}
+
+// getCsrfSecret returns the CSRF secret of an active session, giving one to
+// sessions started before CSRF protection. Empty when the session is not active.
+func (manager *sessionManager) getCsrfSecret(ctx context.Context, sessionID string) (string, error) {
+	session, err := manager.sessionRepository.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return "", err
+	}
+
+	if session == nil || !session.IsActive(now.Unix()) {
+		return "", nil
+	}
+
+	if session.CsrfSecret != "" {
+		return session.CsrfSecret, nil
+	}
+
+	secret, err := utils.GenerateRandomString(auth.CSRF_SECRET_SIZE)
+	if err != nil {
+		return "", err
+	}
+
+	err = manager.sessionRepository.SetCsrfSecret(ctx, sessionID, secret)
+	if err != nil {
+		return "", err
+	}
+
+	// a concurrent request may have set another one first
+	session, err = manager.sessionRepository.GetSessionByID(ctx, sessionID)
+	if err != nil || session == nil {
+		return "", err
+	}
+
+	return session.CsrfSecret, nil
+}
+
+func (manager *sessionManager) generateTokens(userEntity user.UserEntity, sessionID string, refreshTokenID string) (*auth.AuthenticationResponse, error) {
+	accessToken, err := auth.GenerateJWT(userEntity, auth.TOKEN_TYPE_ACCESS, sessionID, utils.GenerateUniqueId())
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := auth.GenerateJWT(userEntity, auth.TOKEN_TYPE_REFRESH, sessionID, refreshTokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth.AuthenticationResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"mini-wallet/domain"
+	"mini-wallet/domain/auth"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type sessionRepository struct {
+	sessionCollection *mongo.Collection
+}
+
+func NewSessionRepository(repositoryParam domain.RepositoryParam) auth.SessionRepository {
+	return &sessionRepository{
+		sessionCollection: repositoryParam.Mongo.Collection("user_session"),
+	}
+}
+
+func (repository *sessionRepository) InsertSession(ctx context.Context, session auth.SessionEntity) (err error) {
+	_, err = repository.sessionCollection.InsertOne(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *sessionRepository) GetSessionByID(ctx context.Context, id string) (res *auth.SessionEntity, err error) {
+	filter := bson.M{"id": id}
+
+	result := repository.sessionCollection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		return nil, err
+	}
+
+	result.Decode(&res)
+
+	return res, nil
+}
+
+func (repository *sessionRepository) RotateRefreshToken(ctx context.Context, id string, currentTokenID string, newTokenID string, now int64) (rotated bool, err error) {
+	filter := bson.M{
+		"id":               id,
+		"refresh_token_id": currentTokenID,
+		"revoked_at":       nil,
+		"expired_at": bson.M{
+			"$gt": now,
+		},
+	}
+
+	update := bson.M{"$set": bson.M{
+		"refresh_token_id":          newTokenID,
+		"previous_refresh_token_id": currentTokenID,
+		"rotated_at":                now,
+		"updated_at":                now,
+	}}
+
+	result, err := repository.sessionCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (repository *sessionRepository) RevokeSession(ctx context.Context, id string, reason string, now int64) (err error) {
+	filter := bson.M{
+		"id":         id,
+		"revoked_at": nil,
+	}
+
+	update := bson.M{"$set": bson.M{
+		"revoked_at":     now,
+		"revoked_reason": reason,
+		"updated_at":     now,
+	}}
+
+	_, err = repository.sessionCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (repository *sessionRepository) RevokeUserSessions(ctx context.Context, userID string, exceptSessionID string, reason string, now int64) (err error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked_at": nil,
+	}
+
+	if exceptSessionID != "" {
+		filter["id"] = bson.M{
+			"$ne": exceptSessionID,
+		}
+	}
+
+	update := bson.M{"$set": bson.M{
+		"revoked_at":     now,
+		"revoked_reason": reason,
+		"updated_at":     now,
+	}}
+
+	_, err = repository.sessionCollection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+func (repository *sessionRepository) GetActiveSessionsByUserID(ctx context.Context, userID string, now int64) (res []auth.SessionEntity, err error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked_at": nil,
+		"expired_at": bson.M{
+			"$gt": now,
+		},
+	}
+	opts := options.Find().SetSort(bson.D{
+		{
+			Key:   "last_seen_at",
+			Value: -1,
+		},
+	})
+
+	result, err := repository.sessionCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res = []auth.SessionEntity{}
+	err = result.All(ctx, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (repository *sessionRepository) TouchSession(ctx context.Context, id string, clientInfo auth.ClientInfo, now int64) (err error) {
+	filter := bson.M{"id": id}
+
+	update := bson.M{"$set": bson.M{
+		"last_seen_at": now,
+		"ip_address":   clientInfo.IPAddress,
+		"user_agent":   clientInfo.UserAgent,
+	}}
+
+	_, err = repository.sessionCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (repository *sessionRepository) MarkClaimsChanged(ctx context.Context, userID string, now int64) (err error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked_at": nil,
+	}
+
+	update := bson.M{"$set": bson.M{
+		"claims_changed_at": now,
+	}}
+
+	_, err = repository.sessionCollection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+func (repository *sessionRepository) SetCsrfSecret(ctx context.Context, id string, secret string) (err error) {
+	filter := bson.M{
+		"id":          id,
+		"csrf_secret": bson.M{"$exists": false},
+	}
+
+	update := bson.M{"$set": bson.M{
+		"csrf_secret": secret,
+	}}
+
+	_, err = repository.sessionCollection.UpdateOne(ctx, filter, update)
+	return err
+}
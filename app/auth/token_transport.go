@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"fmt"
+	_auth "mini-wallet/domain/auth"
+	"mini-wallet/domain/serviceaccount"
+	"mini-wallet/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	COOKIE_PREFIX_HOST   = "__Host-"
+	COOKIE_PREFIX_SECURE = "__Secure-"
+)
+
+// tokenTransport is the only place tokens travel between this service and its
+// clients. Browsers get cookies following the policy of AppConfig, mobile clients
+// read the tokens from the body and send the access token as a bearer token.
+type tokenTransport struct {
+	domain           string
+	sameSite         http.SameSite
+	secure           bool
+	prefix           string
+	accessTokenName  string
+	refreshTokenName string
+}
+
+func newTokenTransport(config *utils.AppConfig) (*tokenTransport, error) {
+	transport := &tokenTransport{
+		domain:           config.CookieDomain,
+		secure:           !config.CookieInsecure,
+		prefix:           config.CookiePrefix,
+		accessTokenName:  config.AccessTokenKey,
+		refreshTokenName: config.RefreshTokenKey,
+	}
+
+	switch strings.ToLower(config.CookieSameSite) {
+	case "", "lax":
+		transport.sameSite = http.SameSiteLaxMode
+	case "strict":
+		transport.sameSite = http.SameSiteStrictMode
+	case "none":
+		transport.sameSite = http.SameSiteNoneMode
+	default:
+		return nil, fmt.Errorf("unknown COOKIE_SAME_SITE %q", config.CookieSameSite)
+	}
+
+	// browsers silently drop these cookies, failing here is easier to notice
+	if transport.sameSite == http.SameSiteNoneMode && !transport.secure {
+		return nil, fmt.Errorf("COOKIE_SAME_SITE none requires secure cookies")
+	}
+
+	if strings.HasPrefix(transport.prefix, COOKIE_PREFIX_SECURE) && !transport.secure {
+		return nil, fmt.Errorf("cookie prefix %s requires secure cookies", COOKIE_PREFIX_SECURE)
+	}
+
+	if strings.HasPrefix(transport.prefix, COOKIE_PREFIX_HOST) && (!transport.secure || transport.domain != "") {
+		return nil, fmt.Errorf("cookie prefix %s requires secure cookies without COOKIE_DOMAIN", COOKIE_PREFIX_HOST)
+	}
+
+	return transport, nil
+}
+
+// cookie builds a cookie of the policy, name is the name without the prefix
+func (transport *tokenTransport) cookie(name string, value string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     transport.prefix + name,
+		Value:    value,
+		Domain:   transport.domain,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   transport.secure,
+		SameSite: transport.sameSite,
+		Expires:  expires,
+	}
+}
+
+// expiredCookie removes a cookie set by cookie
+func (transport *tokenTransport) expiredCookie(name string) *http.Cookie {
+	cookie := transport.cookie(name, "", time.Unix(0, 0))
+	cookie.MaxAge = -1
+	return cookie
+}
+
+// csrfCookie is the only cookie the frontend reads, it lives as long as the session
+func (transport *tokenTransport) csrfCookie(csrfToken string) *http.Cookie {
+	cookie := transport.cookie(_auth.CSRF_COOKIE, csrfToken, time.Now().Add(_auth.REFRESH_TOKEN_LIFETIME))
+	cookie.HttpOnly = false
+	return cookie
+}
+
+// sessionCookies carry the tokens of a session, each cookie lives as long as its
+// token. The CSRF token and the device id only come along when the session starts.
+func (transport *tokenTransport) sessionCookies(tokens _auth.AuthenticationResponse) []*http.Cookie {
+	now := time.Now()
+	cookies := []*http.Cookie{
+		transport.cookie(transport.accessTokenName, tokens.AccessToken, now.Add(_auth.ACCESS_TOKEN_LIFETIME)),
+		transport.cookie(transport.refreshTokenName, tokens.RefreshToken, now.Add(_auth.REFRESH_TOKEN_LIFETIME)),
+	}
+
+	if tokens.CsrfToken != "" {
+		cookies = append(cookies, transport.csrfCookie(tokens.CsrfToken))
+	}
+
+	if tokens.DeviceID != "" {
+		cookies = append(cookies, transport.cookie(_auth.DEVICE_COOKIE, tokens.DeviceID, now.Add(_auth.DEVICE_COOKIE_LIFETIME)))
+	}
+
+	return cookies
+}
+
+func (transport *tokenTransport) expiredSessionCookies() []*http.Cookie {
+	return []*http.Cookie{
+		transport.expiredCookie(transport.accessTokenName),
+		transport.expiredCookie(transport.refreshTokenName),
+		transport.expiredCookie(_auth.CSRF_COOKIE),
+	}
+}
+
+// hasSessionCookie tells whether the browser sent any token of a session
+func (transport *tokenTransport) hasSessionCookie(r *http.Request) bool {
+	_, hasAccessToken := transport.cookieValue(r, transport.accessTokenName)
+	_, hasRefreshToken := transport.cookieValue(r, transport.refreshTokenName)
+	return hasAccessToken || hasRefreshToken
+}
+
+// cookieValue reads a cookie set by cookie
+func (transport *tokenTransport) cookieValue(r *http.Request, name string) (string, bool) {
+	cookie, err := r.Cookie(transport.prefix + name)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+
+	return cookie.Value, true
+}
+
+// accessToken prefers the Authorization header over the cookie, bearer tells
+// which one it came from. API keys are not access tokens.
+func (transport *tokenTransport) accessToken(r *http.Request) (token string, bearer bool, found bool) {
+	token, found = getBearerToken(r)
+	if found && !strings.HasPrefix(token, serviceaccount.API_KEY_PREFIX) {
+		return token, true, true
+	}
+
+	token, found = transport.cookieValue(r, transport.accessTokenName)
+	return token, false, found
+}
+
+func (transport *tokenTransport) refreshToken(r *http.Request) (string, bool) {
+	return transport.cookieValue(r, transport.refreshTokenName)
+}
+
+// deviceID prefers the cookie of browsers over the header of other clients
+func (transport *tokenTransport) deviceID(r *http.Request) (string, bool) {
+	if deviceID, found := transport.cookieValue(r, _auth.DEVICE_COOKIE); found {
+		return deviceID, true
+	}
+
+	deviceID := r.Header.Get(_auth.DEVICE_HEADER)
+	return deviceID, deviceID != ""
+}
+
+func getBearerToken(r *http.Request) (string, bool) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return "", false
+	}
+
+	return token, true
+}
@@ -0,0 +1,392 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	_audit "mini-wallet/app/audit"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/user"
+	"mini-wallet/utils"
+	"strings"
+)
+
+const (
+	RECOVERY_CODE_LENGTH = 10
+)
+
+func (usecase *authUsecase) EnrollTwoFactor(ctx context.Context, userID string) (res response.Response[auth.TwoFactorEnrollmentDTO]) {
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	if existingUser.HashedPassword == nil {
+		res.BadRequest("Autentikasi dua langkah hanya tersedia untuk akun dengan kata sandi", nil)
+		return
+	}
+
+	if existingUser.IsTwoFactorEnabled() {
+		res.BadRequest("Autentikasi dua langkah sudah aktif", nil)
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	err = usecase.userRepository.SetTwoFactorPendingSecret(ctx, existingUser.UID, secret)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.Success(auth.TwoFactorEnrollmentDTO{
+		Secret:     secret,
+		OTPAuthURI: utils.BuildTOTPURI(auth.TWO_FACTOR_ISSUER, existingUser.Email, secret),
+	})
+	return
+}
+
+func (usecase *authUsecase) ConfirmTwoFactor(ctx context.Context, userID string, req auth.TwoFactorConfirmationDTO) (res response.Response[auth.TwoFactorRecoveryCodesDTO]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_TWO_FACTOR_ENABLE, audit.METHOD_TWO_FACTOR)
+	event.UserID = userID
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	if existingUser.TwoFactor == nil || existingUser.TwoFactor.PendingSecret == nil {
+		res.BadRequest("Mulai pendaftaran autentikasi dua langkah terlebih dahulu", nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	pendingSecret := *existingUser.TwoFactor.PendingSecret
+	step, ok := utils.ValidateTOTPCode(pendingSecret, req.Code, *now)
+	if !ok {
+		res.BadRequest("Kode verifikasi salah", nil)
+		return
+	}
+
+	recoveryCodes, hashedRecoveryCodes, err := generateRecoveryCodes()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	enabled, err := usecase.userRepository.EnableTwoFactor(ctx, existingUser.UID, pendingSecret, hashedRecoveryCodes, step, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !enabled {
+		res.BadRequest("Mulai pendaftaran autentikasi dua langkah terlebih dahulu", nil)
+		return
+	}
+
+	res.Success(auth.TwoFactorRecoveryCodesDTO{
+		RecoveryCodes: recoveryCodes,
+	})
+	return
+}
+
+func (usecase *authUsecase) DisableTwoFactor(ctx context.Context, userID string, req auth.TwoFactorDisableDTO) (res response.Response[string]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_TWO_FACTOR_DISABLE, audit.METHOD_TWO_FACTOR)
+	event.UserID = userID
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	if !existingUser.IsTwoFactorEnabled() {
+		res.BadRequest("Autentikasi dua langkah belum aktif", nil)
+		return
+	}
+
+	twoFactorAttempt := newAttempt(twoFactorPolicy, existingUser.UID)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, twoFactorAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
+	matched := false
+	if existingUser.HashedPassword != nil {
+		matched, err = usecase.verifyPassword(ctx, existingUser, req.Password)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+	}
+
+	if !matched {
+		err = usecase.recordFailedLogin(ctx, existingUser, twoFactorAttempt)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		res.BadRequest("Kata sandi salah", nil)
+		return
+	}
+
+	verified, err := usecase.verifyTwoFactor(ctx, *existingUser, req.Code, req.RecoveryCode)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !verified {
+		err = usecase.recordFailedLogin(ctx, existingUser, twoFactorAttempt)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		res.BadRequest("Kode verifikasi salah", nil)
+		return
+	}
+
+	err = usecase.userRepository.DisableTwoFactor(ctx, existingUser.UID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithMessage("Autentikasi dua langkah dinonaktifkan")
+	return
+}
+
+func (usecase *authUsecase) RegenerateRecoveryCodes(ctx context.Context, userID string, req auth.TwoFactorConfirmationDTO) (res response.Response[auth.TwoFactorRecoveryCodesDTO]) {
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	if !existingUser.IsTwoFactorEnabled() {
+		res.BadRequest("Autentikasi dua langkah belum aktif", nil)
+		return
+	}
+
+	twoFactorAttempt := newAttempt(twoFactorPolicy, existingUser.UID)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, twoFactorAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
+	// recovery codes can not be used to mint new ones
+	verified, err := usecase.verifyTwoFactor(ctx, *existingUser, req.Code, "")
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !verified {
+		err = usecase.recordFailedLogin(ctx, existingUser, twoFactorAttempt)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		res.BadRequest("Kode verifikasi salah", nil)
+		return
+	}
+
+	recoveryCodes, hashedRecoveryCodes, err := generateRecoveryCodes()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	err = usecase.userRepository.SetTwoFactorRecoveryCodes(ctx, existingUser.UID, hashedRecoveryCodes)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.Success(auth.TwoFactorRecoveryCodesDTO{
+		RecoveryCodes: recoveryCodes,
+	})
+	return
+}
+
+func (usecase *authUsecase) AuthenticateTwoFactor(ctx context.Context, req auth.TwoFactorAuthenticationDTO) (res response.Response[auth.AuthenticationResponse]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_LOGIN, audit.METHOD_TWO_FACTOR)
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	claims, status := auth.ValidateToken(req.ChallengeToken)
+	if status != 0 || claims.TokenType != auth.TOKEN_TYPE_TWO_FACTOR_CHALLENGE {
+		res.Unauthorized("Sesi masuk kedaluwarsa, silakan masuk kembali")
+		return
+	}
+
+	event.UserID = claims.Subject
+
+	existingUser, err := usecase.userRepository.GetUserByUserID(ctx, claims.Subject)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil || !existingUser.IsTwoFactorEnabled() {
+		res.Unauthorized("Sesi masuk kedaluwarsa, silakan masuk kembali")
+		return
+	}
+
+	twoFactorAttempt := newAttempt(twoFactorPolicy, existingUser.UID)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, twoFactorAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
+	verified, err := usecase.verifyTwoFactor(ctx, *existingUser, req.Code, req.RecoveryCode)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !verified {
+		err = usecase.recordFailedLogin(ctx, existingUser, twoFactorAttempt)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		res.BadRequest("Kode verifikasi salah", nil)
+		return
+	}
+
+	err = usecase.attemptLimiter.reset(ctx, twoFactorAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	tokens, err := usecase.signIn(ctx, *existingUser)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithCookie("success", *tokens, usecase.tokenTransport.sessionCookies(*tokens))
+
+	return
+}
+
+// twoFactorChallenge ends the password step of a 2FA user, no session is started yet
+func (usecase *authUsecase) twoFactorChallenge(userEntity user.UserEntity) (res response.Response[auth.AuthenticationResponse]) {
+	challengeToken, err := auth.GenerateJWT(userEntity, auth.TOKEN_TYPE_TWO_FACTOR_CHALLENGE, "", utils.GenerateUniqueId())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.Success(auth.AuthenticationResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challengeToken,
+	})
+	return
+}
+
+// verifyTwoFactor checks a TOTP code, or a recovery code when code is empty. Both are single use.
+func (usecase *authUsecase) verifyTwoFactor(ctx context.Context, userEntity user.UserEntity, code string, recoveryCode string) (bool, error) {
+	if code != "" {
+		now, err := utils.GetJktTime()
+		if err != nil {
+			return false, err
+		}
+
+		step, ok := utils.ValidateTOTPCode(userEntity.TwoFactor.Secret, code, *now)
+		if !ok {
+			return false, nil
+		}
+
+		return usecase.userRepository.UseTwoFactorStep(ctx, userEntity.UID, step)
+	}
+
+	if recoveryCode == "" {
+		return false, nil
+	}
+
+	return usecase.userRepository.UseTwoFactorRecoveryCode(ctx, userEntity.UID, utils.HashToken(normalizeRecoveryCode(recoveryCode)))
+}
+
+// generateRecoveryCodes returns the codes shown once to the user and the hashes to store
+func generateRecoveryCodes() (codes []string, hashedCodes []string, err error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	for i := 0; i < auth.TWO_FACTOR_RECOVERY_CODE_COUNT; i++ {
+		b := make([]byte, RECOVERY_CODE_LENGTH)
+		_, err = rand.Read(b)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(encoding.EncodeToString(b)[:RECOVERY_CODE_LENGTH])
+		codes = append(codes, code[:RECOVERY_CODE_LENGTH/2]+"-"+code[RECOVERY_CODE_LENGTH/2:])
+		hashedCodes = append(hashedCodes, utils.HashToken(code))
+	}
+
+	return codes, hashedCodes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	_audit "mini-wallet/app/audit"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/utils"
+	"time"
+)
+
+func (usecase *authUsecase) RequestWhatsAppCode(ctx context.Context, req auth.WhatsAppCodeRequestDTO) (res response.Response[string]) {
+	phoneAttempt := newAttempt(whatsAppCodePhonePolicy, req.PhoneNumber)
+	ipAttempt := newAttempt(whatsAppCodeIPPolicy, auth.GetClientInfo(ctx).IPAddress)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, phoneAttempt, ipAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
+	for _, a := range []attempt{phoneAttempt, ipAttempt} {
+		_, err = usecase.attemptLimiter.record(ctx, a)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+	}
+
+	existingUser, err := usecase.userRepository.GetUserByPhoneNumber(ctx, req.PhoneNumber)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.BadRequest("Nomor handphone belum terdaftar", nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	code, err := utils.GenerateNumericCode(auth.ONE_TIME_CODE_LENGTH)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	oneTimeCode := auth.OneTimeCodeEntity{
+		ID:          utils.GenerateUniqueId(),
+		Purpose:     auth.ONE_TIME_CODE_PURPOSE_WHATSAPP_LOGIN,
+		Destination: req.PhoneNumber,
+		CreatedAt:   now.Unix(),
+		ExpiredAt:   now.Add(auth.ONE_TIME_CODE_LIFETIME).Unix(),
+	}
+	oneTimeCode.CodeHash = auth.HashOneTimeCode(oneTimeCode.ID, code)
+
+	err = usecase.oneTimeCodeRepository.UpsertCode(ctx, oneTimeCode)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	message := fmt.Sprintf("Kode masuk Sebia Anda: %s\nBerlaku %d menit. Jangan berikan kode ini kepada siapa pun, termasuk pihak Sebia.", code, int(auth.ONE_TIME_CODE_LIFETIME.Minutes()))
+	err = usecase.notificationService.SendWhatsAppMessage(ctx, message, req.PhoneNumber)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithMessage("Kode dikirimkan ke WhatsApp Anda")
+	return
+}
+
+func (usecase *authUsecase) AuthenticateWithWhatsAppCode(ctx context.Context, req auth.WhatsAppCodeVerificationDTO) (res response.Response[auth.AuthenticationResponse]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_LOGIN, audit.METHOD_WHATSAPP)
+	event.Identifier = req.PhoneNumber
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	ipAttempt := newAttempt(loginIPPolicy, auth.GetClientInfo(ctx).IPAddress)
+	retryAfter, err := usecase.attemptLimiter.check(ctx, ipAttempt)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(tooManyAttemptsMessage(retryAfter), retryAfterSeconds(retryAfter))
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	oneTimeCode, err := usecase.oneTimeCodeRepository.GetCode(ctx, auth.ONE_TIME_CODE_PURPOSE_WHATSAPP_LOGIN, req.PhoneNumber, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if oneTimeCode == nil {
+		res.BadRequest("Kode salah atau kedaluwarsa, silakan minta kode baru", nil)
+		return
+	}
+
+	// the guess is counted before comparing, parallel guesses can not outrun the limit
+	claimed, attempts, err := usecase.oneTimeCodeRepository.ClaimAttempt(ctx, oneTimeCode.ID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !claimed {
+		res.BadRequest("Kode salah atau kedaluwarsa, silakan minta kode baru", nil)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(auth.HashOneTimeCode(oneTimeCode.ID, req.Code)), []byte(oneTimeCode.CodeHash)) != 1 {
+		err = usecase.recordFailedLogin(ctx, nil, ipAttempt)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		if attempts >= auth.ONE_TIME_CODE_MAX_ATTEMPTS {
+			_, err = usecase.oneTimeCodeRepository.DeleteCode(ctx, oneTimeCode.ID)
+			if err != nil {
+				res.InternalServerError(err.Error())
+				return
+			}
+
+			res.BadRequest("Terlalu banyak percobaan, silakan minta kode baru", nil)
+			return
+		}
+
+		res.BadRequest("Kode salah", nil)
+		return
+	}
+
+	// a concurrent verification may have consumed the code already
+	deleted, err := usecase.oneTimeCodeRepository.DeleteCode(ctx, oneTimeCode.ID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !deleted {
+		res.BadRequest("Kode salah atau kedaluwarsa, silakan minta kode baru", nil)
+		return
+	}
+
+	existingUser, err := usecase.userRepository.GetUserByPhoneNumber(ctx, req.PhoneNumber)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser == nil {
+		res.BadRequest("Nomor handphone belum terdaftar", nil)
+		return
+	}
+
+	event.UserID = existingUser.UID
+
+	if existingUser.PhoneNumberVerifiedAt == nil {
+		verifiedAt := now.Format(time.RFC3339)
+		err = usecase.userRepository.SetPhoneNumberVerified(ctx, existingUser.UID, verifiedAt)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		existingUser.PhoneNumberVerifiedAt = &verifiedAt
+	}
+
+	return usecase.completeSignIn(ctx, *existingUser)
+}
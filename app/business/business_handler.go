@@ -21,9 +21,10 @@ func SetBusinessHandler(router *chi.Mux, usecases domain.Usecases, middleware _a
 	}
 
 	router.Route("/businesses/", func(r chi.Router) {
+		r.Use(middleware.AllowApiKey)
 		r.Use(middleware.AuthMiddleware)
-		r.Post("/", businessHandler.CreateBusiness)
-		r.Get("/status", businessHandler.GetUserBusinessStatus)
+		r.With(middleware.RequirePermission(_auth.PERMISSION_BUSINESS_CREATE)).Post("/", businessHandler.CreateBusiness)
+		r.With(middleware.RequirePermission(_auth.PERMISSION_BUSINESS_READ)).Get("/status", businessHandler.GetUserBusinessStatus)
 	})
 
 	router.Route("/public/businesses", func(r chi.Router) {
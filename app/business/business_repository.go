@@ -8,6 +8,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type businessRepository struct {
@@ -68,3 +69,62 @@ func (repository *businessRepository) GetBusinessById(ctx context.Context, id st
 
 	return res, nil
 }
+
+func (repository *businessRepository) GetBusinesses(ctx context.Context, filter business.BusinessFilter) (res []business.BusinessEntity, total int64, err error) {
+	query := bson.M{}
+	if filter.Status != 0 {
+		query["status"] = filter.Status
+	}
+
+	if filter.ProvinceID != 0 {
+		query["province_id"] = filter.ProvinceID
+	}
+
+	if filter.CityID != 0 {
+		query["city_id"] = filter.CityID
+	}
+
+	total, err = repository.businessCollection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((filter.Page - 1) * filter.Size)).
+		SetLimit(int64(filter.Size))
+
+	result, err := repository.businessCollection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	res = []business.BusinessEntity{}
+	err = result.All(ctx, &res)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return res, total, nil
+}
+
+func (repository *businessRepository) UpdateBusinessStatus(ctx context.Context, id string, fromStatus int64, toStatus int64, rejectionReason *string, now int64) (updated bool, err error) {
+	filter := bson.M{
+		"id":     id,
+		"status": fromStatus,
+	}
+
+	update := bson.M{"$set": bson.M{
+		"status":           toStatus,
+		"rejection_reason": rejectionReason,
+		"reviewed_at":      now,
+		"updated_at":       now,
+	}}
+
+	result, err := repository.businessCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
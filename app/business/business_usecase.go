@@ -205,12 +205,7 @@ func (uc *businessUsecase) GetUserBusinessStatus(ctx context.Context, userID str
 		return
 	}
 
-	if userBusiness.Status == 1 {
-		pending := "pending"
-		res.Success(&pending)
-		return
-	}
-
-	res.Success(&userBusiness.ID)
+	status := userBusiness.GetOwnerStatus()
+	res.Success(&status)
 	return
 }
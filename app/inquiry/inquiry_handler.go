@@ -23,7 +23,9 @@ func SetInquiryHandler(router *chi.Mux, usecases domain.Usecases, middleware _au
 	}
 
 	router.Route("/inquiries", func(r chi.Router) {
+		r.Use(middleware.AllowApiKey)
 		r.Use(middleware.AuthMiddleware)
+		r.Use(middleware.RequirePermission(_auth.PERMISSION_INQUIRY_READ))
 	})
 
 	router.Route("/public/inquiries", func(r chi.Router) {
@@ -63,3 +63,40 @@ func (repo *inquiryRepository) GetInquiryById(ctx context.Context, id string) (r
 
 	return res, nil
 }
+
+func (repo *inquiryRepository) GetInquiriesOfUser(ctx context.Context, userID string, email string) (res []inquiry.InquiryEntity, err error) {
+	filter := bson.M{"$or": []bson.M{
+		{"user_id": userID},
+		{"email": email},
+	}}
+
+	result, err := repo.inquiryCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	res = []inquiry.InquiryEntity{}
+	err = result.All(ctx, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (repo *inquiryRepository) AnonymizeInquiries(ctx context.Context, userID string, email string, anonymizedName string) (err error) {
+	filter := bson.M{"$or": []bson.M{
+		{"user_id": userID},
+		{"email": email},
+	}}
+
+	update := bson.M{"$set": bson.M{
+		"full_name":    anonymizedName,
+		"phone_number": "",
+		"email":        "",
+		"user_id":      nil,
+	}}
+
+	_, err = repo.inquiryCollection.UpdateMany(ctx, filter, update)
+	return err
+}
@@ -0,0 +1,118 @@
+package moderation
+
+import (
+	"mini-wallet/domain"
+	_auth "mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/moderation"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/gorilla/schema"
+)
+
+type moderationHandler struct {
+	moderationUsecase moderation.ModerationUsecase
+	decoder           *schema.Decoder
+}
+
+func SetModerationHandler(router *chi.Mux, usecases domain.Usecases, middleware _auth.AuthMiddleware) {
+	moderationHandler := moderationHandler{
+		moderationUsecase: usecases.ModerationUsecase,
+		decoder:           schema.NewDecoder(),
+	}
+
+	router.Route("/admin/applications", func(r chi.Router) {
+		r.Use(middleware.AllowApiKey)
+		r.Use(middleware.AuthMiddleware)
+		r.Use(middleware.RequirePermission(_auth.PERMISSION_APPLICATION_REVIEW))
+		r.Get("/", moderationHandler.GetApplications)
+		r.Get("/{type}/{id}", moderationHandler.GetApplication)
+		r.Post("/{type}/{id}/approve", moderationHandler.ApproveApplication)
+		r.Post("/{type}/{id}/reject", moderationHandler.RejectApplication)
+	})
+}
+
+func (handler *moderationHandler) GetApplications(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	var params moderation.GetApplicationsRequest
+	if err := handler.decoder.Decode(&params, r.URL.Query()); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := params.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	res := handler.moderationUsecase.GetApplications(r.Context(), params)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *moderationHandler) GetApplication(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	applicationType := chi.URLParam(r, "type")
+	if !moderation.IsValidApplicationType(applicationType) {
+		resp.BadRequest(moderation.ErrUnknownApplicationType.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	res := handler.moderationUsecase.GetApplication(r.Context(), applicationType, chi.URLParam(r, "id"))
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *moderationHandler) ApproveApplication(w http.ResponseWriter, r *http.Request) {
+	handler.decideApplication(w, r, moderation.DECISION_APPROVE)
+}
+
+func (handler *moderationHandler) RejectApplication(w http.ResponseWriter, r *http.Request) {
+	handler.decideApplication(w, r, moderation.DECISION_REJECT)
+}
+
+func (handler *moderationHandler) decideApplication(w http.ResponseWriter, r *http.Request, decision string) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	applicationType := chi.URLParam(r, "type")
+	if !moderation.IsValidApplicationType(applicationType) {
+		resp.BadRequest(moderation.ErrUnknownApplicationType.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	// the body is optional for approvals
+	req := moderation.DecisionDTO{}
+	if r.ContentLength != 0 {
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			resp.BadRequest(err.Error(), nil)
+			resp.WriteResponse()
+			return
+		}
+	}
+
+	if err := req.Validate(decision); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	moderatorID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.moderationUsecase.DecideApplication(r.Context(), *moderatorID, applicationType, chi.URLParam(r, "id"), decision, req)
+	res.Writer = w
+	res.WriteResponse()
+}
@@ -0,0 +1,56 @@
+package moderation
+
+import (
+	"context"
+	"mini-wallet/domain"
+	"mini-wallet/domain/moderation"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type moderationRepository struct {
+	decisionCollection *mongo.Collection
+}
+
+func NewModerationRepository(repositoryParam domain.RepositoryParam) moderation.ModerationRepository {
+	return &moderationRepository{
+		decisionCollection: repositoryParam.Mongo.Collection("moderation_decision"),
+	}
+}
+
+func (repository *moderationRepository) InsertDecision(ctx context.Context, entity moderation.ModerationDecisionEntity) (err error) {
+	_, err = repository.decisionCollection.InsertOne(ctx, entity)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *moderationRepository) GetDecisions(ctx context.Context, applicationType string, applicationID string) (res []moderation.ModerationDecisionEntity, err error) {
+	filter := bson.M{
+		"application_type": applicationType,
+		"application_id":   applicationID,
+	}
+	opts := options.Find().SetSort(bson.D{
+		{
+			Key:   "created_at",
+			Value: -1,
+		},
+	})
+
+	result, err := repository.decisionCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res = []moderation.ModerationDecisionEntity{}
+	err = result.All(ctx, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,404 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"mini-wallet/domain"
+	"mini-wallet/domain/affiliate"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/business"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/moderation"
+	"mini-wallet/domain/user"
+	"mini-wallet/infrastructure"
+	"mini-wallet/integration"
+	"mini-wallet/utils"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// requirement files are uploaded to the private bucket, see file.UploadFile
+const REQUIREMENT_FILE_BUCKET = "sebia"
+
+const APPLICATION_ALREADY_DECIDED_MESSAGE = "Pengajuan sudah diputuskan sebelumnya"
+
+type moderationUsecase struct {
+	moderationRepository moderation.ModerationRepository
+	businessRepository   business.BusinessRepository
+	affiliateRepository  affiliate.AffiliateRepository
+	userRepository       user.UserRepository
+	sessionRepository    auth.SessionRepository
+	notificationService  integration.NotificationService
+	s3Service            s3.S3
+	config               *utils.AppConfig
+}
+
+func NewModerationUsecase(repositories domain.Repositories, integrations domain.Infrastructure, config *utils.AppConfig) moderation.ModerationUsecase {
+	return &moderationUsecase{
+		moderationRepository: repositories.ModerationRepository,
+		businessRepository:   repositories.BusinessRepository,
+		affiliateRepository:  repositories.AffiliateRepository,
+		userRepository:       repositories.UserRepository,
+		sessionRepository:    repositories.SessionRepository,
+		notificationService:  integrations.NotificationService,
+		s3Service:            integrations.S3,
+		config:               config,
+	}
+}
+
+func (usecase *moderationUsecase) GetApplications(ctx context.Context, req moderation.GetApplicationsRequest) (res response.Response[moderation.ApplicationPageDTO]) {
+	page := moderation.ApplicationPageDTO{
+		Applications: []moderation.ApplicationDTO{},
+		Page:         req.Page,
+		Size:         req.Size,
+	}
+
+	switch req.Type {
+	case moderation.APPLICATION_TYPE_BUSINESS:
+		businesses, total, err := usecase.businessRepository.GetBusinesses(ctx, business.BusinessFilter{
+			Status:     businessStatus(req.Status),
+			ProvinceID: req.ProvinceID,
+			CityID:     req.CityID,
+			Page:       req.Page,
+			Size:       req.Size,
+		})
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		for _, businessEntity := range businesses {
+			page.Applications = append(page.Applications, businessApplication(businessEntity))
+		}
+		page.Total = total
+	case moderation.APPLICATION_TYPE_AFFILIATE:
+		affiliates, total, err := usecase.affiliateRepository.GetAffiliates(ctx, affiliate.AffiliateFilter{
+			Status:     int(businessStatus(req.Status)),
+			ProvinceID: int(req.ProvinceID),
+			CityID:     int(req.CityID),
+			Page:       req.Page,
+			Size:       req.Size,
+		})
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		for _, affiliateEntity := range affiliates {
+			page.Applications = append(page.Applications, affiliateApplication(affiliateEntity))
+		}
+		page.Total = total
+	}
+
+	res.Success(page)
+	return
+}
+
+func (usecase *moderationUsecase) GetApplication(ctx context.Context, applicationType string, id string) (res response.Response[moderation.ApplicationDTO]) {
+	application, err := usecase.getApplication(ctx, applicationType, id)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if application == nil {
+		res.NotFound("Pengajuan tidak ditemukan", nil)
+		return
+	}
+
+	err = usecase.withDetails(ctx, application)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.Success(*application)
+	return
+}
+
+func (usecase *moderationUsecase) DecideApplication(ctx context.Context, moderatorID string, applicationType string, id string, decision string, req moderation.DecisionDTO) (res response.Response[moderation.ApplicationDTO]) {
+	application, err := usecase.getApplication(ctx, applicationType, id)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if application == nil {
+		res.NotFound("Pengajuan tidak ditemukan", nil)
+		return
+	}
+
+	if application.Status != moderation.APPLICATION_STATUS_PENDING {
+		res.BadRequest(APPLICATION_ALREADY_DECIDED_MESSAGE, nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	var reason *string
+	if req.Reason != nil && strings.TrimSpace(*req.Reason) != "" {
+		trimmed := strings.TrimSpace(*req.Reason)
+		reason = &trimmed
+	}
+
+	status := moderation.APPLICATION_STATUS_APPROVED
+	var rejectionReason *string
+	if decision == moderation.DECISION_REJECT {
+		status = moderation.APPLICATION_STATUS_REJECTED
+		rejectionReason = reason
+	}
+
+	updated := false
+	switch applicationType {
+	case moderation.APPLICATION_TYPE_BUSINESS:
+		updated, err = usecase.businessRepository.UpdateBusinessStatus(ctx, id, business.BUSINESS_STATUS_PENDING, businessStatus(status), rejectionReason, now.Unix())
+	case moderation.APPLICATION_TYPE_AFFILIATE:
+		updated, err = usecase.affiliateRepository.UpdateAffiliateStatus(ctx, id, affiliate.AFFILIATE_STATUS_PENDING, int(businessStatus(status)), rejectionReason, now.Unix())
+	}
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !updated {
+		res.BadRequest(APPLICATION_ALREADY_DECIDED_MESSAGE, nil)
+		return
+	}
+
+	err = usecase.moderationRepository.InsertDecision(ctx, moderation.ModerationDecisionEntity{
+		ID:              utils.GenerateUniqueId(),
+		ApplicationType: applicationType,
+		ApplicationID:   id,
+		ApplicantID:     application.ApplicantID,
+		ModeratorID:     moderatorID,
+		Decision:        decision,
+		Reason:          reason,
+		CreatedAt:       now.Unix(),
+	})
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if decision == moderation.DECISION_APPROVE {
+		// the sessions of the applicant pick the role up on their next request
+		err = grantRole(ctx, usecase.userRepository, usecase.sessionRepository, application.ApplicantID, applicationRole(applicationType), now.Unix())
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+	}
+
+	application.Status = status
+	application.RejectionReason = rejectionReason
+	reviewedAt := now.Unix()
+	application.ReviewedAt = &reviewedAt
+
+	err = usecase.withDetails(ctx, application)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	go usecase.notifyApplicant(*application)
+
+	res.Success(*application)
+	return
+}
+
+func (usecase *moderationUsecase) getApplication(ctx context.Context, applicationType string, id string) (*moderation.ApplicationDTO, error) {
+	switch applicationType {
+	case moderation.APPLICATION_TYPE_BUSINESS:
+		businessEntity, err := usecase.businessRepository.GetBusinessById(ctx, id)
+		if err != nil || businessEntity == nil {
+			return nil, err
+		}
+
+		application := businessApplication(*businessEntity)
+		return &application, nil
+	case moderation.APPLICATION_TYPE_AFFILIATE:
+		affiliateEntity, err := usecase.affiliateRepository.GetAffiliateByUserId(ctx, id)
+		if err != nil || affiliateEntity == nil {
+			return nil, err
+		}
+
+		application := affiliateApplication(*affiliateEntity)
+		return &application, nil
+	}
+
+	return nil, nil
+}
+
+// withDetails adds what the list leaves out: the applicant, the decision history
+// and a link to the requirement file that is valid for a few minutes
+func (usecase *moderationUsecase) withDetails(ctx context.Context, application *moderation.ApplicationDTO) error {
+	applicant, err := usecase.userRepository.GetUserByUserID(ctx, application.ApplicantID)
+	if err != nil {
+		return err
+	}
+
+	if applicant != nil {
+		application.ApplicantName = applicant.Name
+	}
+
+	decisions, err := usecase.moderationRepository.GetDecisions(ctx, application.Type, application.ID)
+	if err != nil {
+		return err
+	}
+
+	application.Decisions = []moderation.ModerationDecisionDTO{}
+	for _, decision := range decisions {
+		application.Decisions = append(application.Decisions, decision.ToModerationDecisionDTO())
+	}
+
+	if application.RequirementFileUrl != nil && *application.RequirementFileUrl != "" && !strings.HasPrefix(*application.RequirementFileUrl, "http") {
+		request, _ := usecase.s3Service.GetObjectRequest(&s3.GetObjectInput{
+			Bucket: aws.String(REQUIREMENT_FILE_BUCKET),
+			Key:    application.RequirementFileUrl,
+		})
+
+		url, err := request.Presign(moderation.REQUIREMENT_FILE_URL_LIFETIME_MINUTES * time.Minute)
+		if err != nil {
+			return err
+		}
+		application.RequirementFileUrl = &url
+	}
+
+	return nil
+}
+
+// notifyApplicant prefers WhatsApp and falls back to email
+func (usecase *moderationUsecase) notifyApplicant(application moderation.ApplicationDTO) {
+	ctx := context.Background()
+	applicant, err := usecase.userRepository.GetUserByUserID(ctx, application.ApplicantID)
+	if err != nil || applicant == nil {
+		fmt.Println("error notifying applicant:", application.ApplicantID, err)
+		return
+	}
+
+	title, message := buildDecisionMessage(application)
+	reason := ""
+	if application.RejectionReason != nil {
+		reason = *application.RejectionReason
+	}
+
+	if applicant.PhoneNumber != nil {
+		whatsAppMessage := fmt.Sprintf("Halo %s,\n%s", applicant.Name, message)
+		if reason != "" {
+			whatsAppMessage += "\nAlasan: " + reason
+		}
+
+		err = usecase.notificationService.SendWhatsAppMessage(ctx, whatsAppMessage, *applicant.PhoneNumber)
+		if err == nil {
+			return
+		}
+		fmt.Println("error sending application decision:", err.Error())
+	}
+
+	infrastructure.SendApplicationDecision(applicant.Email, applicant.Name, title, message, reason, usecase.config.AppDomain)
+}
+
+func buildDecisionMessage(application moderation.ApplicationDTO) (title string, message string) {
+	subject := "affiliate"
+	if application.Type == moderation.APPLICATION_TYPE_BUSINESS {
+		subject = "bisnis"
+		if details, ok := application.Details.(business.BusinessDTO); ok {
+			subject = fmt.Sprintf("bisnis \"%s\"", details.Name)
+		}
+	}
+
+	if application.Status == moderation.APPLICATION_STATUS_APPROVED {
+		return "Pengajuan Disetujui", fmt.Sprintf("Selamat, pengajuan %s Anda telah disetujui.", subject)
+	}
+
+	return "Pengajuan Ditolak", fmt.Sprintf("Mohon maaf, pengajuan %s Anda belum dapat kami setujui.", subject)
+}
+
+func businessApplication(businessEntity business.BusinessEntity) moderation.ApplicationDTO {
+	status := applicationStatus(businessEntity.Status)
+	requirementFileUrl := businessEntity.RequirementFileUrl
+
+	return moderation.ApplicationDTO{
+		Type:               moderation.APPLICATION_TYPE_BUSINESS,
+		ID:                 businessEntity.ID,
+		ApplicantID:        businessEntity.UserID,
+		Status:             status,
+		RequirementFileUrl: &requirementFileUrl,
+		RejectionReason:    businessEntity.RejectionReason,
+		Details: business.BusinessDTO{
+			Name:               businessEntity.Name,
+			PhoneNumber:        businessEntity.PhoneNumber,
+			Address:            businessEntity.Address,
+			RequirementFileUrl: businessEntity.RequirementFileUrl,
+			CityID:             businessEntity.CityID,
+			ProvinceID:         businessEntity.ProvinceID,
+			DistrictID:         businessEntity.DistrictID,
+			Status:             status,
+		},
+		CreatedAt:  businessEntity.CreatedAt,
+		ReviewedAt: businessEntity.ReviewedAt,
+	}
+}
+
+// affiliate applications have no id of their own, there is one per user
+func affiliateApplication(affiliateEntity affiliate.AffiliateEntity) moderation.ApplicationDTO {
+	return moderation.ApplicationDTO{
+		Type:            moderation.APPLICATION_TYPE_AFFILIATE,
+		ID:              affiliateEntity.UserID,
+		ApplicantID:     affiliateEntity.UserID,
+		Status:          applicationStatus(int64(affiliateEntity.Status)),
+		RejectionReason: affiliateEntity.RejectionReason,
+		Details: affiliate.AffiliateAppicationDTO{
+			InstagramUsername: affiliateEntity.InstagramUsername,
+			TiktokUsername:    affiliateEntity.TiktokUsername,
+			Age:               affiliateEntity.Age,
+			GenderID:          affiliateEntity.GenderID,
+			Address:           affiliateEntity.Address,
+			ProvinceID:        affiliateEntity.ProvinceID,
+			CityID:            affiliateEntity.CityID,
+			DistrictID:        affiliateEntity.DistrictID,
+			UserID:            affiliateEntity.UserID,
+		},
+		CreatedAt:  affiliateEntity.CreatedAt,
+		ReviewedAt: affiliateEntity.ReviewedAt,
+	}
+}
+
+func applicationRole(applicationType string) string {
+	if applicationType == moderation.APPLICATION_TYPE_AFFILIATE {
+		return auth.ROLE_AFFILIATE
+	}
+
+	return auth.ROLE_HOST
+}
+
+// business and affiliate statuses share the same values
+func businessStatus(status string) int64 {
+	switch status {
+	case moderation.APPLICATION_STATUS_APPROVED:
+		return business.BUSINESS_STATUS_APPROVED
+	case moderation.APPLICATION_STATUS_REJECTED:
+		return business.BUSINESS_STATUS_REJECTED
+	default:
+		return business.BUSINESS_STATUS_PENDING
+	}
+}
+
+// anything else than pending or rejected was approved by hand before this API existed
+func applicationStatus(status int64) string {
+	switch status {
+	case business.BUSINESS_STATUS_PENDING:
+		return moderation.APPLICATION_STATUS_PENDING
+	case business.BUSINESS_STATUS_REJECTED:
+		return moderation.APPLICATION_STATUS_REJECTED
+	default:
+		return moderation.APPLICATION_STATUS_APPROVED
+	}
+}
@@ -0,0 +1,94 @@
+package moderation
+
+import (
+	"context"
+	"mini-wallet/domain"
+	"mini-wallet/domain/affiliate"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/business"
+	"mini-wallet/domain/moderation"
+	"mini-wallet/domain/user"
+	"mini-wallet/utils"
+)
+
+const syncPageSize = 100
+
+// SyncApprovedRoles grants the host and affiliate roles to applicants approved
+// before roles existed, who were approved by editing the status by hand. Such
+// statuses are normalized to approved. Safe to run on every start.
+func SyncApprovedRoles(ctx context.Context, repositories domain.Repositories) error {
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return err
+	}
+
+	for page := 1; ; page++ {
+		businesses, _, err := repositories.BusinessRepository.GetBusinesses(ctx, business.BusinessFilter{Page: page, Size: syncPageSize})
+		if err != nil {
+			return err
+		}
+
+		for _, businessEntity := range businesses {
+			if applicationStatus(businessEntity.Status) != moderation.APPLICATION_STATUS_APPROVED {
+				continue
+			}
+
+			if businessEntity.Status != business.BUSINESS_STATUS_APPROVED {
+				_, err = repositories.BusinessRepository.UpdateBusinessStatus(ctx, businessEntity.ID, businessEntity.Status, business.BUSINESS_STATUS_APPROVED, nil, now.Unix())
+				if err != nil {
+					return err
+				}
+			}
+
+			err = grantRole(ctx, repositories.UserRepository, repositories.SessionRepository, businessEntity.UserID, auth.ROLE_HOST, now.Unix())
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(businesses) < syncPageSize {
+			break
+		}
+	}
+
+	for page := 1; ; page++ {
+		affiliates, _, err := repositories.AffiliateRepository.GetAffiliates(ctx, affiliate.AffiliateFilter{Page: page, Size: syncPageSize})
+		if err != nil {
+			return err
+		}
+
+		for _, affiliateEntity := range affiliates {
+			if applicationStatus(int64(affiliateEntity.Status)) != moderation.APPLICATION_STATUS_APPROVED {
+				continue
+			}
+
+			if affiliateEntity.Status != affiliate.AFFILIATE_STATUS_APPROVED {
+				_, err = repositories.AffiliateRepository.UpdateAffiliateStatus(ctx, affiliateEntity.UserID, affiliateEntity.Status, affiliate.AFFILIATE_STATUS_APPROVED, nil, now.Unix())
+				if err != nil {
+					return err
+				}
+			}
+
+			err = grantRole(ctx, repositories.UserRepository, repositories.SessionRepository, affiliateEntity.UserID, auth.ROLE_AFFILIATE, now.Unix())
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(affiliates) < syncPageSize {
+			break
+		}
+	}
+
+	return nil
+}
+
+// grantRole adds the role, the sessions of the user only refresh when it is new
+func grantRole(ctx context.Context, userRepository user.UserRepository, sessionRepository auth.SessionRepository, userID string, role string, now int64) error {
+	added, err := userRepository.AddRole(ctx, userID, role)
+	if err != nil || !added {
+		return err
+	}
+
+	return sessionRepository.MarkClaimsChanged(ctx, userID, now)
+}
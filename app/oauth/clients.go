@@ -0,0 +1,42 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"mini-wallet/domain/oauth"
+	"mini-wallet/utils"
+	"os"
+)
+
+// SyncClients upserts the clients listed in a JSON file, registered clients
+// are managed through that file only. Secrets are stored as client_secret_hash
+// (hex sha256), a client without one is public and must use PKCE.
+func SyncClients(ctx context.Context, repository oauth.OAuthRepository, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	clients := []oauth.ClientEntity{}
+	err = json.Unmarshal(content, &clients)
+	if err != nil {
+		return err
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return err
+	}
+
+	for _, client := range clients {
+		client.CreatedAt = now.Unix()
+		client.UpdatedAt = now.Unix()
+
+		err = repository.UpsertClient(ctx, client)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
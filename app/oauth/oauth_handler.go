@@ -0,0 +1,215 @@
+package oauth
+
+import (
+	"mini-wallet/domain"
+	_auth "mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/oauth"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type oauthHandler struct {
+	oauthUsecase oauth.OAuthUsecase
+	authUsecase  _auth.AuthUsecase
+}
+
+func SetOAuthHandler(router *chi.Mux, usecases domain.Usecases, middleware _auth.AuthMiddleware) {
+	oauthHandler := oauthHandler{
+		oauthUsecase: usecases.OAuthUsecase,
+		authUsecase:  usecases.AuthUsecase,
+	}
+
+	router.Get("/.well-known/openid-configuration", oauthHandler.GetDiscoveryDocument)
+
+	router.Route("/oauth/authorize", func(r chi.Router) {
+		r.With(middleware.OptionalAuthMiddleware).Get("/", oauthHandler.Authorize)
+		r.With(middleware.AuthMiddleware, middleware.DenyImpersonation).Post("/", oauthHandler.SubmitConsent)
+	})
+
+	router.Route("/oauth/", func(r chi.Router) {
+		r.Get("/clients/{clientId}", oauthHandler.GetClient)
+		r.Post("/token", oauthHandler.Token)
+		r.Post("/introspect", oauthHandler.Introspect)
+		r.Get("/userinfo", oauthHandler.UserInfo)
+		r.Post("/userinfo", oauthHandler.UserInfo)
+	})
+}
+
+func (handler *oauthHandler) GetDiscoveryDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	render.JSON(w, r, handler.oauthUsecase.GetDiscoveryDocument())
+}
+
+func (handler *oauthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+	sessionID := r.Context().Value(_auth.SessionIDContext{}).(string)
+
+	query := r.URL.Query()
+	req := oauth.AuthorizationRequestDTO{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		Nonce:               query.Get("nonce"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+		Prompt:              query.Get("prompt"),
+	}
+
+	redirectTo, oauthErr := handler.oauthUsecase.Authorize(r.Context(), userID, sessionID, req)
+	if oauthErr != nil {
+		writeError(w, r, oauthErr)
+		return
+	}
+
+	http.Redirect(w, r, redirectTo, http.StatusFound)
+}
+
+func (handler *oauthHandler) SubmitConsent(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+	sessionID := r.Context().Value(_auth.SessionIDContext{}).(string)
+	if userID == nil {
+		resp.Unauthorized(response.ERROR_UNAUTHORIZED)
+		resp.WriteResponse()
+		return
+	}
+
+	req := oauth.ConsentDTO{}
+	err := render.DecodeJSON(r.Body, &req)
+	if err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	res := handler.oauthUsecase.SubmitConsent(r.Context(), *userID, sessionID, req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *oauthHandler) GetClient(w http.ResponseWriter, r *http.Request) {
+	res := handler.oauthUsecase.GetClient(r.Context(), chi.URLParam(r, "clientId"))
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *oauthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	err := r.ParseForm()
+	if err != nil {
+		writeError(w, r, oauth.NewError(oauth.ERROR_INVALID_REQUEST, err.Error()))
+		return
+	}
+
+	req := oauth.TokenRequestDTO{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+	}
+
+	// client_secret_basic takes precedence over client_secret_post
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		req.ClientID = clientID
+		req.ClientSecret = clientSecret
+	}
+
+	res, oauthErr := handler.oauthUsecase.Token(r.Context(), req)
+	if oauthErr != nil {
+		if oauthErr.Code == oauth.ERROR_INVALID_CLIENT {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+
+		writeError(w, r, oauthErr)
+		return
+	}
+
+	render.JSON(w, r, res)
+}
+
+func (handler *oauthHandler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	accessToken, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || accessToken == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		writeError(w, r, oauth.NewError(oauth.ERROR_INVALID_TOKEN, ""))
+		return
+	}
+
+	res, oauthErr := handler.oauthUsecase.UserInfo(r.Context(), accessToken)
+	if oauthErr != nil {
+		if oauthErr.Code == oauth.ERROR_INVALID_TOKEN {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		}
+
+		writeError(w, r, oauthErr)
+		return
+	}
+
+	render.JSON(w, r, res)
+}
+
+// Introspect is the RFC 7662 endpoint, for resource servers holding a client
+// secret. Internal services may use the gRPC AuthService instead.
+func (handler *oauthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	err := r.ParseForm()
+	if err != nil {
+		writeError(w, r, oauth.NewError(oauth.ERROR_INVALID_REQUEST, err.Error()))
+		return
+	}
+
+	req := oauth.IntrospectionRequestDTO{
+		Token:        r.PostForm.Get("token"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+	}
+
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		req.ClientID = clientID
+		req.ClientSecret = clientSecret
+	}
+
+	oauthErr := handler.oauthUsecase.AuthenticateIntrospectionClient(r.Context(), req.ClientID, req.ClientSecret)
+	if oauthErr != nil {
+		if oauthErr.Code == oauth.ERROR_INVALID_CLIENT {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+
+		writeError(w, r, oauthErr)
+		return
+	}
+
+	if req.Token == "" {
+		writeError(w, r, oauth.NewError(oauth.ERROR_INVALID_REQUEST, "token is required"))
+		return
+	}
+
+	res := handler.authUsecase.IntrospectToken(r.Context(), req.Token)
+	if res.StatusCode != http.StatusOK {
+		writeError(w, r, oauth.NewError(oauth.ERROR_SERVER_ERROR, ""))
+		return
+	}
+
+	render.JSON(w, r, res.Data)
+}
+
+// writeError answers with the bare RFC 6749 error body, OAuth clients do not know response.Response
+func writeError(w http.ResponseWriter, r *http.Request, oauthErr *oauth.Error) {
+	render.Status(r, oauthErr.StatusCode)
+	render.JSON(w, r, oauthErr)
+}
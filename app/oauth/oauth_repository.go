@@ -0,0 +1,136 @@
+package oauth
+
+import (
+	"context"
+	"mini-wallet/domain"
+	"mini-wallet/domain/oauth"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type oauthRepository struct {
+	clientCollection            *mongo.Collection
+	authorizationCodeCollection *mongo.Collection
+	consentCollection           *mongo.Collection
+}
+
+func NewOAuthRepository(repositoryParam domain.RepositoryParam) oauth.OAuthRepository {
+	return &oauthRepository{
+		clientCollection:            repositoryParam.Mongo.Collection("oauth_client"),
+		authorizationCodeCollection: repositoryParam.Mongo.Collection("oauth_authorization_code"),
+		consentCollection:           repositoryParam.Mongo.Collection("oauth_consent"),
+	}
+}
+
+func (repository *oauthRepository) UpsertClient(ctx context.Context, client oauth.ClientEntity) (err error) {
+	opts := options.Update().SetUpsert(true)
+	filter := bson.M{"client_id": client.ClientID}
+
+	update := bson.M{
+		"$set": bson.M{
+			"hashed_secret": client.HashedSecret,
+			"name":          client.Name,
+			"redirect_uris": client.RedirectURIs,
+			"scopes":        client.Scopes,
+			"first_party":   client.FirstParty,
+			"updated_at":    client.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{
+			"created_at": client.CreatedAt,
+		},
+	}
+
+	_, err = repository.clientCollection.UpdateOne(ctx, filter, update, opts)
+	return err
+}
+
+func (repository *oauthRepository) GetClientByID(ctx context.Context, clientID string) (res *oauth.ClientEntity, err error) {
+	filter := bson.M{"client_id": clientID}
+
+	result := repository.clientCollection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		return nil, err
+	}
+
+	result.Decode(&res)
+
+	return res, nil
+}
+
+func (repository *oauthRepository) InsertAuthorizationCode(ctx context.Context, code oauth.AuthorizationCodeEntity) (err error) {
+	_, err = repository.authorizationCodeCollection.InsertOne(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *oauthRepository) TakeAuthorizationCode(ctx context.Context, codeHash string, now int64) (res *oauth.AuthorizationCodeEntity, err error) {
+	filter := bson.M{
+		"code_hash": codeHash,
+		"expired_at": bson.M{
+			"$gt": now,
+		},
+	}
+
+	result := repository.authorizationCodeCollection.FindOneAndDelete(ctx, filter)
+	if result.Err() != nil {
+		return nil, err
+	}
+
+	result.Decode(&res)
+
+	return res, nil
+}
+
+func (repository *oauthRepository) GetConsent(ctx context.Context, userID string, clientID string) (res *oauth.ConsentEntity, err error) {
+	filter := bson.M{
+		"user_id":   userID,
+		"client_id": clientID,
+	}
+
+	result := repository.consentCollection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		return nil, err
+	}
+
+	result.Decode(&res)
+
+	return res, nil
+}
+
+func (repository *oauthRepository) UpsertConsent(ctx context.Context, consent oauth.ConsentEntity) (err error) {
+	opts := options.Update().SetUpsert(true)
+	filter := bson.M{
+		"user_id":   consent.UserID,
+		"client_id": consent.ClientID,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"scopes":     consent.Scopes,
+			"updated_at": consent.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{
+			"created_at": consent.CreatedAt,
+		},
+	}
+
+	_, err = repository.consentCollection.UpdateOne(ctx, filter, update, opts)
+	return err
+}
+
+func (repository *oauthRepository) DeleteUserGrants(ctx context.Context, userID string) (err error) {
+	filter := bson.M{"user_id": userID}
+
+	_, err = repository.authorizationCodeCollection.DeleteMany(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	_, err = repository.consentCollection.DeleteMany(ctx, filter)
+	return err
+}
@@ -0,0 +1,535 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"mini-wallet/domain"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/oauth"
+	"mini-wallet/domain/user"
+	"mini-wallet/utils"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AUTHORIZATION_CODE_SIZE = 32
+	MIN_CODE_VERIFIER_SIZE  = 43
+	MAX_CODE_VERIFIER_SIZE  = 128
+)
+
+type oauthUsecase struct {
+	oauthRepository   oauth.OAuthRepository
+	userRepository    user.UserRepository
+	sessionRepository auth.SessionRepository
+	config            *utils.AppConfig
+}
+
+func NewOAuthUsecase(repositories domain.Repositories, config *utils.AppConfig) oauth.OAuthUsecase {
+	return &oauthUsecase{
+		oauthRepository:   repositories.OAuthRepository,
+		userRepository:    repositories.UserRepository,
+		sessionRepository: repositories.SessionRepository,
+		config:            config,
+	}
+}
+
+func (usecase *oauthUsecase) Authorize(ctx context.Context, userID *string, sessionID string, req oauth.AuthorizationRequestDTO) (redirectTo string, oauthErr *oauth.Error) {
+	client, oauthErr := usecase.getClient(ctx, req)
+	if oauthErr != nil {
+		return "", oauthErr
+	}
+
+	// the redirect uri is trusted from here on, errors are sent back to the client
+	oauthErr = validateAuthorizationRequest(*client, req)
+	if oauthErr != nil {
+		return errorRedirect(req, oauthErr), nil
+	}
+
+	if userID == nil || req.Prompt == oauth.PROMPT_LOGIN {
+		if req.Prompt == oauth.PROMPT_NONE {
+			return errorRedirect(req, oauth.NewError(oauth.ERROR_LOGIN_REQUIRED, "")), nil
+		}
+
+		return usecase.loginURL(req), nil
+	}
+
+	if !client.FirstParty {
+		consent, err := usecase.oauthRepository.GetConsent(ctx, *userID, client.ClientID)
+		if err != nil {
+			return "", oauth.NewError(oauth.ERROR_SERVER_ERROR, err.Error())
+		}
+
+		if consent == nil || !consent.Covers(req.Scopes()) || req.Prompt == oauth.PROMPT_CONSENT {
+			if req.Prompt == oauth.PROMPT_NONE {
+				return errorRedirect(req, oauth.NewError(oauth.ERROR_CONSENT_REQUIRED, "")), nil
+			}
+
+			return usecase.consentURL(req), nil
+		}
+	}
+
+	redirectTo, err := usecase.issueCode(ctx, *userID, sessionID, req)
+	if err != nil {
+		return "", oauth.NewError(oauth.ERROR_SERVER_ERROR, err.Error())
+	}
+
+	return redirectTo, nil
+}
+
+func (usecase *oauthUsecase) SubmitConsent(ctx context.Context, userID string, sessionID string, req oauth.ConsentDTO) (res response.Response[oauth.ConsentResultDTO]) {
+	client, oauthErr := usecase.getClient(ctx, req.AuthorizationRequestDTO)
+	if oauthErr != nil {
+		res.BadRequest(oauthErr.Description, nil)
+		return
+	}
+
+	oauthErr = validateAuthorizationRequest(*client, req.AuthorizationRequestDTO)
+	if oauthErr != nil {
+		res.Success(oauth.ConsentResultDTO{
+			RedirectTo: errorRedirect(req.AuthorizationRequestDTO, oauthErr),
+		})
+		return
+	}
+
+	if !req.Approved {
+		res.Success(oauth.ConsentResultDTO{
+			RedirectTo: errorRedirect(req.AuthorizationRequestDTO, oauth.NewError(oauth.ERROR_ACCESS_DENIED, "")),
+		})
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	consent, err := usecase.oauthRepository.GetConsent(ctx, userID, client.ClientID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	scopes := req.Scopes()
+	if consent != nil {
+		for _, scope := range consent.Scopes {
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	err = usecase.oauthRepository.UpsertConsent(ctx, oauth.ConsentEntity{
+		UserID:    userID,
+		ClientID:  client.ClientID,
+		Scopes:    scopes,
+		CreatedAt: now.Unix(),
+		UpdatedAt: now.Unix(),
+	})
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	redirectTo, err := usecase.issueCode(ctx, userID, sessionID, req.AuthorizationRequestDTO)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.Success(oauth.ConsentResultDTO{
+		RedirectTo: redirectTo,
+	})
+	return
+}
+
+func (usecase *oauthUsecase) GetClient(ctx context.Context, clientID string) (res response.Response[oauth.ClientDTO]) {
+	client, err := usecase.oauthRepository.GetClientByID(ctx, clientID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if client == nil {
+		res.NotFound("Aplikasi tidak ditemukan", nil)
+		return
+	}
+
+	res.Success(client.ToClientDTO())
+	return
+}
+
+func (usecase *oauthUsecase) Token(ctx context.Context, req oauth.TokenRequestDTO) (res *oauth.TokenResponse, oauthErr *oauth.Error) {
+	if req.GrantType != oauth.GRANT_TYPE_AUTHORIZATION {
+		return nil, oauth.NewError(oauth.ERROR_UNSUPPORTED_GRANT, "")
+	}
+
+	client, err := usecase.oauthRepository.GetClientByID(ctx, req.ClientID)
+	if err != nil {
+		return nil, oauth.NewError(oauth.ERROR_SERVER_ERROR, err.Error())
+	}
+
+	if client == nil {
+		return nil, oauth.NewError(oauth.ERROR_INVALID_CLIENT, "unknown client")
+	}
+
+	// confidential clients authenticate, public ones must not send a secret
+	if client.HashedSecret != nil && !client.VerifySecret(req.ClientSecret) {
+		return nil, oauth.NewError(oauth.ERROR_INVALID_CLIENT, "client authentication failed")
+	}
+
+	if client.HashedSecret == nil && req.ClientSecret != "" {
+		return nil, oauth.NewError(oauth.ERROR_INVALID_CLIENT, "client authentication failed")
+	}
+
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, oauth.NewError(oauth.ERROR_INVALID_REQUEST, "code and code_verifier are required")
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return nil, oauth.NewError(oauth.ERROR_SERVER_ERROR, err.Error())
+	}
+
+	code, err := usecase.oauthRepository.TakeAuthorizationCode(ctx, utils.HashToken(req.Code), now.Unix())
+	if err != nil {
+		return nil, oauth.NewError(oauth.ERROR_SERVER_ERROR, err.Error())
+	}
+
+	if code == nil || code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI {
+		return nil, oauth.NewError(oauth.ERROR_INVALID_GRANT, "invalid authorization code")
+	}
+
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return nil, oauth.NewError(oauth.ERROR_INVALID_GRANT, "invalid code_verifier")
+	}
+
+	userEntity, oauthErr := usecase.getActiveUser(ctx, code.UserID, code.SessionID, now.Unix())
+	if oauthErr != nil {
+		return nil, oauth.NewError(oauth.ERROR_INVALID_GRANT, "session ended")
+	}
+
+	scope := strings.Join(code.Scopes, " ")
+	expiresAt := now.Add(oauth.OAUTH_ACCESS_TOKEN_LIFETIME)
+	accessToken, err := auth.SignClaims(oauth.AccessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        utils.GenerateUniqueId(),
+			Issuer:    auth.GetIssuer(),
+			Subject:   userEntity.UID,
+			Audience:  jwt.ClaimStrings{client.ClientID},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(*now),
+		},
+		Scope:     scope,
+		ClientID:  client.ClientID,
+		SessionID: code.SessionID,
+		TokenType: oauth.TOKEN_TYPE_OAUTH_ACCESS,
+	})
+	if err != nil {
+		return nil, oauth.NewError(oauth.ERROR_SERVER_ERROR, err.Error())
+	}
+
+	idToken, err := auth.SignClaims(oauth.IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    auth.GetIssuer(),
+			Subject:   userEntity.UID,
+			Audience:  jwt.ClaimStrings{client.ClientID},
+			ExpiresAt: jwt.NewNumericDate(now.Add(oauth.ID_TOKEN_LIFETIME)),
+			IssuedAt:  jwt.NewNumericDate(*now),
+		},
+		UserClaims:      oauth.NewUserClaims(*userEntity, code.Scopes),
+		Nonce:           code.Nonce,
+		AuthTime:        code.AuthTime,
+		AuthorizedParty: client.ClientID,
+	})
+	if err != nil {
+		return nil, oauth.NewError(oauth.ERROR_SERVER_ERROR, err.Error())
+	}
+
+	return &oauth.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(oauth.OAUTH_ACCESS_TOKEN_LIFETIME.Seconds()),
+		IDToken:     idToken,
+		Scope:       scope,
+	}, nil
+}
+
+func (usecase *oauthUsecase) UserInfo(ctx context.Context, accessToken string) (res *oauth.UserInfoResponse, oauthErr *oauth.Error) {
+	claims := &oauth.AccessTokenClaims{}
+	status := auth.ParseClaims(accessToken, claims)
+	if status != 0 || claims.TokenType != oauth.TOKEN_TYPE_OAUTH_ACCESS {
+		return nil, oauth.NewError(oauth.ERROR_INVALID_TOKEN, "")
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return nil, oauth.NewError(oauth.ERROR_SERVER_ERROR, err.Error())
+	}
+
+	userEntity, oauthErr := usecase.getActiveUser(ctx, claims.Subject, claims.SessionID, now.Unix())
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+
+	return &oauth.UserInfoResponse{
+		Subject:    userEntity.UID,
+		UserClaims: oauth.NewUserClaims(*userEntity, strings.Fields(claims.Scope)),
+	}, nil
+}
+
+func (usecase *oauthUsecase) AuthenticateIntrospectionClient(ctx context.Context, clientID string, clientSecret string) (oauthErr *oauth.Error) {
+	if clientID == "" {
+		return oauth.NewError(oauth.ERROR_INVALID_CLIENT, "client authentication is required")
+	}
+
+	client, err := usecase.oauthRepository.GetClientByID(ctx, clientID)
+	if err != nil {
+		return oauth.NewError(oauth.ERROR_SERVER_ERROR, err.Error())
+	}
+
+	// public clients have nothing to authenticate with, anyone could scan tokens as them
+	if client == nil || client.HashedSecret == nil || !client.VerifySecret(clientSecret) {
+		return oauth.NewError(oauth.ERROR_INVALID_CLIENT, "client authentication failed")
+	}
+
+	return nil
+}
+
+func (usecase *oauthUsecase) GetDiscoveryDocument() oauth.DiscoveryDocument {
+	issuer := auth.GetIssuer()
+
+	return oauth.DiscoveryDocument{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserinfoEndpoint:                  issuer + "/oauth/userinfo",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		JwksURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{oauth.RESPONSE_TYPE_CODE},
+		GrantTypesSupported:               []string{oauth.GRANT_TYPE_AUTHORIZATION},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()},
+		ScopesSupported:                   oauth.SupportedScopes,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{oauth.CODE_CHALLENGE_METHOD_S256},
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "azp",
+			"name", "gender", "email", "email_verified", "phone_number", "phone_number_verified",
+		},
+	}
+}
+
+// getClient checks the client and its redirect uri, failures here must not redirect
+func (usecase *oauthUsecase) getClient(ctx context.Context, req oauth.AuthorizationRequestDTO) (*oauth.ClientEntity, *oauth.Error) {
+	if req.ClientID == "" || req.RedirectURI == "" {
+		return nil, oauth.NewError(oauth.ERROR_INVALID_REQUEST, "client_id and redirect_uri are required")
+	}
+
+	client, err := usecase.oauthRepository.GetClientByID(ctx, req.ClientID)
+	if err != nil {
+		return nil, oauth.NewError(oauth.ERROR_SERVER_ERROR, err.Error())
+	}
+
+	if client == nil {
+		return nil, oauth.NewError(oauth.ERROR_INVALID_REQUEST, "unknown client")
+	}
+
+	if !client.HasRedirectURI(req.RedirectURI) {
+		return nil, oauth.NewError(oauth.ERROR_INVALID_REQUEST, "redirect_uri is not registered")
+	}
+
+	return client, nil
+}
+
+// getActiveUser resolves the user behind a code or token, the session they
+// authorized from must still be active
+func (usecase *oauthUsecase) getActiveUser(ctx context.Context, userID string, sessionID string, now int64) (*user.UserEntity, *oauth.Error) {
+	session, err := usecase.sessionRepository.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, oauth.NewError(oauth.ERROR_SERVER_ERROR, err.Error())
+	}
+
+	if session == nil || session.UserID != userID || !session.IsActive(now) {
+		return nil, oauth.NewError(oauth.ERROR_INVALID_TOKEN, "session ended")
+	}
+
+	userEntity, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		return nil, oauth.NewError(oauth.ERROR_SERVER_ERROR, err.Error())
+	}
+
+	if userEntity == nil {
+		return nil, oauth.NewError(oauth.ERROR_INVALID_TOKEN, "user not found")
+	}
+
+	return userEntity, nil
+}
+
+func (usecase *oauthUsecase) issueCode(ctx context.Context, userID string, sessionID string, req oauth.AuthorizationRequestDTO) (string, error) {
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return "", err
+	}
+
+	session, err := usecase.sessionRepository.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	authTime := now.Unix()
+	if session != nil {
+		authTime = session.CreatedAt
+	}
+
+	code, err := utils.GenerateRandomString(AUTHORIZATION_CODE_SIZE)
+	if err != nil {
+		return "", err
+	}
+
+	err = usecase.oauthRepository.InsertAuthorizationCode(ctx, oauth.AuthorizationCodeEntity{
+		CodeHash:            utils.HashToken(code),
+		ClientID:            req.ClientID,
+		UserID:              userID,
+		SessionID:           sessionID,
+		RedirectURI:         req.RedirectURI,
+		Scopes:              req.Scopes(),
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		AuthTime:            authTime,
+		ExpiredAt:           now.Add(oauth.AUTHORIZATION_CODE_LIFETIME).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return buildRedirect(req.RedirectURI, url.Values{
+		"code":  {code},
+		"state": {req.State},
+	}), nil
+}
+
+// loginURL sends the browser to the login page, which comes back to /oauth/authorize afterwards
+func (usecase *oauthUsecase) loginURL(req oauth.AuthorizationRequestDTO) string {
+	loginURL := usecase.config.OAuthLoginURL
+	if loginURL == "" {
+		loginURL = "https://" + usecase.config.AppDomain + "/login"
+	}
+
+	// prompt=login is satisfied by this very login, keeping it would loop
+	if req.Prompt == oauth.PROMPT_LOGIN {
+		req.Prompt = ""
+	}
+
+	return buildRedirect(loginURL, url.Values{
+		"redirect": {auth.GetIssuer() + "/oauth/authorize?" + authorizationQuery(req).Encode()},
+	})
+}
+
+func (usecase *oauthUsecase) consentURL(req oauth.AuthorizationRequestDTO) string {
+	consentURL := usecase.config.OAuthConsentURL
+	if consentURL == "" {
+		consentURL = "https://" + usecase.config.AppDomain + "/oauth/consent"
+	}
+
+	return buildRedirect(consentURL, authorizationQuery(req))
+}
+
+func validateAuthorizationRequest(client oauth.ClientEntity, req oauth.AuthorizationRequestDTO) *oauth.Error {
+	if req.ResponseType != oauth.RESPONSE_TYPE_CODE {
+		return oauth.NewError(oauth.ERROR_UNSUPPORTED_RESPONSE, "only the code response type is supported")
+	}
+
+	scopes := req.Scopes()
+	if !slices.Contains(scopes, oauth.SCOPE_OPENID) {
+		return oauth.NewError(oauth.ERROR_INVALID_SCOPE, "the openid scope is required")
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(oauth.SupportedScopes, scope) {
+			return oauth.NewError(oauth.ERROR_INVALID_SCOPE, "unsupported scope "+scope)
+		}
+	}
+
+	if !client.AllowsScopes(scopes) {
+		return oauth.NewError(oauth.ERROR_INVALID_SCOPE, "scope not allowed for this client")
+	}
+
+	if req.CodeChallengeMethod != oauth.CODE_CHALLENGE_METHOD_S256 || req.CodeChallenge == "" {
+		return oauth.NewError(oauth.ERROR_INVALID_REQUEST, "PKCE with S256 is required")
+	}
+
+	return nil
+}
+
+// verifyCodeChallenge checks BASE64URL(SHA256(code_verifier)) == code_challenge, RFC 7636 section 4.6
+func verifyCodeChallenge(codeVerifier string, codeChallenge string) bool {
+	if len(codeVerifier) < MIN_CODE_VERIFIER_SIZE || len(codeVerifier) > MAX_CODE_VERIFIER_SIZE {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(codeVerifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(codeChallenge)) == 1
+}
+
+func errorRedirect(req oauth.AuthorizationRequestDTO, oauthErr *oauth.Error) string {
+	query := url.Values{
+		"error": {oauthErr.Code},
+		"state": {req.State},
+	}
+
+	if oauthErr.Description != "" {
+		query.Set("error_description", oauthErr.Description)
+	}
+
+	return buildRedirect(req.RedirectURI, query)
+}
+
+func authorizationQuery(req oauth.AuthorizationRequestDTO) url.Values {
+	query := url.Values{
+		"response_type":         {req.ResponseType},
+		"client_id":             {req.ClientID},
+		"redirect_uri":          {req.RedirectURI},
+		"scope":                 {req.Scope},
+		"state":                 {req.State},
+		"nonce":                 {req.Nonce},
+		"code_challenge":        {req.CodeChallenge},
+		"code_challenge_method": {req.CodeChallengeMethod},
+	}
+
+	if req.Prompt != "" {
+		query.Set("prompt", req.Prompt)
+	}
+
+	return query
+}
+
+// buildRedirect adds params to the query of base, empty values are left out
+func buildRedirect(base string, params url.Values) string {
+	redirect, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+
+	query := redirect.Query()
+	for key, values := range params {
+		for _, value := range values {
+			if value != "" {
+				query.Add(key, value)
+			}
+		}
+	}
+
+	redirect.RawQuery = query.Encode()
+	return redirect.String()
+}
@@ -0,0 +1,23 @@
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"mini-wallet/domain"
+	"mini-wallet/domain/privacy"
+	"time"
+)
+
+// StartDeletionWorker deletes the accounts whose grace period is over, every
+// ACCOUNT_DELETION_INTERVAL. It blocks, run it in its own goroutine.
+func StartDeletionWorker(usecases domain.Usecases) {
+	ticker := time.NewTicker(privacy.ACCOUNT_DELETION_INTERVAL)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := usecases.PrivacyUsecase.ProcessDueDeletions(context.Background())
+		if err != nil {
+			fmt.Println("error processing account deletions:", err.Error())
+		}
+	}
+}
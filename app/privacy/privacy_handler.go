@@ -0,0 +1,173 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mini-wallet/domain"
+	_auth "mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/privacy"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type privacyHandler struct {
+	privacyUsecase privacy.PrivacyUsecase
+}
+
+func SetPrivacyHandler(router *chi.Mux, usecases domain.Usecases, middleware _auth.AuthMiddleware) {
+	privacyHandler := privacyHandler{
+		privacyUsecase: usecases.PrivacyUsecase,
+	}
+
+	router.Route("/privacy", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.With(middleware.DenyImpersonation).Get("/export", privacyHandler.ExportUserData)
+		r.Get("/deletion", privacyHandler.GetAccountDeletion)
+		r.With(middleware.DenyImpersonation).Post("/deletion", privacyHandler.RequestAccountDeletion)
+		r.With(middleware.DenyImpersonation).Delete("/deletion", privacyHandler.CancelAccountDeletion)
+	})
+
+	router.Route("/admin/users/{userId}/deletion", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Use(middleware.RequirePermission(_auth.PERMISSION_USER_DELETE))
+		r.Post("/", privacyHandler.DeleteAccount)
+	})
+}
+
+// ExportUserData answers the data as a json attachment, or with ?format=zip as a
+// zip archive holding data.json
+func (handler *privacyHandler) ExportUserData(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = privacy.EXPORT_FORMAT_JSON
+	}
+
+	if format != privacy.EXPORT_FORMAT_JSON && format != privacy.EXPORT_FORMAT_ZIP {
+		resp.BadRequest(privacy.ErrUnknownExportFormat.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userId := r.Context().Value(_auth.UserIDContext{}).(*string)
+	res := handler.privacyUsecase.ExportUserData(r.Context(), *userId)
+	if res.Data == nil {
+		res.Writer = w
+		res.WriteResponse()
+		return
+	}
+
+	data, err := json.MarshalIndent(res.Data, "", "  ")
+	if err != nil {
+		resp.InternalServerError(err.Error())
+		resp.WriteResponse()
+		return
+	}
+
+	fileName := fmt.Sprintf("sebia-data-%s", *userId)
+	contentType := "application/json"
+	if format == privacy.EXPORT_FORMAT_ZIP {
+		data, err = zipExport(data)
+		if err != nil {
+			resp.InternalServerError(err.Error())
+			resp.WriteResponse()
+			return
+		}
+
+		contentType = "application/zip"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, fileName, format))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func zipExport(data []byte) ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	archive := zip.NewWriter(buffer)
+
+	file, err := archive.Create("data.json")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = file.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (handler *privacyHandler) GetAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.privacyUsecase.GetAccountDeletion(r.Context(), *userId)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *privacyHandler) RequestAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	var req privacy.AccountDeletionRequestDTO
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userId := r.Context().Value(_auth.UserIDContext{}).(*string)
+	res := handler.privacyUsecase.RequestAccountDeletion(r.Context(), *userId, req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *privacyHandler) CancelAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.privacyUsecase.CancelAccountDeletion(r.Context(), *userId)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *privacyHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	var req privacy.AdminAccountDeletionDTO
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	adminId := r.Context().Value(_auth.UserIDContext{}).(*string)
+	res := handler.privacyUsecase.DeleteAccount(r.Context(), *adminId, chi.URLParam(r, "userId"), req)
+	res.Writer = w
+	res.WriteResponse()
+}
@@ -0,0 +1,139 @@
+package privacy
+
+import (
+	"context"
+	"mini-wallet/domain"
+	"mini-wallet/domain/privacy"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type privacyRepository struct {
+	accountDeletionCollection *mongo.Collection
+}
+
+func NewPrivacyRepository(repositoryParam domain.RepositoryParam) privacy.PrivacyRepository {
+	return &privacyRepository{
+		accountDeletionCollection: repositoryParam.Mongo.Collection("account_deletion"),
+	}
+}
+
+func (repository *privacyRepository) InsertAccountDeletion(ctx context.Context, entity privacy.AccountDeletionEntity) (err error) {
+	_, err = repository.accountDeletionCollection.InsertOne(ctx, entity)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *privacyRepository) GetPendingAccountDeletion(ctx context.Context, userID string) (res *privacy.AccountDeletionEntity, err error) {
+	filter := bson.M{
+		"user_id": userID,
+		"status":  privacy.DELETION_STATUS_PENDING,
+	}
+
+	result := repository.accountDeletionCollection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		return nil, err
+	}
+
+	result.Decode(&res)
+
+	return res, nil
+}
+
+// unclaimedFilter matches deletions no replica holds, deletions stored before
+// claiming existed have no locked_until
+func unclaimedFilter(now int64) bson.A {
+	return bson.A{
+		bson.M{"locked_until": bson.M{"$exists": false}},
+		bson.M{"locked_until": bson.M{"$lte": now}},
+	}
+}
+
+func (repository *privacyRepository) ClaimDueAccountDeletion(ctx context.Context, now int64, lockedUntil int64) (res *privacy.AccountDeletionEntity, err error) {
+	filter := bson.M{
+		"status": privacy.DELETION_STATUS_PENDING,
+		"scheduled_at": bson.M{
+			"$lte": now,
+		},
+		"$or": unclaimedFilter(now),
+	}
+
+	update := bson.M{"$set": bson.M{
+		"locked_until": lockedUntil,
+	}}
+
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "scheduled_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	result := repository.accountDeletionCollection.FindOneAndUpdate(ctx, filter, update, opts)
+	if result.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	err = result.Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (repository *privacyRepository) ClaimAccountDeletion(ctx context.Context, id string, now int64, lockedUntil int64) (claimed bool, err error) {
+	filter := bson.M{
+		"id":     id,
+		"status": privacy.DELETION_STATUS_PENDING,
+		"$or":    unclaimedFilter(now),
+	}
+
+	update := bson.M{"$set": bson.M{
+		"locked_until": lockedUntil,
+	}}
+
+	result, err := repository.accountDeletionCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (repository *privacyRepository) CancelAccountDeletion(ctx context.Context, userID string, now int64) (cancelled bool, err error) {
+	filter := bson.M{
+		"user_id": userID,
+		"status":  privacy.DELETION_STATUS_PENDING,
+	}
+
+	update := bson.M{"$set": bson.M{
+		"status":       privacy.DELETION_STATUS_CANCELLED,
+		"cancelled_at": now,
+	}}
+
+	result, err := repository.accountDeletionCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (repository *privacyRepository) CompleteAccountDeletion(ctx context.Context, id string, now int64) (err error) {
+	filter := bson.M{"id": id}
+
+	update := bson.M{"$set": bson.M{
+		"status":       privacy.DELETION_STATUS_COMPLETED,
+		"completed_at": now,
+	}}
+
+	_, err = repository.accountDeletionCollection.UpdateOne(ctx, filter, update)
+	return err
+}
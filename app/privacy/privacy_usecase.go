@@ -0,0 +1,461 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	_audit "mini-wallet/app/audit"
+	"mini-wallet/domain"
+	"mini-wallet/domain/affiliate"
+	"mini-wallet/domain/audit"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/business"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/inquiry"
+	"mini-wallet/domain/oauth"
+	"mini-wallet/domain/privacy"
+	"mini-wallet/domain/review"
+	"mini-wallet/domain/user"
+	"mini-wallet/utils"
+	"net/http"
+)
+
+type privacyUsecase struct {
+	privacyRepository   privacy.PrivacyRepository
+	userRepository      user.UserRepository
+	sessionRepository   auth.SessionRepository
+	passkeyRepository   auth.PasskeyRepository
+	deviceRepository    auth.KnownDeviceRepository
+	inquiryRepository   inquiry.InquiryRepository
+	reviewRepository    review.ReviewRepository
+	businessRepository  business.BusinessRepository
+	affiliateRepository affiliate.AffiliateRepository
+	oauthRepository     oauth.OAuthRepository
+	auditor             *_audit.Auditor
+}
+
+func NewPrivacyUsecase(repositories domain.Repositories) privacy.PrivacyUsecase {
+	return &privacyUsecase{
+		privacyRepository:   repositories.PrivacyRepository,
+		userRepository:      repositories.UserRepository,
+		sessionRepository:   repositories.SessionRepository,
+		passkeyRepository:   repositories.PasskeyRepository,
+		deviceRepository:    repositories.KnownDeviceRepository,
+		inquiryRepository:   repositories.InquiryRepository,
+		reviewRepository:    repositories.ReviewRepository,
+		businessRepository:  repositories.BusinessRepository,
+		affiliateRepository: repositories.AffiliateRepository,
+		oauthRepository:     repositories.OAuthRepository,
+		auditor:             _audit.NewAuditor(repositories),
+	}
+}
+
+func (usecase *privacyUsecase) ExportUserData(ctx context.Context, userID string) (res response.Response[privacy.DataExportDTO]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_DATA_EXPORT, "")
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	userEntity, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if userEntity == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	data := privacy.DataExportDTO{
+		ExportedAt: now.Unix(),
+		Profile:    privacy.NewProfileExportDTO(*userEntity),
+		Inquiries:  []privacy.InquiryExportDTO{},
+		Reviews:    []privacy.ReviewExportDTO{},
+		Identities: []privacy.IdentityExportDTO{},
+		Roles:      auth.GetUserRoles(*userEntity),
+	}
+
+	for _, identity := range userEntity.Identities {
+		data.Identities = append(data.Identities, privacy.NewIdentityExportDTO(identity))
+	}
+
+	inquiries, err := usecase.inquiryRepository.GetInquiriesOfUser(ctx, userEntity.UID, userEntity.Email)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	for _, inquiryEntity := range inquiries {
+		data.Inquiries = append(data.Inquiries, privacy.NewInquiryExportDTO(inquiryEntity))
+	}
+
+	reviews, err := usecase.reviewRepository.GetReviewsByUserID(ctx, userEntity.UID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	for _, reviewEntity := range reviews {
+		data.Reviews = append(data.Reviews, privacy.NewReviewExportDTO(reviewEntity))
+	}
+
+	businessEntity, err := usecase.businessRepository.GetBusinessByUserId(ctx, userEntity.UID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if businessEntity != nil {
+		data.Business = privacy.NewBusinessExportDTO(*businessEntity)
+	}
+
+	affiliateEntity, err := usecase.affiliateRepository.GetAffiliateByUserId(ctx, userEntity.UID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if affiliateEntity != nil {
+		data.Affiliate = privacy.NewAffiliateExportDTO(*affiliateEntity)
+	}
+
+	deletion, err := usecase.privacyRepository.GetPendingAccountDeletion(ctx, userEntity.UID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if deletion != nil {
+		deletionDTO := deletion.ToAccountDeletionDTO()
+		data.Deletion = &deletionDTO
+	}
+
+	res.Success(data)
+	return
+}
+
+func (usecase *privacyUsecase) GetAccountDeletion(ctx context.Context, userID string) (res response.Response[privacy.AccountDeletionDTO]) {
+	deletion, err := usecase.privacyRepository.GetPendingAccountDeletion(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if deletion == nil {
+		res.NotFound("Tidak ada permintaan penghapusan akun", nil)
+		return
+	}
+
+	res.Success(deletion.ToAccountDeletionDTO())
+	return
+}
+
+func (usecase *privacyUsecase) RequestAccountDeletion(ctx context.Context, userID string, req privacy.AccountDeletionRequestDTO) (res response.Response[privacy.AccountDeletionDTO]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_ACCOUNT_DELETION_REQUEST, "")
+	event.Reason = req.Reason
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	userEntity, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if userEntity == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	if userEntity.HashedPassword != nil {
+		_, err = userEntity.VerifyPassword(req.Password)
+		if errors.Is(err, utils.ErrPasswordMismatch) {
+			res.BadRequest("Kata sandi salah", nil)
+			return
+		}
+
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+	}
+
+	ownsBusiness, err := usecase.ownsBusiness(ctx, userEntity.UID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if ownsBusiness {
+		res.BadRequest("Akun pemilik bisnis tidak dapat dihapus sendiri, silakan hubungi admin", nil)
+		return
+	}
+
+	deletion, err := usecase.privacyRepository.GetPendingAccountDeletion(ctx, userEntity.UID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	// asking twice keeps the first schedule
+	if deletion != nil {
+		res.Success(deletion.ToAccountDeletionDTO())
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	deletion = &privacy.AccountDeletionEntity{
+		ID:          utils.GenerateUniqueId(),
+		UserID:      userEntity.UID,
+		RequestedBy: userEntity.UID,
+		Reason:      req.Reason,
+		Status:      privacy.DELETION_STATUS_PENDING,
+		RequestedAt: now.Unix(),
+		ScheduledAt: now.Add(privacy.ACCOUNT_DELETION_GRACE_PERIOD).Unix(),
+	}
+
+	err = usecase.privacyRepository.InsertAccountDeletion(ctx, *deletion)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.Success(deletion.ToAccountDeletionDTO())
+	return
+}
+
+func (usecase *privacyUsecase) CancelAccountDeletion(ctx context.Context, userID string) (res response.Response[string]) {
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_ACCOUNT_DELETION_CANCEL, "")
+	defer _audit.RecordOutcome(usecase.auditor, ctx, event, &res)
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	cancelled, err := usecase.privacyRepository.CancelAccountDeletion(ctx, userID, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !cancelled {
+		res.NotFound("Tidak ada permintaan penghapusan akun", nil)
+		return
+	}
+
+	res.SuccessWithMessage("Penghapusan akun dibatalkan")
+	return
+}
+
+func (usecase *privacyUsecase) DeleteAccount(ctx context.Context, adminID string, userID string, req privacy.AdminAccountDeletionDTO) (res response.Response[string]) {
+	userEntity, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if userEntity == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	ownsBusiness, err := usecase.ownsBusiness(ctx, userEntity.UID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if ownsBusiness {
+		res.BadRequest("Akun pemilik bisnis tidak dapat dihapus, alihkan bisnisnya terlebih dahulu", nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	deletion, err := usecase.privacyRepository.GetPendingAccountDeletion(ctx, userEntity.UID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	lockedUntil := now.Add(privacy.ACCOUNT_DELETION_LOCK).Unix()
+
+	// the deletion is claimed like the worker does, so no replica deletes the
+	// account at the same time
+	if deletion == nil {
+		deletion = &privacy.AccountDeletionEntity{
+			ID:          utils.GenerateUniqueId(),
+			UserID:      userEntity.UID,
+			RequestedBy: adminID,
+			Reason:      req.Reason,
+			Status:      privacy.DELETION_STATUS_PENDING,
+			RequestedAt: now.Unix(),
+			ScheduledAt: now.Unix(),
+			LockedUntil: lockedUntil,
+		}
+
+		err = usecase.privacyRepository.InsertAccountDeletion(ctx, *deletion)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+	} else {
+		claimed, err := usecase.privacyRepository.ClaimAccountDeletion(ctx, deletion.ID, now.Unix(), lockedUntil)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		if !claimed {
+			res.BadRequest("Penghapusan akun sedang diproses, coba lagi nanti", nil)
+			return
+		}
+	}
+
+	err = usecase.deleteAccount(ctx, *deletion)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithMessage("Akun dihapus")
+	return
+}
+
+func (usecase *privacyUsecase) ProcessDueDeletions(ctx context.Context) (err error) {
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return err
+	}
+
+	lockedUntil := now.Add(privacy.ACCOUNT_DELETION_LOCK).Unix()
+
+	// every replica runs the worker, a deletion is only processed by the replica
+	// that claimed it
+	for i := 0; i < privacy.ACCOUNT_DELETION_BATCH_SIZE; i++ {
+		deletion, err := usecase.privacyRepository.ClaimDueAccountDeletion(ctx, now.Unix(), lockedUntil)
+		if err != nil {
+			return err
+		}
+
+		if deletion == nil {
+			return nil
+		}
+
+		err = usecase.deleteAccount(ctx, *deletion)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ownsBusiness guards both deletion paths, bookings and services depend on the
+// business so it has to be taken over first
+func (usecase *privacyUsecase) ownsBusiness(ctx context.Context, userID string) (bool, error) {
+	businessEntity, err := usecase.businessRepository.GetBusinessByUserId(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return businessEntity != nil, nil
+}
+
+// deleteAccount ends every session, anonymizes what other parties still need
+// (inquiries of the hosts, reviews of the services), removes the rest and finally
+// the user document. Each step can be repeated, a failed deletion stays pending
+// and is retried by ProcessDueDeletions once its claim expires.
+func (usecase *privacyUsecase) deleteAccount(ctx context.Context, deletion privacy.AccountDeletionEntity) error {
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return err
+	}
+
+	userEntity, err := usecase.userRepository.GetUserByUserID(ctx, deletion.UserID)
+	if err != nil {
+		return err
+	}
+
+	if userEntity != nil {
+		err = usecase.sessionRepository.RevokeUserSessions(ctx, userEntity.UID, "", auth.SESSION_REVOKED_ACCOUNT_DELETED, now.Unix())
+		if err != nil {
+			return err
+		}
+
+		err = usecase.inquiryRepository.AnonymizeInquiries(ctx, userEntity.UID, userEntity.Email, privacy.ANONYMIZED_NAME)
+		if err != nil {
+			return err
+		}
+
+		err = usecase.reviewRepository.AnonymizeReviews(ctx, userEntity.UID)
+		if err != nil {
+			return err
+		}
+
+		err = usecase.affiliateRepository.DeleteAffiliate(ctx, userEntity.UID)
+		if err != nil {
+			return err
+		}
+
+		err = usecase.passkeyRepository.DeletePasskeysByUserID(ctx, userEntity.UID)
+		if err != nil {
+			return err
+		}
+
+		err = usecase.deviceRepository.DeleteKnownDevicesByUserID(ctx, userEntity.UID)
+		if err != nil {
+			return err
+		}
+
+		err = usecase.oauthRepository.DeleteUserGrants(ctx, userEntity.UID)
+		if err != nil {
+			return err
+		}
+
+		err = usecase.userRepository.DeleteUserPasswordResetEntity(ctx, userEntity.Email)
+		if err != nil {
+			return err
+		}
+
+		err = usecase.userRepository.DeleteTemporaryUser(ctx, userEntity.Email)
+		if err != nil {
+			return err
+		}
+
+		err = usecase.userRepository.DeleteUser(ctx, userEntity.UID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = usecase.privacyRepository.CompleteAccountDeletion(ctx, deletion.ID, now.Unix())
+	if err != nil {
+		return err
+	}
+
+	// the actor is the admin, nobody for the scheduled deletions
+	event := _audit.NewAuditEvent(ctx, audit.EVENT_ACCOUNT_DELETE, "")
+	event.UserID = deletion.UserID
+	event.Identifier = deletion.ID
+	event.Reason = deletion.Reason
+	event.Outcome = audit.OUTCOME_SUCCESS
+	event.StatusCode = http.StatusOK
+	usecase.auditor.Record(ctx, event)
+
+	return nil
+}
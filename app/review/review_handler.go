@@ -22,8 +22,9 @@ func SetReviewHandler(router *chi.Mux, usecases domain.Usecases, middleware _aut
 	}
 
 	router.Route("/reviews", func(r chi.Router) {
+		r.Use(middleware.AllowApiKey)
 		r.Use(middleware.AuthMiddleware)
-		r.Post("/", reviewHandler.CreateReview)
+		r.With(middleware.RequirePermission(_auth.PERMISSION_REVIEW_CREATE)).Post("/", reviewHandler.CreateReview)
 	})
 
 	router.Route("/public/reviews", func(r chi.Router) {
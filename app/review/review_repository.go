@@ -51,3 +51,31 @@ func (repo *reviewRepository) GetServiceTopReview(ctx context.Context, serviceId
 
 	return res, nil
 }
+
+func (repo *reviewRepository) GetReviewsByUserID(ctx context.Context, userID string) (res []review.ReviewEntity, err error) {
+	filter := bson.M{"user_id": userID}
+
+	result, err := repo.reviewsCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	res = []review.ReviewEntity{}
+	err = result.All(ctx, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (repo *reviewRepository) AnonymizeReviews(ctx context.Context, userID string) (err error) {
+	filter := bson.M{"user_id": userID}
+
+	update := bson.M{"$set": bson.M{
+		"user_id": "",
+	}}
+
+	_, err = repo.reviewsCollection.UpdateMany(ctx, filter, update)
+	return err
+}
@@ -71,7 +71,7 @@ func (uc *reviewUsecase) CreateReview(ctx context.Context, req review.ReviewDTO)
 	reviewEntity.ServiceID = inquiryEntity.ServiceID
 
 	if req.UserID != *inquiryEntity.UserID {
-		res.Unauthorized("pesanan milik pengguna lain")
+		res.Forbidden("pesanan milik pengguna lain", nil)
 		return
 	}
 
@@ -0,0 +1,108 @@
+package serviceaccount
+
+import (
+	"mini-wallet/domain"
+	_auth "mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/serviceaccount"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type serviceAccountHandler struct {
+	serviceAccountUsecase serviceaccount.ServiceAccountUsecase
+}
+
+// SetServiceAccountHandler registers the management of service accounts and
+// their keys. API keys themselves never carry PERMISSION_API_KEY_MANAGE, so a
+// key can not mint other keys.
+func SetServiceAccountHandler(router *chi.Mux, usecases domain.Usecases, middleware _auth.AuthMiddleware) {
+	serviceAccountHandler := serviceAccountHandler{
+		serviceAccountUsecase: usecases.ServiceAccountUsecase,
+	}
+
+	router.Route("/service-accounts", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Use(middleware.RequirePermission(_auth.PERMISSION_API_KEY_MANAGE))
+		r.With(middleware.DenyImpersonation).Post("/", serviceAccountHandler.CreateServiceAccount)
+		r.Get("/", serviceAccountHandler.GetServiceAccounts)
+		r.With(middleware.DenyImpersonation).Post("/{serviceAccountId}/keys", serviceAccountHandler.CreateApiKey)
+		r.Get("/{serviceAccountId}/keys", serviceAccountHandler.GetApiKeys)
+		r.With(middleware.DenyImpersonation).Delete("/{serviceAccountId}/keys/{keyId}", serviceAccountHandler.RevokeApiKey)
+	})
+}
+
+func (handler *serviceAccountHandler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := serviceaccount.ServiceAccountCreationDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+	res := handler.serviceAccountUsecase.CreateServiceAccount(r.Context(), *userID, req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+// GetServiceAccounts lists the accounts of ?business_id=, the internal ones without it
+func (handler *serviceAccountHandler) GetServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.serviceAccountUsecase.GetServiceAccounts(r.Context(), *userID, r.URL.Query().Get("business_id"))
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *serviceAccountHandler) CreateApiKey(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := serviceaccount.ApiKeyCreationDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+	res := handler.serviceAccountUsecase.CreateApiKey(r.Context(), *userID, chi.URLParam(r, "serviceAccountId"), req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *serviceAccountHandler) GetApiKeys(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.serviceAccountUsecase.GetApiKeys(r.Context(), *userID, chi.URLParam(r, "serviceAccountId"))
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *serviceAccountHandler) RevokeApiKey(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.serviceAccountUsecase.RevokeApiKey(r.Context(), *userID, chi.URLParam(r, "serviceAccountId"), chi.URLParam(r, "keyId"))
+	res.Writer = w
+	res.WriteResponse()
+}
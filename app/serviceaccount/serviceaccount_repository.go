@@ -0,0 +1,144 @@
+package serviceaccount
+
+import (
+	"context"
+	"mini-wallet/domain"
+	"mini-wallet/domain/serviceaccount"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type serviceAccountRepository struct {
+	serviceAccountCollection *mongo.Collection
+	apiKeyCollection         *mongo.Collection
+}
+
+func NewServiceAccountRepository(repositoryParam domain.RepositoryParam) serviceaccount.ServiceAccountRepository {
+	return &serviceAccountRepository{
+		serviceAccountCollection: repositoryParam.Mongo.Collection("service_account"),
+		apiKeyCollection:         repositoryParam.Mongo.Collection("api_key"),
+	}
+}
+
+func (repository *serviceAccountRepository) InsertServiceAccount(ctx context.Context, entity serviceaccount.ServiceAccountEntity) (err error) {
+	_, err = repository.serviceAccountCollection.InsertOne(ctx, entity)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *serviceAccountRepository) GetServiceAccountByID(ctx context.Context, id string) (res *serviceaccount.ServiceAccountEntity, err error) {
+	filter := bson.M{"id": id}
+
+	result := repository.serviceAccountCollection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		return nil, err
+	}
+
+	result.Decode(&res)
+
+	return res, nil
+}
+
+func (repository *serviceAccountRepository) GetServiceAccounts(ctx context.Context, businessID *string) (res []serviceaccount.ServiceAccountEntity, err error) {
+	filter := bson.M{"business_id": businessID}
+	opts := options.Find().SetSort(bson.D{
+		{
+			Key:   "created_at",
+			Value: 1,
+		},
+	})
+
+	result, err := repository.serviceAccountCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res = []serviceaccount.ServiceAccountEntity{}
+	err = result.All(ctx, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (repository *serviceAccountRepository) InsertApiKey(ctx context.Context, entity serviceaccount.ApiKeyEntity) (err error) {
+	_, err = repository.apiKeyCollection.InsertOne(ctx, entity)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *serviceAccountRepository) GetApiKeyByID(ctx context.Context, id string) (res *serviceaccount.ApiKeyEntity, err error) {
+	filter := bson.M{"id": id}
+
+	result := repository.apiKeyCollection.FindOne(ctx, filter)
+	if result.Err() != nil {
+		return nil, err
+	}
+
+	result.Decode(&res)
+
+	return res, nil
+}
+
+func (repository *serviceAccountRepository) GetApiKeys(ctx context.Context, serviceAccountID string) (res []serviceaccount.ApiKeyEntity, err error) {
+	filter := bson.M{"service_account_id": serviceAccountID}
+	opts := options.Find().SetSort(bson.D{
+		{
+			Key:   "created_at",
+			Value: -1,
+		},
+	})
+
+	result, err := repository.apiKeyCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res = []serviceaccount.ApiKeyEntity{}
+	err = result.All(ctx, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (repository *serviceAccountRepository) RevokeApiKey(ctx context.Context, serviceAccountID string, id string, now int64) (revoked bool, err error) {
+	filter := bson.M{
+		"id":                 id,
+		"service_account_id": serviceAccountID,
+		"revoked_at":         nil,
+	}
+
+	update := bson.M{"$set": bson.M{
+		"revoked_at": now,
+	}}
+
+	result, err := repository.apiKeyCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (repository *serviceAccountRepository) TouchApiKey(ctx context.Context, id string, ipAddress string, now int64) (err error) {
+	filter := bson.M{"id": id}
+
+	update := bson.M{"$set": bson.M{
+		"last_used_at": now,
+		"last_used_ip": ipAddress,
+	}}
+
+	_, err = repository.apiKeyCollection.UpdateOne(ctx, filter, update)
+	return err
+}
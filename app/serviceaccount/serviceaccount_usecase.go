@@ -0,0 +1,257 @@
+package serviceaccount
+
+import (
+	"context"
+	"mini-wallet/domain"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/business"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/serviceaccount"
+	"mini-wallet/utils"
+	"time"
+)
+
+type serviceAccountUsecase struct {
+	serviceAccountRepository serviceaccount.ServiceAccountRepository
+	businessRepository       business.BusinessRepository
+}
+
+func NewServiceAccountUsecase(repositories domain.Repositories) serviceaccount.ServiceAccountUsecase {
+	return &serviceAccountUsecase{
+		serviceAccountRepository: repositories.ServiceAccountRepository,
+		businessRepository:       repositories.BusinessRepository,
+	}
+}
+
+func (usecase *serviceAccountUsecase) CreateServiceAccount(ctx context.Context, userID string, req serviceaccount.ServiceAccountCreationDTO) (res response.Response[serviceaccount.ServiceAccountDTO]) {
+	canManage, err := usecase.canManage(ctx, userID, req.BusinessID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !canManage {
+		res.Forbidden(auth.FORBIDDEN_MESSAGE, nil)
+		return
+	}
+
+	if req.BusinessID != nil {
+		businessEntity, err := usecase.businessRepository.GetBusinessById(ctx, *req.BusinessID)
+		if err != nil {
+			res.InternalServerError(err.Error())
+			return
+		}
+
+		if businessEntity == nil {
+			res.NotFound("Bisnis tidak ditemukan", nil)
+			return
+		}
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	serviceAccount := serviceaccount.ServiceAccountEntity{
+		ID:         utils.GenerateUniqueId(),
+		Name:       req.Name,
+		BusinessID: req.BusinessID,
+		Roles:      req.Roles,
+		CreatedBy:  userID,
+		CreatedAt:  now.Unix(),
+	}
+
+	err = usecase.serviceAccountRepository.InsertServiceAccount(ctx, serviceAccount)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.Success(serviceAccount.ToServiceAccountDTO())
+	return
+}
+
+func (usecase *serviceAccountUsecase) GetServiceAccounts(ctx context.Context, userID string, businessID string) (res response.Response[[]serviceaccount.ServiceAccountDTO]) {
+	var accountsOf *string
+	if businessID != "" {
+		accountsOf = &businessID
+	}
+
+	canManage, err := usecase.canManage(ctx, userID, accountsOf)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !canManage {
+		res.Forbidden(auth.FORBIDDEN_MESSAGE, nil)
+		return
+	}
+
+	serviceAccounts, err := usecase.serviceAccountRepository.GetServiceAccounts(ctx, accountsOf)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	result := []serviceaccount.ServiceAccountDTO{}
+	for _, serviceAccount := range serviceAccounts {
+		result = append(result, serviceAccount.ToServiceAccountDTO())
+	}
+
+	res.Success(result)
+	return
+}
+
+func (usecase *serviceAccountUsecase) CreateApiKey(ctx context.Context, userID string, serviceAccountID string, req serviceaccount.ApiKeyCreationDTO) (res response.Response[serviceaccount.CreatedApiKeyDTO]) {
+	serviceAccount, res := getManagedServiceAccount[serviceaccount.CreatedApiKeyDTO](ctx, usecase, userID, serviceAccountID)
+	if res.StatusCode != 0 {
+		return
+	}
+
+	if !serviceAccount.AllowsScopes(req.Scopes) {
+		res.BadRequest(serviceaccount.ErrUnknownScope.Error(), nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	secret, err := utils.GenerateRandomString(serviceaccount.API_KEY_SECRET_LENGTH)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	apiKey := serviceaccount.ApiKeyEntity{
+		ID:               utils.GenerateUniqueId(),
+		ServiceAccountID: serviceAccount.ID,
+		Name:             req.Name,
+		Scopes:           req.Scopes,
+		CreatedBy:        userID,
+		CreatedAt:        now.Unix(),
+	}
+
+	key := serviceaccount.API_KEY_PREFIX + apiKey.ID + "_" + secret
+	apiKey.KeyHash = utils.HashToken(key)
+	apiKey.LastCharacters = key[len(key)-serviceaccount.API_KEY_VISIBLE_CHARACTERS:]
+
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour).Unix()
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	err = usecase.serviceAccountRepository.InsertApiKey(ctx, apiKey)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.Success(serviceaccount.CreatedApiKeyDTO{
+		ApiKeyDTO: apiKey.ToApiKeyDTO(),
+		Key:       key,
+	})
+	return
+}
+
+func (usecase *serviceAccountUsecase) GetApiKeys(ctx context.Context, userID string, serviceAccountID string) (res response.Response[[]serviceaccount.ApiKeyDTO]) {
+	serviceAccount, res := getManagedServiceAccount[[]serviceaccount.ApiKeyDTO](ctx, usecase, userID, serviceAccountID)
+	if res.StatusCode != 0 {
+		return
+	}
+
+	apiKeys, err := usecase.serviceAccountRepository.GetApiKeys(ctx, serviceAccount.ID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	result := []serviceaccount.ApiKeyDTO{}
+	for _, apiKey := range apiKeys {
+		result = append(result, apiKey.ToApiKeyDTO())
+	}
+
+	res.Success(result)
+	return
+}
+
+func (usecase *serviceAccountUsecase) RevokeApiKey(ctx context.Context, userID string, serviceAccountID string, apiKeyID string) (res response.Response[string]) {
+	serviceAccount, res := getManagedServiceAccount[string](ctx, usecase, userID, serviceAccountID)
+	if res.StatusCode != 0 {
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	revoked, err := usecase.serviceAccountRepository.RevokeApiKey(ctx, serviceAccount.ID, apiKeyID, now.Unix())
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !revoked {
+		res.NotFound("Kunci API tidak ditemukan", nil)
+		return
+	}
+
+	res.SuccessWithMessage("Kunci API dicabut")
+	return
+}
+
+// canManage tells whether the user manages the accounts of the business. Holders
+// of PERMISSION_SERVICE_ACCOUNT_MANAGE manage every account, the internal ones
+// (businessID nil) only by them.
+func (usecase *serviceAccountUsecase) canManage(ctx context.Context, userID string, businessID *string) (bool, error) {
+	if auth.IsPermitted(ctx, auth.PERMISSION_SERVICE_ACCOUNT_MANAGE) {
+		return true, nil
+	}
+
+	if businessID == nil {
+		return false, nil
+	}
+
+	businessEntity, err := usecase.businessRepository.GetBusinessById(ctx, *businessID)
+	if err != nil {
+		return false, err
+	}
+
+	return businessEntity != nil && businessEntity.UserID == userID, nil
+}
+
+// getManagedServiceAccount loads the account, a non zero StatusCode means the
+// user may not manage it and res is the answer
+func getManagedServiceAccount[T any](ctx context.Context, usecase *serviceAccountUsecase, userID string, serviceAccountID string) (serviceAccount *serviceaccount.ServiceAccountEntity, res response.Response[T]) {
+	serviceAccount, err := usecase.serviceAccountRepository.GetServiceAccountByID(ctx, serviceAccountID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if serviceAccount == nil {
+		res.NotFound("Akun layanan tidak ditemukan", nil)
+		return
+	}
+
+	canManage, err := usecase.canManage(ctx, userID, serviceAccount.BusinessID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !canManage {
+		// not telling whether the account exists
+		res.NotFound("Akun layanan tidak ditemukan", nil)
+		return
+	}
+
+	return serviceAccount, res
+}
@@ -25,12 +25,13 @@ func SetServicesHandler(router *chi.Mux, usecases domain.Usecases, middleware _a
 	}
 
 	router.Route("/services", func(r chi.Router) {
+		r.Use(middleware.AllowApiKey)
 		r.Use(middleware.AuthMiddleware)
-		r.Post("/", servicesHandler.CreateService)
-		r.Put("/", servicesHandler.UpdateService)
+		r.With(middleware.RequirePermission(_auth.PERMISSION_SERVICE_WRITE)).Post("/", servicesHandler.CreateService)
+		r.With(middleware.RequirePermission(_auth.PERMISSION_SERVICE_WRITE)).Put("/", servicesHandler.UpdateService)
 
-		r.Get("/", servicesHandler.GetServices)
-		r.Get("/{slug}", servicesHandler.GetServiceBySlug)
+		r.With(middleware.RequirePermission(_auth.PERMISSION_SERVICE_READ)).Get("/", servicesHandler.GetServices)
+		r.With(middleware.RequirePermission(_auth.PERMISSION_SERVICE_READ)).Get("/{slug}", servicesHandler.GetServiceBySlug)
 	})
 	router.Route("/public/services", func(r chi.Router) {
 		r.Get("/", servicesHandler.GetPublicServices)
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"mini-wallet/domain"
+	"mini-wallet/domain/auth"
 	"mini-wallet/domain/business"
 	"mini-wallet/domain/common/response"
 	"mini-wallet/domain/services"
@@ -57,8 +58,14 @@ func (usecase *servicesUsecase) UpdateService(ctx context.Context, req services.
 		return
 	}
 
-	if serviceEntity.BusinessID != req.BusinessID {
-		res.Unauthorized("has no right on this service")
+	ownsBusiness, err := usecase.ownsBusiness(ctx, userID, serviceEntity.BusinessID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if !ownsBusiness || serviceEntity.BusinessID != req.BusinessID {
+		res.Forbidden(auth.FORBIDDEN_MESSAGE, nil)
 		return
 	}
 
@@ -113,19 +120,14 @@ func (usecase *servicesUsecase) GetServices(ctx context.Context, req services.Ge
 }
 
 func (usecase *servicesUsecase) CreateService(ctx context.Context, req services.ServiceDTO, userID string) (res response.Response[string]) {
-	businessEntity, err := usecase.BusinessRepository.GetBusinessByUserId(ctx, userID)
+	ownsBusiness, err := usecase.ownsBusiness(ctx, userID, req.BusinessID)
 	if err != nil {
 		res.InternalServerError(err.Error())
 		return
 	}
 
-	if businessEntity == nil {
-		res.Forbidden("user has no business", nil)
-		return
-	}
-
-	if businessEntity.ID != req.BusinessID {
-		res.Unauthorized("has no right on this business")
+	if !ownsBusiness {
+		res.Forbidden(auth.FORBIDDEN_MESSAGE, nil)
 		return
 	}
 
@@ -140,3 +142,14 @@ func (usecase *servicesUsecase) CreateService(ctx context.Context, req services.
 	res.Success(serviceEntity.Slug)
 	return
 }
+
+// ownsBusiness tells whether businessID is the business of the user, the
+// permission middleware only checks the role, not which business it is for
+func (usecase *servicesUsecase) ownsBusiness(ctx context.Context, userID string, businessID string) (bool, error) {
+	businessEntity, err := usecase.BusinessRepository.GetBusinessByUserId(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return businessEntity != nil && businessEntity.ID == businessID, nil
+}
@@ -0,0 +1,169 @@
+package user
+
+import (
+	"mini-wallet/domain"
+	_auth "mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/user"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type userHandler struct {
+	userUsecase user.UserUsecase
+}
+
+func SetUserHandler(router *chi.Mux, usecases domain.Usecases, middleware _auth.AuthMiddleware) {
+	userHandler := userHandler{
+		userUsecase: usecases.UserUsecase,
+	}
+
+	router.Route("/users/me", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Get("/", userHandler.GetMe)
+		r.Get("/profile", userHandler.GetProfile)
+		r.Put("/profile", userHandler.UpdateProfile)
+		r.With(middleware.DenyImpersonation).Post("/email", userHandler.RequestEmailChange)
+		r.With(middleware.DenyImpersonation).Post("/email/verify", userHandler.ConfirmEmailChange)
+		r.With(middleware.DenyImpersonation).Post("/phone-number", userHandler.RequestPhoneNumberChange)
+		r.With(middleware.DenyImpersonation).Post("/phone-number/verify", userHandler.ConfirmPhoneNumberChange)
+	})
+}
+
+func (handler *userHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.userUsecase.GetMe(r.Context(), *userID)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *userHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+
+	res := handler.userUsecase.GetProfile(r.Context(), *userID)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *userHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := user.UpdateProfileDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+	res := handler.userUsecase.UpdateProfile(r.Context(), *userID, req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *userHandler) RequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := user.EmailChangeRequestDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+	res := handler.userUsecase.RequestEmailChange(r.Context(), *userID, req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *userHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := user.ContactChangeConfirmationDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+	res := handler.userUsecase.ConfirmEmailChange(r.Context(), *userID, req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *userHandler) RequestPhoneNumberChange(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := user.PhoneNumberChangeRequestDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+	res := handler.userUsecase.RequestPhoneNumberChange(r.Context(), *userID, req)
+	res.Writer = w
+	res.WriteResponse()
+}
+
+func (handler *userHandler) ConfirmPhoneNumberChange(w http.ResponseWriter, r *http.Request) {
+	resp := &response.Response[string]{
+		Writer: w,
+	}
+
+	req := user.ContactChangeConfirmationDTO{}
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		resp.BadRequest(err.Error(), nil)
+		resp.WriteResponse()
+		return
+	}
+
+	userID := r.Context().Value(_auth.UserIDContext{}).(*string)
+	res := handler.userUsecase.ConfirmPhoneNumberChange(r.Context(), *userID, req)
+	res.Writer = w
+	res.WriteResponse()
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"mini-wallet/domain"
 	"mini-wallet/domain/user"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -78,13 +79,8 @@ func (repository *userRepository) InsertUserPasswordResetEntity(ctx context.Cont
 func (repository *userRepository) DeleteUserPasswordResetEntity(ctx context.Context, email string) (err error) {
 	filter := bson.M{"email": email}
 
-	res := repository.userPasswordResetCollection.FindOneAndDelete(ctx, filter)
-	if res.Err() != nil {
-		return err
-	}
-
-	return nil
-
+	_, err = repository.userPasswordResetCollection.DeleteMany(ctx, filter)
+	return err
 }
 
 func (repository *userRepository) GetUserPasswordResetEntity(ctx context.Context, token string, now int64) (passwordReset *user.UserPasswordResetEntity, err error) {
@@ -105,23 +101,95 @@ func (repository *userRepository) GetUserPasswordResetEntity(ctx context.Context
 	return passwordReset, nil
 }
 
-func (repository *userRepository) DeleteTemporaryUser(ctx context.Context, email string) (err error) {
-	filter := bson.M{"email": email}
+func (repository *userRepository) DeleteUser(ctx context.Context, userID string) (err error) {
+	filter := bson.M{"uid": userID}
 
-	res := repository.temporaryUserCollection.FindOneAndDelete(ctx, filter)
-	if res.Err() != nil {
-		return err
+	_, err = repository.userCollection.DeleteOne(ctx, filter)
+	return err
+}
+
+func (repository *userRepository) EnsureExpiryIndexes(ctx context.Context) (err error) {
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "purge_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	// documents stored before purge_at existed are purged once expired like the
+	// others, the ones without an expiry right away
+	filter := bson.M{"purge_at": bson.M{"$exists": false}}
+	backfill := bson.A{
+		bson.M{"$set": bson.M{
+			"purge_at": bson.M{"$toDate": bson.M{
+				"$multiply": bson.A{bson.M{"$ifNull": bson.A{"$expired_at", 0}}, 1000},
+			}},
+		}},
+	}
+
+	for _, collection := range []*mongo.Collection{repository.temporaryUserCollection, repository.userPasswordResetCollection} {
+		_, err = collection.Indexes().CreateOne(ctx, index)
+		if err != nil {
+			return err
+		}
+
+		_, err = collection.UpdateMany(ctx, filter, backfill)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+func (repository *userRepository) EnsureUniqueIndexes(ctx context.Context) (err error) {
+	// users without an email or a phone number are left out of the index
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"email": bson.M{"$gt": ""},
+			}),
+		},
+		{
+			Keys: bson.D{{Key: "phone_number", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"phone_number": bson.M{"$gt": ""},
+			}),
+		},
+	}
+
+	_, err = repository.userCollection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+func (repository *userRepository) DeleteTemporaryUser(ctx context.Context, email string) (err error) {
+	filter := bson.M{"email": email}
+
+	_, err = repository.temporaryUserCollection.DeleteMany(ctx, filter)
+	return err
+}
+
+func (repository *userRepository) RenewTemporaryUserVerification(ctx context.Context, userID string, token string, expiredAt int, purgeAt time.Time) (err error) {
+	filter := bson.M{"uid": userID}
+
+	update := bson.M{"$set": bson.M{
+		"verification_token": token,
+		"expired_at":         expiredAt,
+		"purge_at":           purgeAt,
+	}}
+
+	_, err = repository.temporaryUserCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
 func (repository *userRepository) GetTemporaryUserByIdentifier(ctx context.Context, identifier string, now int64) (user *user.TemporaryUserEntity, err error) {
 	filter := bson.M{
 		"$or": bson.A{
 			bson.M{"email": identifier},
 			bson.M{"phone_number": identifier},
 		},
+		"expired_at": bson.M{
+			"$gt": now,
+		},
 	}
 
 	res := repository.temporaryUserCollection.FindOne(ctx, filter)
@@ -152,14 +220,24 @@ func (repository *userRepository) GetTemporaryUserByVerificationToken(ctx contex
 	return user, nil
 }
 
-func (repository *userRepository) InsertTemporaryUser(ctx context.Context, user user.TemporaryUserEntity) (err error) {
-	_, err = repository.temporaryUserCollection.InsertOne(ctx, user)
+func (repository *userRepository) ReplaceTemporaryUser(ctx context.Context, user user.TemporaryUserEntity) (err error) {
+	filter := bson.M{"email": user.Email}
+	if user.PhoneNumber != nil {
+		filter = bson.M{
+			"$or": bson.A{
+				bson.M{"email": user.Email},
+				bson.M{"phone_number": *user.PhoneNumber},
+			},
+		}
+	}
+
+	_, err = repository.temporaryUserCollection.DeleteMany(ctx, filter)
 	if err != nil {
-		log.Fatal(err.Error())
 		return err
 	}
 
-	return nil
+	_, err = repository.temporaryUserCollection.InsertOne(ctx, user)
+	return err
 }
 
 func (repository *userRepository) InsertUser(ctx context.Context, user user.UserEntity) (err error) {
@@ -210,3 +288,298 @@ func (repository *userRepository) GetUserByPhoneNumber(ctx context.Context, phon
 
 	return user, nil
 }
+
+func (repository *userRepository) SetTwoFactorPendingSecret(ctx context.Context, userID string, secret string) (err error) {
+	filter := bson.M{"uid": userID}
+
+	update := bson.M{"$set": bson.M{
+		"two_factor.pending_secret": secret,
+	}}
+
+	_, err = repository.userCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (repository *userRepository) EnableTwoFactor(ctx context.Context, userID string, secret string, recoveryCodes []string, usedStep int64, now int64) (enabled bool, err error) {
+	filter := bson.M{
+		"uid":                       userID,
+		"two_factor.pending_secret": secret,
+	}
+
+	update := bson.M{"$set": bson.M{
+		"two_factor.enabled":        true,
+		"two_factor.secret":         secret,
+		"two_factor.pending_secret": nil,
+		"two_factor.recovery_codes": recoveryCodes,
+		"two_factor.last_used_step": usedStep,
+		"two_factor.enabled_at":     now,
+	}}
+
+	result, err := repository.userCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (repository *userRepository) DisableTwoFactor(ctx context.Context, userID string) (err error) {
+	filter := bson.M{"uid": userID}
+
+	update := bson.M{"$unset": bson.M{
+		"two_factor": "",
+	}}
+
+	_, err = repository.userCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (repository *userRepository) SetTwoFactorRecoveryCodes(ctx context.Context, userID string, recoveryCodes []string) (err error) {
+	filter := bson.M{
+		"uid":                userID,
+		"two_factor.enabled": true,
+	}
+
+	update := bson.M{"$set": bson.M{
+		"two_factor.recovery_codes": recoveryCodes,
+	}}
+
+	_, err = repository.userCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (repository *userRepository) UseTwoFactorStep(ctx context.Context, userID string, step int64) (used bool, err error) {
+	filter := bson.M{
+		"uid": userID,
+		"two_factor.last_used_step": bson.M{
+			"$lt": step,
+		},
+	}
+
+	update := bson.M{"$set": bson.M{
+		"two_factor.last_used_step": step,
+	}}
+
+	result, err := repository.userCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (repository *userRepository) UseTwoFactorRecoveryCode(ctx context.Context, userID string, hashedCode string) (used bool, err error) {
+	filter := bson.M{
+		"uid":                       userID,
+		"two_factor.recovery_codes": hashedCode,
+	}
+
+	update := bson.M{"$pull": bson.M{
+		"two_factor.recovery_codes": hashedCode,
+	}}
+
+	result, err := repository.userCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (repository *userRepository) GetUserByIdentity(ctx context.Context, provider string, subject string) (user *user.UserEntity, err error) {
+	filter := bson.M{"identities": bson.M{
+		"$elemMatch": bson.M{
+			"provider": provider,
+			"subject":  subject,
+		},
+	}}
+
+	res := repository.userCollection.FindOne(ctx, filter)
+	if res.Err() != nil {
+		return nil, err
+	}
+
+	res.Decode(&user)
+
+	return user, nil
+}
+
+func (repository *userRepository) LinkIdentity(ctx context.Context, userID string, identity user.LinkedIdentityEntity) (linked bool, err error) {
+	filter := bson.M{
+		"uid": userID,
+		"identities.provider": bson.M{
+			"$ne": identity.Provider,
+		},
+	}
+
+	update := bson.M{"$push": bson.M{
+		"identities": identity,
+	}}
+
+	result, err := repository.userCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (repository *userRepository) UnlinkIdentity(ctx context.Context, userID string, provider string) (unlinked bool, err error) {
+	filter := bson.M{
+		"uid":                 userID,
+		"identities.provider": provider,
+	}
+
+	update := bson.M{"$pull": bson.M{
+		"identities": bson.M{
+			"provider": provider,
+		},
+	}}
+
+	result, err := repository.userCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (repository *userRepository) SetFirstPassword(ctx context.Context, userID string, hashedPassword string, now string) (set bool, err error) {
+	filter := bson.M{
+		"uid":             userID,
+		"hashed_password": nil,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"hashed_password": hashedPassword,
+			"updated_at":      now,
+		},
+		"$unset": bson.M{"password_salt": ""},
+	}
+
+	result, err := repository.userCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (repository *userRepository) UpdatePasswordHash(ctx context.Context, userID string, currentHash string, newHash string) (updated bool, err error) {
+	filter := bson.M{
+		"uid":             userID,
+		"hashed_password": currentHash,
+	}
+
+	update := bson.M{
+		"$set":   bson.M{"hashed_password": newHash},
+		"$unset": bson.M{"password_salt": ""},
+	}
+
+	result, err := repository.userCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (repository *userRepository) RequirePasswordReset(ctx context.Context, userID string) (err error) {
+	filter := bson.M{"uid": userID}
+
+	update := bson.M{"$set": bson.M{
+		"password_reset_required": true,
+	}}
+
+	_, err = repository.userCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (repository *userRepository) SetPhoneNumberVerified(ctx context.Context, userID string, now string) (err error) {
+	filter := bson.M{
+		"uid":                      userID,
+		"phone_number_verified_at": nil,
+	}
+
+	update := bson.M{"$set": bson.M{
+		"phone_number_verified_at": now,
+		"updated_at":               now,
+	}}
+
+	_, err = repository.userCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (repository *userRepository) UpdateProfile(ctx context.Context, userID string, name string, gender *string, now string) (err error) {
+	filter := bson.M{"uid": userID}
+
+	update := bson.M{"$set": bson.M{
+		"name":       name,
+		"gender":     gender,
+		"updated_at": now,
+	}}
+
+	_, err = repository.userCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (repository *userRepository) UpdateEmail(ctx context.Context, userID string, email string, now string) (taken bool, err error) {
+	filter := bson.M{"uid": userID}
+
+	update := bson.M{"$set": bson.M{
+		"email":             email,
+		"email_verified_at": now,
+		"updated_at":        now,
+	}}
+
+	_, err = repository.userCollection.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return true, nil
+	}
+
+	return false, err
+}
+
+func (repository *userRepository) UpdatePhoneNumber(ctx context.Context, userID string, phoneNumber string, now string) (taken bool, err error) {
+	filter := bson.M{"uid": userID}
+
+	update := bson.M{"$set": bson.M{
+		"phone_number":             phoneNumber,
+		"phone_number_verified_at": now,
+		"updated_at":               now,
+	}}
+
+	_, err = repository.userCollection.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return true, nil
+	}
+
+	return false, err
+}
+
+func (repository *userRepository) SetRoles(ctx context.Context, userID string, roles []string) (err error) {
+	filter := bson.M{"uid": userID}
+
+	update := bson.M{"$set": bson.M{
+		"roles": roles,
+	}}
+
+	_, err = repository.userCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (repository *userRepository) AddRole(ctx context.Context, userID string, role string) (added bool, err error) {
+	filter := bson.M{"uid": userID}
+
+	update := bson.M{"$addToSet": bson.M{
+		"roles": role,
+	}}
+
+	result, err := repository.userCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
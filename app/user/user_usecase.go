@@ -0,0 +1,408 @@
+package user
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"mini-wallet/domain"
+	"mini-wallet/domain/affiliate"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/business"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/user"
+	"mini-wallet/infrastructure"
+	"mini-wallet/integration"
+	"mini-wallet/utils"
+	"time"
+)
+
+type userUsecase struct {
+	userRepository        user.UserRepository
+	businessRepository    business.BusinessRepository
+	affiliateRepository   affiliate.AffiliateRepository
+	oneTimeCodeRepository auth.OneTimeCodeRepository
+	notificationService   integration.NotificationService
+	config                *utils.AppConfig
+}
+
+func NewUserUsecase(repositories domain.Repositories, integrations domain.Infrastructure, config *utils.AppConfig) user.UserUsecase {
+	return &userUsecase{
+		userRepository:        repositories.UserRepository,
+		businessRepository:    repositories.BusinessRepository,
+		affiliateRepository:   repositories.AffiliateRepository,
+		oneTimeCodeRepository: repositories.OneTimeCodeRepository,
+		notificationService:   integrations.NotificationService,
+		config:                config,
+	}
+}
+
+func (usecase *userUsecase) GetMe(ctx context.Context, userID string) (res response.Response[user.UserMeDTO]) {
+	userEntity, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if userEntity == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	me := user.UserMeDTO{
+		Profile: userEntity.ToUserProfileDTO(),
+		Roles:   auth.GetUserRoles(*userEntity),
+	}
+
+	userBusiness, err := usecase.businessRepository.GetBusinessByUserId(ctx, userEntity.UID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if userBusiness != nil {
+		businessStatus := userBusiness.GetOwnerStatus()
+		me.BusinessStatus = &businessStatus
+	}
+
+	userAffiliate, err := usecase.affiliateRepository.GetAffiliateByUserId(ctx, userEntity.UID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if userAffiliate != nil {
+		me.AffiliateStatus = int(userAffiliate.Status)
+	}
+
+	res.Success(me)
+	return
+}
+
+func (usecase *userUsecase) GetProfile(ctx context.Context, userID string) (res response.Response[user.UserProfileDTO]) {
+	userEntity, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if userEntity == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	res.Success(userEntity.ToUserProfileDTO())
+	return
+}
+
+func (usecase *userUsecase) UpdateProfile(ctx context.Context, userID string, req user.UpdateProfileDTO) (res response.Response[user.UserProfileDTO]) {
+	userEntity, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if userEntity == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	err = usecase.userRepository.UpdateProfile(ctx, userEntity.UID, req.Name, req.Gender, now.Format(time.RFC3339))
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	userEntity.Name = req.Name
+	userEntity.Gender = req.Gender
+
+	res.Success(userEntity.ToUserProfileDTO())
+	return
+}
+
+func (usecase *userUsecase) RequestEmailChange(ctx context.Context, userID string, req user.EmailChangeRequestDTO) (res response.Response[string]) {
+	userEntity, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if userEntity == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	if userEntity.Email == req.Email {
+		res.BadRequest("Email sama dengan email saat ini", nil)
+		return
+	}
+
+	existingUser, err := usecase.userRepository.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser != nil {
+		res.BadRequest("Email sudah digunakan", nil)
+		return
+	}
+
+	code, retryAfter, err := usecase.issueContactChangeCode(ctx, userEntity.UID, auth.ONE_TIME_CODE_PURPOSE_EMAIL_CHANGE, req.Email)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(fmt.Sprintf("Silakan tunggu %d detik sebelum meminta kode baru", retryAfter), retryAfter)
+		return
+	}
+
+	go infrastructure.SendVerificationCode(req.Email, userEntity.Name, code, auth.ONE_TIME_CODE_LIFETIME, usecase.config.AppDomain)
+
+	res.SuccessWithMessage("Kode verifikasi dikirimkan ke email baru Anda")
+	return
+}
+
+func (usecase *userUsecase) ConfirmEmailChange(ctx context.Context, userID string, req user.ContactChangeConfirmationDTO) (res response.Response[user.UserProfileDTO]) {
+	email, message, err := usecase.consumeContactChangeCode(ctx, userID, auth.ONE_TIME_CODE_PURPOSE_EMAIL_CHANGE, req.Code)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if message != "" {
+		res.BadRequest(message, nil)
+		return
+	}
+
+	// the email may have been taken since the code was sent
+	existingUser, err := usecase.userRepository.GetUserByEmail(ctx, email)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser != nil {
+		res.BadRequest("Email sudah digunakan", nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	// the unique index settles a race with another account taking the email
+	taken, err := usecase.userRepository.UpdateEmail(ctx, userID, email, now.Format(time.RFC3339))
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if taken {
+		res.BadRequest("Email sudah digunakan", nil)
+		return
+	}
+
+	return usecase.GetProfile(ctx, userID)
+}
+
+func (usecase *userUsecase) RequestPhoneNumberChange(ctx context.Context, userID string, req user.PhoneNumberChangeRequestDTO) (res response.Response[string]) {
+	userEntity, err := usecase.userRepository.GetUserByUserID(ctx, userID)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if userEntity == nil {
+		res.NotFound("Pengguna tidak ditemukan", nil)
+		return
+	}
+
+	if userEntity.PhoneNumber != nil && *userEntity.PhoneNumber == req.PhoneNumber {
+		res.BadRequest("Nomor handphone sama dengan nomor saat ini", nil)
+		return
+	}
+
+	existingUser, err := usecase.userRepository.GetUserByPhoneNumber(ctx, req.PhoneNumber)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser != nil {
+		res.BadRequest("Nomor handphone sudah digunakan", nil)
+		return
+	}
+
+	code, retryAfter, err := usecase.issueContactChangeCode(ctx, userEntity.UID, auth.ONE_TIME_CODE_PURPOSE_PHONE_NUMBER_CHANGE, req.PhoneNumber)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if retryAfter > 0 {
+		res.TooManyRequests(fmt.Sprintf("Silakan tunggu %d detik sebelum meminta kode baru", retryAfter), retryAfter)
+		return
+	}
+
+	message := fmt.Sprintf("Kode verifikasi nomor Sebia Anda: %s\nBerlaku %d menit. Jangan berikan kode ini kepada siapa pun, termasuk pihak Sebia.", code, int(auth.ONE_TIME_CODE_LIFETIME.Minutes()))
+	err = usecase.notificationService.SendWhatsAppMessage(ctx, message, req.PhoneNumber)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	res.SuccessWithMessage("Kode verifikasi dikirimkan ke WhatsApp nomor baru Anda")
+	return
+}
+
+func (usecase *userUsecase) ConfirmPhoneNumberChange(ctx context.Context, userID string, req user.ContactChangeConfirmationDTO) (res response.Response[user.UserProfileDTO]) {
+	phoneNumber, message, err := usecase.consumeContactChangeCode(ctx, userID, auth.ONE_TIME_CODE_PURPOSE_PHONE_NUMBER_CHANGE, req.Code)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if message != "" {
+		res.BadRequest(message, nil)
+		return
+	}
+
+	// the number may have been taken since the code was sent
+	existingUser, err := usecase.userRepository.GetUserByPhoneNumber(ctx, phoneNumber)
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if existingUser != nil {
+		res.BadRequest("Nomor handphone sudah digunakan", nil)
+		return
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	taken, err := usecase.userRepository.UpdatePhoneNumber(ctx, userID, phoneNumber, now.Format(time.RFC3339))
+	if err != nil {
+		res.InternalServerError(err.Error())
+		return
+	}
+
+	if taken {
+		res.BadRequest("Nomor handphone sudah digunakan", nil)
+		return
+	}
+
+	return usecase.GetProfile(ctx, userID)
+}
+
+// issueContactChangeCode replaces the pending change of the user with a new code
+// for target. retryAfter (seconds) is set instead when the last code is younger
+// than CONTACT_CHANGE_RESEND_COOLDOWN.
+func (usecase *userUsecase) issueContactChangeCode(ctx context.Context, userID string, purpose string, target string) (code string, retryAfter int, err error) {
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return "", 0, err
+	}
+
+	pendingCode, err := usecase.oneTimeCodeRepository.GetCode(ctx, purpose, userID, now.Unix())
+	if err != nil {
+		return "", 0, err
+	}
+
+	if pendingCode != nil {
+		resendAt := time.Unix(pendingCode.CreatedAt, 0).Add(user.CONTACT_CHANGE_RESEND_COOLDOWN)
+		if now.Before(resendAt) {
+			return "", int(math.Ceil(resendAt.Sub(*now).Seconds())), nil
+		}
+	}
+
+	code, err = utils.GenerateNumericCode(auth.ONE_TIME_CODE_LENGTH)
+	if err != nil {
+		return "", 0, err
+	}
+
+	oneTimeCode := auth.OneTimeCodeEntity{
+		ID:          utils.GenerateUniqueId(),
+		Purpose:     purpose,
+		Destination: userID,
+		Target:      target,
+		CreatedAt:   now.Unix(),
+		ExpiredAt:   now.Add(auth.ONE_TIME_CODE_LIFETIME).Unix(),
+	}
+	oneTimeCode.CodeHash = auth.HashOneTimeCode(oneTimeCode.ID, code)
+
+	err = usecase.oneTimeCodeRepository.UpsertCode(ctx, oneTimeCode)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return code, 0, nil
+}
+
+// consumeContactChangeCode checks the code of the pending change and returns its
+// target, or the message telling why the code was refused
+func (usecase *userUsecase) consumeContactChangeCode(ctx context.Context, userID string, purpose string, code string) (target string, message string, err error) {
+	now, err := utils.GetJktTime()
+	if err != nil {
+		return "", "", err
+	}
+
+	oneTimeCode, err := usecase.oneTimeCodeRepository.GetCode(ctx, purpose, userID, now.Unix())
+	if err != nil {
+		return "", "", err
+	}
+
+	if oneTimeCode == nil {
+		return "", "Kode salah atau kedaluwarsa, silakan minta kode baru", nil
+	}
+
+	// the guess is counted before comparing, parallel guesses can not outrun the limit
+	claimed, attempts, err := usecase.oneTimeCodeRepository.ClaimAttempt(ctx, oneTimeCode.ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !claimed {
+		return "", "Kode salah atau kedaluwarsa, silakan minta kode baru", nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(auth.HashOneTimeCode(oneTimeCode.ID, code)), []byte(oneTimeCode.CodeHash)) != 1 {
+		if attempts >= auth.ONE_TIME_CODE_MAX_ATTEMPTS {
+			_, err = usecase.oneTimeCodeRepository.DeleteCode(ctx, oneTimeCode.ID)
+			if err != nil {
+				return "", "", err
+			}
+
+			return "", "Terlalu banyak percobaan, silakan minta kode baru", nil
+		}
+
+		return "", "Kode salah", nil
+	}
+
+	// a concurrent confirmation may have consumed the code already
+	deleted, err := usecase.oneTimeCodeRepository.DeleteCode(ctx, oneTimeCode.ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !deleted {
+		return "", "Kode salah atau kedaluwarsa, silakan minta kode baru", nil
+	}
+
+	return oneTimeCode.Target, "", nil
+}
@@ -90,10 +90,16 @@ func (p *AffiliateAppicationDTO) ToAffiliateEntity() AffiliateEntity {
 		UserID:            p.UserID,
 		CreatedAt:         now.Unix(),
 		UpdatedAt:         now.Unix(),
-		Status:            1,
+		Status:            AFFILIATE_STATUS_PENDING,
 	}
 }
 
+const (
+	AFFILIATE_STATUS_PENDING  = 1
+	AFFILIATE_STATUS_APPROVED = 2
+	AFFILIATE_STATUS_REJECTED = 3
+)
+
 type AffiliateEntity struct {
 	InstagramUsername *string `bson:"instagram_username"`
 	TiktokUsername    *string `bson:"tiktok_username"`
@@ -108,6 +114,10 @@ type AffiliateEntity struct {
 	Status    int   `bson:"status"`
 	CreatedAt int64 `bson:"created_at"`
 	UpdatedAt int64 `bson:"updated_at"`
+
+	// set by the moderation decision, see moderation.ModerationDecisionEntity
+	RejectionReason *string `bson:"rejection_reason,omitempty"`
+	ReviewedAt      *int64  `bson:"reviewed_at,omitempty"`
 }
 
 type AffiliateUsecase interface {
@@ -118,4 +128,17 @@ type AffiliateUsecase interface {
 type AffiliateRepository interface {
 	InsertAffiliate(ctx context.Context, entity AffiliateEntity) (err error)
 	GetAffiliateByUserId(ctx context.Context, userID string) (res *AffiliateEntity, err error)
+	GetAffiliates(ctx context.Context, filter AffiliateFilter) (res []AffiliateEntity, total int64, err error)
+	// UpdateAffiliateStatus only moves an application that is still in fromStatus, false means it was decided meanwhile
+	UpdateAffiliateStatus(ctx context.Context, userID string, fromStatus int, toStatus int, rejectionReason *string, now int64) (updated bool, err error)
+	DeleteAffiliate(ctx context.Context, userID string) (err error)
+}
+
+// AffiliateFilter lists applications newest first, zero values are not filtered on
+type AffiliateFilter struct {
+	Status     int
+	ProvinceID int
+	CityID     int
+	Page       int
+	Size       int
 }
@@ -0,0 +1,166 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/utils"
+)
+
+const (
+	EVENT_LOGIN                    = "login"
+	EVENT_REGISTER                 = "register"
+	EVENT_VERIFY                   = "verify"
+	EVENT_PASSWORD_RESET_REQUEST   = "password_reset_request"
+	EVENT_PASSWORD_RESET           = "password_reset"
+	EVENT_PASSWORD_SET             = "password_set"
+	EVENT_PASSWORD_CHANGE          = "password_change"
+	EVENT_TOKEN_REFRESH            = "token_refresh"
+	EVENT_LOGOUT                   = "logout"
+	EVENT_SESSION_REVOKE           = "session_revoke"
+	EVENT_TWO_FACTOR_ENABLE        = "two_factor_enable"
+	EVENT_TWO_FACTOR_DISABLE       = "two_factor_disable"
+	EVENT_PASSKEY_REGISTER         = "passkey_register"
+	EVENT_PASSKEY_DELETE           = "passkey_delete"
+	EVENT_IDENTITY_LINK            = "identity_link"
+	EVENT_IDENTITY_UNLINK          = "identity_unlink"
+	EVENT_ROLES_CHANGE             = "roles_change"
+	EVENT_DATA_EXPORT              = "data_export"
+	EVENT_ACCOUNT_DELETION_REQUEST = "account_deletion_request"
+	EVENT_ACCOUNT_DELETION_CANCEL  = "account_deletion_cancel"
+	EVENT_ACCOUNT_DELETE           = "account_delete"
+	EVENT_IMPERSONATION_START      = "impersonation_start"
+	EVENT_IMPERSONATION_END        = "impersonation_end"
+	// every request made with an impersonation token, Identifier is the method and path
+	EVENT_IMPERSONATED_REQUEST = "impersonated_request"
+	// a login on a device the user never signed in on, Identifier is the known device
+	EVENT_NEW_DEVICE_LOGIN = "new_device_login"
+	EVENT_SUSPICIOUS_LOGIN = "suspicious_login"
+	EVENT_LOGIN_REPORT     = "login_report"
+)
+
+const (
+	METHOD_PASSWORD   = "password"
+	METHOD_TWO_FACTOR = "two_factor"
+	METHOD_PASSKEY    = "passkey"
+	METHOD_WHATSAPP   = "whatsapp"
+	METHOD_EMAIL_LINK = "email_link"
+	METHOD_INQUIRY    = "inquiry"
+	// identity provider logins use the provider name, e.g. "google"
+)
+
+const (
+	OUTCOME_SUCCESS = "success"
+	OUTCOME_FAILURE = "failure"
+	// the password step passed, the second factor is still required
+	OUTCOME_CHALLENGED = "challenged"
+
+	MAX_PAGE_SIZE = 100
+)
+
+var ErrInvalidTimeRange = errors.New("rentang waktu tidak valid")
+
+// AuditEventEntity is append-only, events are never updated nor deleted.
+// UserID is empty when the user could not be resolved (e.g. unknown email),
+// Identifier then tells what was tried.
+type AuditEventEntity struct {
+	ID         string `bson:"id"`
+	Type       string `bson:"type"`
+	Outcome    string `bson:"outcome"`
+	Method     string `bson:"method,omitempty"`
+	UserID     string `bson:"user_id,omitempty"`
+	ActorID    string `bson:"actor_id,omitempty"`
+	Identifier string `bson:"identifier,omitempty"`
+	SessionID  string `bson:"session_id,omitempty"`
+	// the error message of failures, e.g. "Kata sandi salah"
+	Reason     string `bson:"reason,omitempty"`
+	StatusCode int    `bson:"status_code"`
+	IPAddress  string `bson:"ip_address"`
+	UserAgent  string `bson:"user_agent"`
+	CreatedAt  int64  `bson:"created_at"`
+}
+
+func (p *AuditEventEntity) ToAuditEventDTO() AuditEventDTO {
+	return AuditEventDTO{
+		ID:         p.ID,
+		Type:       p.Type,
+		Outcome:    p.Outcome,
+		Method:     p.Method,
+		UserID:     p.UserID,
+		ActorID:    p.ActorID,
+		Identifier: p.Identifier,
+		SessionID:  p.SessionID,
+		Reason:     p.Reason,
+		StatusCode: p.StatusCode,
+		IPAddress:  p.IPAddress,
+		UserAgent:  p.UserAgent,
+		CreatedAt:  p.CreatedAt,
+	}
+}
+
+type AuditEventDTO struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Outcome    string `json:"outcome"`
+	Method     string `json:"method,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+	ActorID    string `json:"actor_id,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+	SessionID  string `json:"session_id,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	StatusCode int    `json:"status_code"`
+	IPAddress  string `json:"ip_address"`
+	UserAgent  string `json:"user_agent"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+type AuditEventPageDTO struct {
+	Events []AuditEventDTO `json:"events"`
+	Total  int64           `json:"total"`
+	Page   int             `json:"page"`
+	Size   int             `json:"size"`
+}
+
+// GetAuditEventsRequest is decoded from the query string, From and To are unix
+// seconds and inclusive, zero values are not filtered on
+type GetAuditEventsRequest struct {
+	UserID  string `json:"user_id" schema:"user_id"`
+	Type    string `json:"type"`
+	Outcome string `json:"outcome"`
+	From    int64  `json:"from"`
+	To      int64  `json:"to"`
+	Page    int    `json:"page"`
+	Size    int    `json:"size"`
+}
+
+func (p *GetAuditEventsRequest) Validate() error {
+	if p.From < 0 || p.To < 0 || (p.To != 0 && p.From > p.To) {
+		return ErrInvalidTimeRange
+	}
+
+	err := utils.ValidateRequiredInt(p.Page)
+	if err != nil {
+		return err
+	}
+
+	err = utils.ValidateRequiredInt(p.Size)
+	if err != nil {
+		return err
+	}
+
+	if p.Size > MAX_PAGE_SIZE {
+		p.Size = MAX_PAGE_SIZE
+	}
+
+	return nil
+}
+
+type AuditUsecase interface {
+	GetAuditEvents(ctx context.Context, req GetAuditEventsRequest) (res response.Response[AuditEventPageDTO])
+}
+
+// AuditRepository has no update nor delete on purpose
+type AuditRepository interface {
+	InsertEvent(ctx context.Context, entity AuditEventEntity) (err error)
+	GetEvents(ctx context.Context, req GetAuditEventsRequest) (res []AuditEventEntity, total int64, err error)
+}
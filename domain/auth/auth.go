@@ -5,15 +5,19 @@ import (
 	"mini-wallet/domain/common/response"
 	"mini-wallet/domain/user"
 	"mini-wallet/utils"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthUsecase interface {
-	RegisterByGoogle(ctx context.Context, req GoogleRegisterDTO) (res response.Response[AuthenticationResponse])
-	AuthenticateByGoogle(ctx context.Context, req GoogleRegisterDTO) (res response.Response[AuthenticationResponse])
+	RegisterWithProvider(ctx context.Context, provider string, req ProviderAuthenticationDTO) (res response.Response[AuthenticationResponse])
+	AuthenticateWithProvider(ctx context.Context, provider string, req ProviderAuthenticationDTO) (res response.Response[AuthenticationResponse])
+	RequestWhatsAppCode(ctx context.Context, req WhatsAppCodeRequestDTO) (res response.Response[string])
+	AuthenticateWithWhatsAppCode(ctx context.Context, req WhatsAppCodeVerificationDTO) (res response.Response[AuthenticationResponse])
+	RequestMagicLink(ctx context.Context, req MagicLinkRequestDTO) (res response.Response[string])
+	AuthenticateWithMagicLink(ctx context.Context, req MagicLinkVerificationDTO) (res response.Response[AuthenticationResponse])
 	AuthenticateRegularUser(ctx context.Context, req AuthenticationDTO) (res response.Response[AuthenticationResponse])
 	RegisterUser(ctx context.Context, req UserRegistrationDTO) (res response.Response[interface{}])
 	SendPasswordResetLink(ctx context.Context, req PasswordResetDTO) (res response.Response[string])
@@ -21,9 +25,68 @@ type AuthUsecase interface {
 	VerifyResetPasswordToken(ctx context.Context, req VerifyResetPasswordTokenDTO) (res response.Response[string])
 	CheckIdentifier(ctx context.Context, req CheckIndentifierDTO) (res response.Response[string])
 	VerifyPhoneNumber(ctx context.Context, req VerifyEmailDTO) (res response.Response[AuthenticationResponse])
-	RefreshAccess(ctx context.Context) (res response.Response[AuthenticationResponse])
+	RefreshAccess(ctx context.Context, refreshToken string) (res response.Response[AuthenticationResponse])
+	Logout(ctx context.Context, refreshToken string) (res response.Response[AuthenticationResponse])
+	// GetCsrfToken issues a new CSRF token of the session, also as a cookie
+	GetCsrfToken(ctx context.Context, sessionID string) (res response.Response[CsrfTokenDTO])
+	GetSessions(ctx context.Context, userID string, currentSessionID string) (res response.Response[[]SessionDTO])
+	RevokeSession(ctx context.Context, userID string, sessionID string) (res response.Response[string])
+	RevokeOtherSessions(ctx context.Context, userID string, currentSessionID string) (res response.Response[string])
 	RegisterUserFromInquiry(ctx context.Context, req AuthFromInquiryDTO) (res response.Response[string])
+	// ResendVerification sends a new link of a pending registration, the previous link stops working
+	ResendVerification(ctx context.Context, req ResendVerificationDTO) (res response.Response[string])
 	AuthenticateFromInquiry(ctx context.Context, req AuthFromInquiryDTO) (res response.Response[AuthenticationResponse])
+
+	// two factor authentication
+	EnrollTwoFactor(ctx context.Context, userID string) (res response.Response[TwoFactorEnrollmentDTO])
+	ConfirmTwoFactor(ctx context.Context, userID string, req TwoFactorConfirmationDTO) (res response.Response[TwoFactorRecoveryCodesDTO])
+	DisableTwoFactor(ctx context.Context, userID string, req TwoFactorDisableDTO) (res response.Response[string])
+	RegenerateRecoveryCodes(ctx context.Context, userID string, req TwoFactorConfirmationDTO) (res response.Response[TwoFactorRecoveryCodesDTO])
+	AuthenticateTwoFactor(ctx context.Context, req TwoFactorAuthenticationDTO) (res response.Response[AuthenticationResponse])
+
+	// passkeys
+	BeginPasskeyRegistration(ctx context.Context, userID string) (res response.Response[PasskeyCeremonyDTO])
+	FinishPasskeyRegistration(ctx context.Context, userID string, req PasskeyRegistrationDTO) (res response.Response[PasskeyDTO])
+	BeginPasskeyAuthentication(ctx context.Context) (res response.Response[PasskeyCeremonyDTO])
+	FinishPasskeyAuthentication(ctx context.Context, req PasskeyAuthenticationDTO) (res response.Response[AuthenticationResponse])
+	GetPasskeys(ctx context.Context, userID string) (res response.Response[[]PasskeyDTO])
+	DeletePasskey(ctx context.Context, userID string, passkeyID string) (res response.Response[string])
+
+	// login methods
+	GetLoginMethods(ctx context.Context, userID string) (res response.Response[LoginMethodsDTO])
+	LinkIdentity(ctx context.Context, userID string, provider string, req ProviderAuthenticationDTO) (res response.Response[LinkedIdentityDTO])
+	UnlinkIdentity(ctx context.Context, userID string, provider string) (res response.Response[string])
+	SetPassword(ctx context.Context, userID string, req SetPasswordDTO) (res response.Response[string])
+	// ChangePassword replaces the password after checking the current one, the
+	// current session is kept
+	ChangePassword(ctx context.Context, userID string, sessionID string, req ChangePasswordDTO) (res response.Response[string])
+
+	// impersonation
+	// StartImpersonation issues a short-lived access token of the user to a staff
+	// member, there is no refresh token
+	StartImpersonation(ctx context.Context, actorID string, userID string, req ImpersonationRequestDTO) (res response.Response[ImpersonationDTO])
+	// EndImpersonation ends the impersonation session the request is made with
+	EndImpersonation(ctx context.Context, sessionID string) (res response.Response[string])
+
+	// known devices
+	GetKnownDevices(ctx context.Context, userID string) (res response.Response[[]KnownDeviceDTO])
+	// DeleteKnownDevice forgets the device, the next login on it is alerted again
+	DeleteKnownDevice(ctx context.Context, userID string, deviceID string) (res response.Response[string])
+	// ReportUnrecognizedLogin is the "this wasn't me" link of a new device alert,
+	// every session is revoked and the password has to be reset
+	ReportUnrecognizedLogin(ctx context.Context, req LoginReportDTO) (res response.Response[string])
+
+	// roles
+	GetUserRoles(ctx context.Context, userID string) (res response.Response[RoleAssignmentDTO])
+	// SetUserRoles replaces the granted roles, guest is always kept
+	SetUserRoles(ctx context.Context, userID string, req RoleAssignmentDTO) (res response.Response[RoleAssignmentDTO])
+
+	// internal services, see infrastructure/proto/auth.proto
+	// IntrospectToken accepts access tokens of users and of OAuth clients, other
+	// tokens and tokens of ended sessions are inactive
+	IntrospectToken(ctx context.Context, token string) (res response.Response[TokenIntrospectionDTO])
+	GetUserSummary(ctx context.Context, userID string) (res response.Response[UserSummaryDTO])
+	CheckPermission(ctx context.Context, userID string, permission string) (res response.Response[PermissionCheckDTO])
 }
 
 type AuthFromInquiryDTO struct {
@@ -53,9 +116,28 @@ func (p *AuthFromInquiryDTO) Validate() error {
 type UserIDContext struct {
 }
 
+type SessionIDContext struct {
+}
+
 type TokenStatus struct {
 }
 
+type ClientInfoContext struct {
+}
+
+// ClientInfo describes where a request came from, set by ClientInfoMiddleware
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+	// set by DeviceMiddleware, empty for devices that never signed in
+	DeviceID string
+}
+
+func GetClientInfo(ctx context.Context) ClientInfo {
+	clientInfo, _ := ctx.Value(ClientInfoContext{}).(ClientInfo)
+	return clientInfo
+}
+
 type AuthRepository interface {
 	AddToken(ctx context.Context, token string, walletId string) (err error)
 	GetTokenWalletId(ctx context.Context, token string) (walletId string, err error)
@@ -98,16 +180,16 @@ func (p *UserRegistrationDTO) Validate() (err error) {
 		return err
 	}
 
-	err = utils.ValidatePassword(p.Password)
+	newPhoneNumber, err := utils.ValidatePhoneNumber(p.PhoneNumber)
 	if err != nil {
 		return err
 	}
+	p.PhoneNumber = *newPhoneNumber
 
-	newPhoneNumber, err := utils.ValidatePhoneNumber(p.PhoneNumber)
+	err = utils.CheckPasswordPolicy(p.Password, p.Name, p.Email, p.PhoneNumber)
 	if err != nil {
 		return err
 	}
-	p.PhoneNumber = *newPhoneNumber
 
 	err = utils.ValidateFullName(p.Name)
 	if err != nil {
@@ -163,6 +245,23 @@ func (p *VerifyResetPasswordTokenDTO) Validate() (err error) {
 	return nil
 }
 
+// ChangePasswordDTO is sent by a signed in user, SignOutOtherSessions ends every
+// session but the current one
+type ChangePasswordDTO struct {
+	CurrentPassword      string `json:"current_password"`
+	NewPassword          string `json:"new_password"`
+	SignOutOtherSessions bool   `json:"sign_out_other_sessions"`
+}
+
+func (p *ChangePasswordDTO) Validate() (err error) {
+	err = utils.ValidateRequired(p.CurrentPassword)
+	if err != nil {
+		return err
+	}
+
+	return utils.ValidatePassword(p.NewPassword)
+}
+
 type PasswordResetSubmissionDTO struct {
 	Password           string `json:"password"`
 	PasswordResetToken string `json:"password_reset_token"`
@@ -187,6 +286,16 @@ func (p *PasswordResetSubmissionDTO) Validate() (err error) {
 	return nil
 }
 
+// ResendVerificationDTO names the pending registration by its email or phone number
+type ResendVerificationDTO struct {
+	Identifier string `json:"identifier"`
+}
+
+func (p *ResendVerificationDTO) Validate() error {
+	p.Identifier = strings.ToLower(strings.TrimSpace(p.Identifier))
+	return utils.ValidateRequired(p.Identifier)
+}
+
 type VerifyEmailDTO struct {
 	Token string `json:"token"`
 }
@@ -219,43 +328,34 @@ type AuthenticationDTO struct {
 	Password   string `json:"password"`
 }
 
-type GoogleRegisterDTO struct {
-	Name            string          `json:"displayName"`
-	StsTokenManager StsTokenManager `json:"stsTokenManager"`
-	Email           string          `json:"email"`
-}
-
-type StsTokenManager struct {
-	AccessToken  string `json:"accessToken"`
-	RefreshToken string `json:"refreshToken"`
-}
-
 type AuthenticationResponse struct {
 	AccessToken  string `json:config.AccessTokenKey`
 	RefreshToken string `json:config.RefreshTokenKey`
-}
 
-type AcessTokenClaims struct {
-	jwt.RegisteredClaims
-	Name   string `json:"name"`
-	UserID string `json:"user_id"`
+	// set instead of the tokens when the password step passed but 2FA is still required
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+
+	// set when a session starts, see GetCsrfToken
+	CsrfToken string `json:"csrf_token,omitempty"`
+	// set when a session starts, clients without cookies send it in DEVICE_HEADER
+	DeviceID string `json:"device_id,omitempty"`
 }
 
-func (p *GoogleRegisterDTO) ToUserEntity() (res *user.UserEntity, err error) {
-	now, err := utils.GetJktTime()
-	if err != nil {
-		return nil, err
-	}
+// RefreshTokenDTO is how clients without cookies send their refresh token
+type RefreshTokenDTO struct {
+	RefreshToken string `json:"refresh_token"`
+}
 
-	nowString := now.Format(time.RFC3339)
-	return &user.UserEntity{
-		UID:             utils.GenerateUniqueId(),
-		Name:            p.Name,
-		Email:           p.Email,
-		EmailVerifiedAt: nowString,
-		CreatedAt:       now.Format(time.RFC3339),
-		UpdatedAt:       now.Format(time.RFC3339),
-	}, nil
+type AcessTokenClaims struct {
+	jwt.RegisteredClaims
+	Name      string   `json:"name"`
+	UserID    string   `json:"user_id"`
+	SessionID string   `json:"sid,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	// only set on impersonation tokens, the staff member acting as the subject
+	Actor *TokenActor `json:"act,omitempty"`
 }
 
 func (p *UserRegistrationDTO) ToTemporaryUserEntity() (res *user.TemporaryUserEntity, err error) {
@@ -264,20 +364,11 @@ func (p *UserRegistrationDTO) ToTemporaryUserEntity() (res *user.TemporaryUserEn
 		return nil, err
 	}
 
-	salt, err := utils.GenerateSalt(16)
-	if err != nil {
-		return nil, err
-	}
-
-	saltedPassword := p.Password + salt
-	hash, err := bcrypt.GenerateFromPassword([]byte(saltedPassword), bcrypt.DefaultCost)
+	hashedPassword, err := utils.HashPassword(p.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	stringHashedPassword := string(hash)
-	stringSalt := string(salt)
-
 	VerificationToken, err := utils.GenerateRandomString(32)
 	if err != nil {
 		return nil, err
@@ -288,10 +379,10 @@ func (p *UserRegistrationDTO) ToTemporaryUserEntity() (res *user.TemporaryUserEn
 		Name:              p.Name,
 		Email:             p.Email,
 		PhoneNumber:       &p.PhoneNumber,
-		HashedPassword:    &stringHashedPassword,
+		HashedPassword:    &hashedPassword,
 		CreatedAt:         now.Format(time.RFC3339),
-		ExpiredAt:         int(now.Add(time.Minute * 15).Unix()),
-		PasswordSalt:      &stringSalt,
+		ExpiredAt:         int(now.Add(user.TEMPORARY_USER_LIFETIME).Unix()),
 		VerificationToken: VerificationToken,
+		PurgeAt:           now.Add(user.TEMPORARY_USER_LIFETIME),
 	}, nil
 }
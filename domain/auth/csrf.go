@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"mini-wallet/utils"
+	"strings"
+)
+
+const (
+	// readable by the frontend, which copies it into CSRF_HEADER
+	CSRF_COOKIE      = "csrf_token"
+	CSRF_HEADER      = "X-CSRF-Token"
+	CSRF_SECRET_SIZE = 32
+	CSRF_NONCE_SIZE  = 16
+
+	CSRF_REJECTED_MESSAGE = "Permintaan ditolak, muat ulang halaman lalu coba lagi"
+)
+
+// GenerateCsrfToken returns <nonce>.<hmac of nonce>, only the session holding
+// secret can have produced it
+func GenerateCsrfToken(secret string) (string, error) {
+	nonce, err := utils.GenerateRandomString(CSRF_NONCE_SIZE)
+	if err != nil {
+		return "", err
+	}
+
+	return nonce + "." + utils.SignToken(secret, nonce), nil
+}
+
+func VerifyCsrfToken(secret string, token string) bool {
+	nonce, signature, found := strings.Cut(token, ".")
+	if !found || secret == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(utils.SignToken(secret, nonce)), []byte(signature)) == 1
+}
+
+type CsrfTokenDTO struct {
+	CsrfToken string `json:"csrf_token"`
+}
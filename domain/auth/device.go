@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"mini-wallet/utils"
+	"time"
+)
+
+const (
+	// browsers keep the device id in a cookie, clients without cookies send it in
+	// DEVICE_HEADER after reading it from the login response
+	DEVICE_COOKIE          = "device_id"
+	DEVICE_HEADER          = "X-Device-ID"
+	DEVICE_ID_SIZE         = 32
+	DEVICE_COOKIE_LIFETIME = 2 * 365 * 24 * time.Hour
+
+	// a new device signing in after this long without any login is suspicious
+	LOGIN_DORMANT_AFTER = 90 * 24 * time.Hour
+	// the "this wasn't me" link of a new device alert works for this long
+	LOGIN_REPORT_LIFETIME = 7 * 24 * time.Hour
+
+	PASSWORD_RESET_REQUIRED_MESSAGE = "Demi keamanan akun Anda, atur ulang kata sandi melalui link yang kami kirimkan ke email Anda"
+)
+
+// KnownDeviceEntity is a device the user signed in on. The device id stays in
+// the cookie of the device, only its sha256 is stored.
+type KnownDeviceEntity struct {
+	ID          string `bson:"id"`
+	UserID      string `bson:"user_id"`
+	DeviceHash  string `bson:"device_hash"`
+	UserAgent   string `bson:"user_agent"`
+	IPAddress   string `bson:"ip_address"`
+	CreatedAt   int64  `bson:"created_at"`
+	LastLoginAt int64  `bson:"last_login_at"`
+}
+
+func (p *KnownDeviceEntity) ToKnownDeviceDTO(currentDeviceHash string) KnownDeviceDTO {
+	return KnownDeviceDTO{
+		ID:          p.ID,
+		UserAgent:   p.UserAgent,
+		IPAddress:   p.IPAddress,
+		CreatedAt:   p.CreatedAt,
+		LastLoginAt: p.LastLoginAt,
+		Current:     p.DeviceHash == currentDeviceHash,
+	}
+}
+
+type KnownDeviceDTO struct {
+	ID          string `json:"id"`
+	UserAgent   string `json:"user_agent"`
+	IPAddress   string `json:"ip_address"`
+	CreatedAt   int64  `json:"created_at"`
+	LastLoginAt int64  `json:"last_login_at"`
+	Current     bool   `json:"current"`
+}
+
+// FindKnownDevice returns the device of the hash, nil when the user never signed in on it
+func FindKnownDevice(devices []KnownDeviceEntity, deviceHash string) *KnownDeviceEntity {
+	for i := range devices {
+		if devices[i].DeviceHash == deviceHash {
+			return &devices[i]
+		}
+	}
+
+	return nil
+}
+
+// LoginSignals compare a login with the earlier logins of the user
+type LoginSignals struct {
+	// the user has no known device yet, e.g. right after registering
+	FirstLogin   bool
+	NewDevice    bool
+	NewIPAddress bool
+	NewUserAgent bool
+	// zero on the first login
+	SinceLastLogin time.Duration
+}
+
+func EvaluateLoginSignals(devices []KnownDeviceEntity, device *KnownDeviceEntity, clientInfo ClientInfo, now int64) LoginSignals {
+	signals := LoginSignals{
+		FirstLogin:   len(devices) == 0,
+		NewDevice:    device == nil,
+		NewIPAddress: true,
+		NewUserAgent: true,
+	}
+
+	var lastLoginAt int64
+	for _, knownDevice := range devices {
+		if knownDevice.IPAddress == clientInfo.IPAddress {
+			signals.NewIPAddress = false
+		}
+
+		if knownDevice.UserAgent == clientInfo.UserAgent {
+			signals.NewUserAgent = false
+		}
+
+		if knownDevice.LastLoginAt > lastLoginAt {
+			lastLoginAt = knownDevice.LastLoginAt
+		}
+	}
+
+	if lastLoginAt > 0 {
+		signals.SinceLastLogin = time.Duration(now-lastLoginAt) * time.Second
+	}
+
+	return signals
+}
+
+// ShouldAlert tells whether the user is told about the login, the first device
+// of an account is trusted
+func (p LoginSignals) ShouldAlert() bool {
+	return p.NewDevice && !p.FirstLogin
+}
+
+// Suspicious is a new device that also comes from an unknown network, or after a
+// long absence
+func (p LoginSignals) Suspicious() bool {
+	return p.ShouldAlert() && (p.NewIPAddress || p.SinceLastLogin > LOGIN_DORMANT_AFTER)
+}
+
+// LoginReportDTO carries the token of the "this wasn't me" link, <code id>.<secret>
+type LoginReportDTO struct {
+	Token string `json:"token"`
+}
+
+func (p *LoginReportDTO) Validate() error {
+	return utils.ValidateRequired(p.Token)
+}
+
+type KnownDeviceRepository interface {
+	InsertKnownDevice(ctx context.Context, device KnownDeviceEntity) (err error)
+	GetKnownDevicesByUserID(ctx context.Context, userID string) (res []KnownDeviceEntity, err error)
+	// TouchKnownDevice records a login on the device
+	TouchKnownDevice(ctx context.Context, id string, clientInfo ClientInfo, now int64) (err error)
+	// DeleteKnownDevice forgets a device of the user, false means there was none
+	DeleteKnownDevice(ctx context.Context, userID string, id string) (deleted bool, err error)
+	DeleteKnownDevicesByUserID(ctx context.Context, userID string) (err error)
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"errors"
+	"mini-wallet/domain/user"
+	"mini-wallet/integration"
+	"mini-wallet/utils"
+)
+
+// ProviderAuthenticationDTO carries the credential an identity provider SDK
+// gave the client, see integration.ProviderCredential
+type ProviderAuthenticationDTO struct {
+	IDToken     string `json:"id_token"`
+	AccessToken string `json:"access_token"`
+	Nonce       string `json:"nonce"`
+	// only used when the provider does not share the name itself (apple after the first sign in)
+	Name string `json:"name"`
+}
+
+func (p *ProviderAuthenticationDTO) Validate() error {
+	if p.IDToken == "" && p.AccessToken == "" {
+		return errors.New("id_token atau access_token wajib diisi")
+	}
+
+	return nil
+}
+
+func (p *ProviderAuthenticationDTO) ToProviderCredential() integration.ProviderCredential {
+	return integration.ProviderCredential{
+		IDToken:     p.IDToken,
+		AccessToken: p.AccessToken,
+		Nonce:       p.Nonce,
+	}
+}
+
+type LinkedIdentityDTO struct {
+	Provider string `json:"provider"`
+	Email    string `json:"email"`
+	LinkedAt int64  `json:"linked_at"`
+}
+
+// LoginMethodsDTO lists every way the user can sign in, the last one can not be removed
+type LoginMethodsDTO struct {
+	HasPassword bool                `json:"has_password"`
+	Identities  []LinkedIdentityDTO `json:"identities"`
+	Passkeys    int                 `json:"passkeys"`
+	WhatsApp    bool                `json:"whatsapp"`
+}
+
+type SetPasswordDTO struct {
+	Password string `json:"password"`
+}
+
+func (p *SetPasswordDTO) Validate() error {
+	return utils.ValidatePassword(p.Password)
+}
+
+func ToLinkedIdentityDTO(identity user.LinkedIdentityEntity) LinkedIdentityDTO {
+	return LinkedIdentityDTO{
+		Provider: identity.Provider,
+		Email:    identity.Email,
+		LinkedAt: identity.LinkedAt,
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"mini-wallet/utils"
+	"strings"
+	"time"
+)
+
+const (
+	// impersonation tokens are never refreshed, staff start a new impersonation instead
+	IMPERSONATION_TOKEN_LIFETIME = 15 * time.Minute
+
+	IMPERSONATION_FORBIDDEN_MESSAGE = "Tindakan ini tidak dapat dilakukan saat menyamar sebagai pengguna"
+)
+
+// TokenActor is the act claim (RFC 8693) of impersonation tokens, the staff
+// member acting as the subject of the token
+type TokenActor struct {
+	Subject string `json:"sub"`
+	Name    string `json:"name,omitempty"`
+}
+
+// ImpersonatorContext holds the id of the staff member a request is made by
+// while impersonating, it is not set otherwise
+type ImpersonatorContext struct {
+}
+
+// GetImpersonatorID returns the actor of an impersonated request, empty when the
+// user makes the request themselves
+func GetImpersonatorID(ctx context.Context) string {
+	actorID, _ := ctx.Value(ImpersonatorContext{}).(string)
+	return actorID
+}
+
+type ImpersonationRequestDTO struct {
+	// why the user is impersonated, e.g. the support ticket, kept in the audit log
+	Reason string `json:"reason"`
+}
+
+func (p *ImpersonationRequestDTO) Validate() error {
+	p.Reason = strings.TrimSpace(p.Reason)
+	return utils.ValidateRequired(p.Reason)
+}
+
+// ImpersonationDTO only carries an access token, it is meant to be sent as a
+// bearer token so the cookies of the staff member are left untouched
+type ImpersonationDTO struct {
+	AccessToken string `json:"access_token"`
+	SessionID   string `json:"session_id"`
+	UserID      string `json:"user_id"`
+	ActorID     string `json:"actor_id"`
+	ExpiresAt   int64  `json:"expires_at"`
+}
@@ -0,0 +1,37 @@
+package auth
+
+// TokenIntrospectionDTO is the RFC 7662 introspection response. An inactive
+// token only carries active=false, callers are not told why.
+type TokenIntrospectionDTO struct {
+	Active    bool   `json:"active"`
+	TokenType string `json:"token_type,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	TokenID   string `json:"jti,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	// set for OAuth access tokens only
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+
+	// extensions, the session of the token and the current roles of first party tokens
+	SessionID string   `json:"sid,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	// set while impersonating, services should refuse sensitive actions such as payouts
+	Actor *TokenActor `json:"act,omitempty"`
+}
+
+// UserSummaryDTO is what internal services get to know about a user
+type UserSummaryDTO struct {
+	ID                  string   `json:"id"`
+	Name                string   `json:"name"`
+	Email               string   `json:"email"`
+	PhoneNumber         string   `json:"phone_number,omitempty"`
+	EmailVerified       bool     `json:"email_verified"`
+	PhoneNumberVerified bool     `json:"phone_number_verified"`
+	Roles               []string `json:"roles"`
+}
+
+type PermissionCheckDTO struct {
+	Allowed bool `json:"allowed"`
+}
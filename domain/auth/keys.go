@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	KEY_FILE_EXTENSION = ".pem"
+)
+
+// SigningKey is a single entry of the token key set, identified by its kid.
+// Keys without a private part are verification-only (retired keys that still
+// have live tokens out there).
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+type KeySet struct {
+	activeKeyID string
+	keys        map[string]*SigningKey
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+var (
+	keySet      *KeySet
+	keySetMutex sync.RWMutex
+)
+
+// SetKeySet replaces the key set used by GenerateJWT and ValidateToken
+func SetKeySet(ks *KeySet) {
+	keySetMutex.Lock()
+	defer keySetMutex.Unlock()
+
+	keySet = ks
+}
+
+func getKeySet() *KeySet {
+	keySetMutex.RLock()
+	defer keySetMutex.RUnlock()
+
+	return keySet
+}
+
+// GetJWKS returns the public keys of the current key set
+func GetJWKS() JWKSet {
+	ks := getKeySet()
+	if ks == nil {
+		return JWKSet{Keys: []JWK{}}
+	}
+
+	return ks.JWKS()
+}
+
+// LoadKeySet reads every <kid>.pem file in dir. A file may hold a PKCS#8 / PKCS#1
+// private key (signing + verification) or a PKIX public key (verification only).
+// The key named by activeKeyID is used to sign new tokens and must have a private part.
+func LoadKeySet(dir string, activeKeyID string) (*KeySet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+KEY_FILE_EXTENSION))
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{
+		activeKeyID: activeKeyID,
+		keys:        map[string]*SigningKey{},
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		kid := strings.TrimSuffix(filepath.Base(file), KEY_FILE_EXTENSION)
+		key, err := parsePEMKey(kid, content)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", kid, err)
+		}
+
+		ks.keys[kid] = key
+	}
+
+	activeKey, ok := ks.keys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found in %s", activeKeyID, dir)
+	}
+
+	if activeKey.PrivateKey == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeKeyID)
+	}
+
+	return ks, nil
+}
+
+// NewEphemeralKeySet generates an in-memory Ed25519 key, tokens signed with it
+// do not survive a restart. Meant for local development only.
+func NewEphemeralKeySet(kid string) (*KeySet, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeySet{
+		activeKeyID: kid,
+		keys: map[string]*SigningKey{
+			kid: {
+				ID:         kid,
+				Method:     jwt.SigningMethodEdDSA,
+				PrivateKey: privateKey,
+				PublicKey:  publicKey,
+			},
+		},
+	}, nil
+}
+
+func parsePEMKey(kid string, content []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+
+	return key, nil
+}
+
+func (ks *KeySet) ActiveKey() *SigningKey {
+	return ks.keys[ks.activeKeyID]
+}
+
+// keyFunc resolves the verification key from the token kid header
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, errors.New("missing kid header")
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid: %s", kid)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.PublicKey, nil
+}
+
+func (ks *KeySet) validMethods() []string {
+	return []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+}
+
+// JWKS returns the public part of every key, for /.well-known/jwks.json
+func (ks *KeySet) JWKS() JWKSet {
+	res := JWKSet{
+		Keys: []JWK{},
+	}
+
+	for _, key := range ks.keys {
+		jwk := JWK{
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+		}
+
+		switch publicKey := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+
+		res.Keys = append(res.Keys, jwk)
+	}
+
+	sort.Slice(res.Keys, func(i, j int) bool {
+		return res.Keys[i].Kid < res.Keys[j].Kid
+	})
+
+	return res
+}
@@ -6,5 +6,18 @@ import (
 
 type AuthMiddleware interface {
 	AuthMiddleware(next http.Handler) http.Handler
+	OptionalAuthMiddleware(next http.Handler) http.Handler
 	PublicMiddleware(next http.Handler) http.Handler
+	// CsrfMiddleware runs on the whole router, before any route
+	CsrfMiddleware(next http.Handler) http.Handler
+	// DeviceMiddleware runs on the whole router, after ClientInfoMiddleware
+	DeviceMiddleware(next http.Handler) http.Handler
+	// RequirePermission must run after AuthMiddleware, every listed permission is required
+	RequirePermission(permissions ...string) func(next http.Handler) http.Handler
+	// AllowApiKey must run before AuthMiddleware, only on routes whose every
+	// handler sits behind RequirePermission. API keys are refused anywhere else.
+	AllowApiKey(next http.Handler) http.Handler
+	// DenyImpersonation must run after AuthMiddleware, it guards the actions staff
+	// may never take on behalf of a user
+	DenyImpersonation(next http.Handler) http.Handler
 }
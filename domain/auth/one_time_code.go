@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"mini-wallet/utils"
+	"strings"
+	"time"
+)
+
+const (
+	ONE_TIME_CODE_PURPOSE_WHATSAPP_LOGIN = "whatsapp_login"
+	ONE_TIME_CODE_PURPOSE_EMAIL_LOGIN    = "email_login"
+	// the destination is the user id, the new email or phone number is the target
+	ONE_TIME_CODE_PURPOSE_EMAIL_CHANGE        = "email_change"
+	ONE_TIME_CODE_PURPOSE_PHONE_NUMBER_CHANGE = "phone_number_change"
+	// the destination is the known device that signed in, the user id is the target
+	ONE_TIME_CODE_PURPOSE_LOGIN_REPORT = "login_report"
+
+	ONE_TIME_CODE_LENGTH       = 6
+	ONE_TIME_CODE_LIFETIME     = 5 * time.Minute
+	ONE_TIME_CODE_MAX_ATTEMPTS = 5
+
+	MAGIC_LINK_SECRET_SIZE  = 32
+	MAGIC_LINK_LIFETIME     = 15 * time.Minute
+	MAGIC_LINK_NONCE_COOKIE = "magic_link_nonce"
+)
+
+// OneTimeCodeEntity is a short code sent to a destination (a phone number) for one
+// purpose. Only its hash is stored, a destination has at most one code per purpose.
+type OneTimeCodeEntity struct {
+	ID          string `bson:"id"`
+	Purpose     string `bson:"purpose"`
+	Destination string `bson:"destination"`
+	CodeHash    string `bson:"code_hash"`
+	Attempts    int    `bson:"attempts"`
+	// sha256 of a nonce kept in a cookie of the browser that asked for the code
+	BindingHash string `bson:"binding_hash,omitempty"`
+	// the value being verified when it is not the destination itself
+	Target    string `bson:"target,omitempty"`
+	CreatedAt int64  `bson:"created_at"`
+	ExpiredAt int64  `bson:"expired_at"`
+	// read by the TTL index, set from ExpiredAt by UpsertCode
+	PurgeAt time.Time `bson:"purge_at"`
+}
+
+// HashOneTimeCode binds the code to its record, the same digits hash differently for every code
+func HashOneTimeCode(id string, code string) string {
+	return utils.HashToken(id + ":" + code)
+}
+
+type WhatsAppCodeRequestDTO struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+func (p *WhatsAppCodeRequestDTO) Validate() error {
+	phoneNumber, err := utils.ValidatePhoneNumber(p.PhoneNumber)
+	if err != nil {
+		return err
+	}
+
+	p.PhoneNumber = *phoneNumber
+	return nil
+}
+
+type WhatsAppCodeVerificationDTO struct {
+	PhoneNumber string `json:"phone_number"`
+	Code        string `json:"code"`
+}
+
+func (p *WhatsAppCodeVerificationDTO) Validate() error {
+	err := utils.ValidateRequired(p.Code)
+	if err != nil {
+		return err
+	}
+
+	phoneNumber, err := utils.ValidatePhoneNumber(p.PhoneNumber)
+	if err != nil {
+		return err
+	}
+
+	p.PhoneNumber = *phoneNumber
+	return nil
+}
+
+type MagicLinkRequestDTO struct {
+	Email string `json:"email"`
+}
+
+func (p *MagicLinkRequestDTO) Validate() error {
+	p.Email = strings.ToLower(strings.TrimSpace(p.Email))
+	return utils.ValidateEmail(p.Email)
+}
+
+// MagicLinkVerificationDTO carries the token of the link, <code id>.<secret>
+type MagicLinkVerificationDTO struct {
+	Token string `json:"token"`
+	Nonce string `json:"-"`
+}
+
+func (p *MagicLinkVerificationDTO) Validate() error {
+	return utils.ValidateRequired(p.Token)
+}
+
+type OneTimeCodeRepository interface {
+	// EnsureExpiryIndex creates the TTL index of the codes and removes the expired
+	// codes stored before it
+	EnsureExpiryIndex(ctx context.Context, now int64) (err error)
+	// UpsertCode replaces the code of the same purpose & destination
+	UpsertCode(ctx context.Context, code OneTimeCodeEntity) (err error)
+	GetCode(ctx context.Context, purpose string, destination string, now int64) (res *OneTimeCodeEntity, err error)
+	GetCodeByID(ctx context.Context, id string, purpose string, now int64) (res *OneTimeCodeEntity, err error)
+	// ClaimAttempt counts a guess before it is compared and returns the new total,
+	// false once ONE_TIME_CODE_MAX_ATTEMPTS guesses were claimed or the code is gone
+	ClaimAttempt(ctx context.Context, id string) (claimed bool, attempts int, err error)
+	// DeleteCode consumes the code, false means it was consumed or replaced meanwhile
+	DeleteCode(ctx context.Context, id string) (deleted bool, err error)
+}
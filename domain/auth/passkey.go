@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mini-wallet/utils"
+	"time"
+)
+
+const (
+	PASSKEY_CEREMONY_REGISTRATION = "registration"
+	PASSKEY_CEREMONY_LOGIN        = "login"
+
+	PASSKEY_RP_DISPLAY_NAME   = "Sebia"
+	PASSKEY_CEREMONY_LIFETIME = 5 * time.Minute
+	PASSKEY_DEFAULT_NAME      = "Passkey"
+	PASSKEY_MAX_NAME_LENGTH   = 64
+)
+
+// PasskeyEntity is a WebAuthn credential registered by a user. CredentialID is
+// the raw id chosen by the authenticator, ID is ours and used in routes.
+type PasskeyEntity struct {
+	ID     string `bson:"id"`
+	UserID string `bson:"user_id"`
+	Name   string `bson:"name"`
+
+	CredentialID    []byte   `bson:"credential_id"`
+	PublicKey       []byte   `bson:"public_key"`
+	AttestationType string   `bson:"attestation_type"`
+	Transports      []string `bson:"transports"`
+	AAGUID          []byte   `bson:"aaguid"`
+	SignCount       uint32   `bson:"sign_count"`
+	UserVerified    bool     `bson:"user_verified"`
+	BackupEligible  bool     `bson:"backup_eligible"`
+	BackupState     bool     `bson:"backup_state"`
+
+	CreatedAt  int64  `bson:"created_at"`
+	LastUsedAt *int64 `bson:"last_used_at"`
+}
+
+func (p *PasskeyEntity) ToPasskeyDTO() PasskeyDTO {
+	return PasskeyDTO{
+		ID:         p.ID,
+		Name:       p.Name,
+		Synced:     p.BackupEligible,
+		CreatedAt:  p.CreatedAt,
+		LastUsedAt: p.LastUsedAt,
+	}
+}
+
+// PasskeyCeremonyEntity keeps the server side of a WebAuthn ceremony (the
+// challenge) between its begin and finish calls. It is single use.
+type PasskeyCeremonyEntity struct {
+	ID     string  `bson:"id"`
+	Type   string  `bson:"type"`
+	UserID *string `bson:"user_id"`
+	// webauthn.SessionData, json encoded
+	SessionData []byte `bson:"session_data"`
+	ExpiredAt   int64  `bson:"expired_at"`
+}
+
+type PasskeyDTO struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Synced     bool   `json:"synced"`
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt *int64 `json:"last_used_at"`
+}
+
+// PasskeyCeremonyDTO is returned by the begin calls, Options is passed as is to
+// navigator.credentials.create() / get()
+type PasskeyCeremonyDTO struct {
+	CeremonyID string      `json:"ceremony_id"`
+	Options    interface{} `json:"options"`
+}
+
+type PasskeyRegistrationDTO struct {
+	CeremonyID string          `json:"ceremony_id"`
+	Name       string          `json:"name"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+func (p *PasskeyRegistrationDTO) Validate() (err error) {
+	err = utils.ValidateRequired(p.CeremonyID)
+	if err != nil {
+		return err
+	}
+
+	if len(p.Credential) == 0 {
+		return errors.New("credential is required")
+	}
+
+	if len(p.Name) > PASSKEY_MAX_NAME_LENGTH {
+		return errors.New("name is too long")
+	}
+
+	if p.Name == "" {
+		p.Name = PASSKEY_DEFAULT_NAME
+	}
+
+	return nil
+}
+
+type PasskeyAuthenticationDTO struct {
+	CeremonyID string          `json:"ceremony_id"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+func (p *PasskeyAuthenticationDTO) Validate() (err error) {
+	err = utils.ValidateRequired(p.CeremonyID)
+	if err != nil {
+		return err
+	}
+
+	if len(p.Credential) == 0 {
+		return errors.New("credential is required")
+	}
+
+	return nil
+}
+
+type PasskeyRepository interface {
+	InsertPasskey(ctx context.Context, passkey PasskeyEntity) (err error)
+	GetPasskeysByUserID(ctx context.Context, userID string) (res []PasskeyEntity, err error)
+	GetPasskeyByCredentialID(ctx context.Context, credentialID []byte) (res *PasskeyEntity, err error)
+	UpdatePasskeyUsage(ctx context.Context, id string, signCount uint32, backupState bool, now int64) (err error)
+	DeletePasskey(ctx context.Context, userID string, id string) (deleted bool, err error)
+	DeletePasskeysByUserID(ctx context.Context, userID string) (err error)
+
+	InsertCeremony(ctx context.Context, ceremony PasskeyCeremonyEntity) (err error)
+	// TakeCeremony returns and deletes an unexpired ceremony of the given type
+	TakeCeremony(ctx context.Context, id string, ceremonyType string, now int64) (res *PasskeyCeremonyEntity, err error)
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"mini-wallet/domain/user"
+	"slices"
+)
+
+const (
+	// every user is a guest, the other roles are granted on top of it
+	ROLE_GUEST      = "guest"
+	ROLE_HOST       = "host"
+	ROLE_HOST_STAFF = "host-staff"
+	ROLE_AFFILIATE  = "affiliate"
+	ROLE_SUPPORT    = "support"
+	ROLE_ADMIN      = "admin"
+)
+
+const (
+	PERMISSION_BUSINESS_CREATE = "business:create"
+	PERMISSION_BUSINESS_READ   = "business:read"
+	PERMISSION_SERVICE_READ    = "service:read"
+	PERMISSION_SERVICE_WRITE   = "service:write"
+	PERMISSION_INQUIRY_READ    = "inquiry:read"
+	PERMISSION_REVIEW_CREATE   = "review:create"
+	PERMISSION_AFFILIATE_READ  = "affiliate:read"
+	PERMISSION_USER_READ       = "user:read"
+	PERMISSION_ROLE_MANAGE     = "role:manage"
+	// delete any account right away, without the grace period
+	PERMISSION_USER_DELETE = "user:delete"
+	// approve or reject business and affiliate applications
+	PERMISSION_APPLICATION_REVIEW = "application:review"
+	PERMISSION_AUDIT_READ         = "audit:read"
+	// keys of the service accounts of an owned business
+	PERMISSION_API_KEY_MANAGE = "api-key:manage"
+	// any service account, internal ones included
+	PERMISSION_SERVICE_ACCOUNT_MANAGE = "service-account:manage"
+	// no role grants it, only admins may act as another user
+	PERMISSION_USER_IMPERSONATE = "user:impersonate"
+
+	// granted to admins only, matches every permission
+	PERMISSION_ALL = "*"
+)
+
+const FORBIDDEN_MESSAGE = "Anda tidak memiliki akses"
+
+var ErrUnknownRole = errors.New("unknown role")
+
+// rolePermissions is the only place permissions are granted. Ownership (which
+// business a host may edit) is still checked by the usecases.
+var rolePermissions = map[string][]string{
+	ROLE_GUEST: {
+		PERMISSION_BUSINESS_CREATE,
+		PERMISSION_BUSINESS_READ,
+		PERMISSION_REVIEW_CREATE,
+	},
+	ROLE_HOST: {
+		PERMISSION_BUSINESS_READ,
+		PERMISSION_SERVICE_READ,
+		PERMISSION_SERVICE_WRITE,
+		PERMISSION_INQUIRY_READ,
+		PERMISSION_API_KEY_MANAGE,
+	},
+	ROLE_HOST_STAFF: {
+		PERMISSION_BUSINESS_READ,
+		PERMISSION_SERVICE_READ,
+		PERMISSION_INQUIRY_READ,
+	},
+	ROLE_AFFILIATE: {
+		PERMISSION_AFFILIATE_READ,
+	},
+	ROLE_SUPPORT: {
+		PERMISSION_BUSINESS_READ,
+		PERMISSION_SERVICE_READ,
+		PERMISSION_INQUIRY_READ,
+		PERMISSION_USER_READ,
+	},
+	ROLE_ADMIN: {
+		PERMISSION_ALL,
+	},
+}
+
+type RolesContext struct {
+}
+
+// ScopesContext holds the scopes of the API key a request is made with, it is
+// not set for users
+type ScopesContext struct {
+}
+
+// ServiceAccountContext holds the id of the service account a request is made
+// with, it is not set for users
+type ServiceAccountContext struct {
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission tells whether any of the roles grants the permission
+func HasPermission(roles []string, permission string) bool {
+	for _, role := range roles {
+		permissions := rolePermissions[role]
+		if slices.Contains(permissions, PERMISSION_ALL) || slices.Contains(permissions, permission) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetUserRoles is guest followed by the granted roles, the roles of the access token
+func GetUserRoles(userEntity user.UserEntity) []string {
+	roles := []string{ROLE_GUEST}
+	for _, role := range userEntity.Roles {
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles
+}
+
+// GetRoles returns the roles set by AuthMiddleware, nil for anonymous requests
+func GetRoles(ctx context.Context) []string {
+	roles, _ := ctx.Value(RolesContext{}).([]string)
+	return roles
+}
+
+// GetScopes returns the scopes of the API key of the request, limited is false
+// for users, they are only limited by their roles
+func GetScopes(ctx context.Context) (scopes []string, limited bool) {
+	scopes, limited = ctx.Value(ScopesContext{}).([]string)
+	return scopes, limited
+}
+
+// IsPermitted tells whether the request may use the permission: a role has to
+// grant it and, for API keys, it has to be one of the scopes of the key
+func IsPermitted(ctx context.Context, permission string) bool {
+	if !HasPermission(GetRoles(ctx), permission) {
+		return false
+	}
+
+	scopes, limited := GetScopes(ctx)
+	return !limited || slices.Contains(scopes, permission)
+}
+
+type RoleAssignmentDTO struct {
+	Roles []string `json:"roles"`
+}
+
+func (p *RoleAssignmentDTO) Validate() error {
+	for _, role := range p.Roles {
+		if !IsValidRole(role) {
+			return ErrUnknownRole
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	SESSION_REVOKED_LOGOUT          = "logout"
+	SESSION_REVOKED_REFRESH_REUSE   = "refresh_token_reuse"
+	SESSION_REVOKED_BY_USER         = "revoked_by_user"
+	SESSION_REVOKED_ACCOUNT_DELETED = "account_deleted"
+	SESSION_REVOKED_PASSWORD_CHANGE = "password_changed"
+	SESSION_REVOKED_PASSWORD_RESET  = "password_reset"
+	SESSION_REVOKED_IMPERSONATION   = "impersonation_ended"
+	SESSION_REVOKED_LOGIN_REPORTED  = "login_reported"
+
+	// last_seen_at is only written when older than this, not on every request
+	SESSION_LAST_SEEN_INTERVAL = 5 * 60
+	// concurrent requests of a browser send the same refresh token, the one just
+	// rotated is still accepted for this many seconds
+	REFRESH_TOKEN_REUSE_GRACE = 10
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrSessionRevoked      = errors.New("session revoked")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+// SessionEntity is a refresh token family. Only the refresh token whose jti
+// equals RefreshTokenID may be exchanged, every exchange rotates it.
+type SessionEntity struct {
+	ID             string `bson:"id"`
+	UserID         string `bson:"user_id"`
+	RefreshTokenID string `bson:"refresh_token_id"`
+	// the refresh token rotated last, accepted until RotatedAt + REFRESH_TOKEN_REUSE_GRACE
+	PreviousRefreshTokenID string `bson:"previous_refresh_token_id,omitempty"`
+	RotatedAt              int64  `bson:"rotated_at,omitempty"`
+
+	UserAgent string `bson:"user_agent"`
+	IPAddress string `bson:"ip_address"`
+
+	CreatedAt     int64   `bson:"created_at"`
+	UpdatedAt     int64   `bson:"updated_at"`
+	LastSeenAt    int64   `bson:"last_seen_at"`
+	ExpiredAt     int64   `bson:"expired_at"`
+	RevokedAt     *int64  `bson:"revoked_at"`
+	RevokedReason *string `bson:"revoked_reason"`
+	// set when the roles of the user change, older access tokens are refreshed
+	ClaimsChangedAt int64 `bson:"claims_changed_at"`
+	// signs the CSRF tokens of the session, empty for sessions older than CSRF protection
+	CsrfSecret string `bson:"csrf_secret,omitempty"`
+	// set on impersonation sessions, the staff member acting as the user
+	ImpersonatorID string `bson:"impersonator_id,omitempty"`
+}
+
+func (p *SessionEntity) IsActive(now int64) bool {
+	return p.RevokedAt == nil && p.ExpiredAt > now
+}
+
+func (p *SessionEntity) ToSessionDTO(currentSessionID string) SessionDTO {
+	return SessionDTO{
+		ID:         p.ID,
+		UserAgent:  p.UserAgent,
+		IPAddress:  p.IPAddress,
+		CreatedAt:  p.CreatedAt,
+		LastSeenAt: p.LastSeenAt,
+		Current:    p.ID == currentSessionID,
+		// the user sees that staff is acting on their account
+		Impersonated: p.ImpersonatorID != "",
+	}
+}
+
+type SessionDTO struct {
+	ID           string `json:"id"`
+	UserAgent    string `json:"user_agent"`
+	IPAddress    string `json:"ip_address"`
+	CreatedAt    int64  `json:"created_at"`
+	LastSeenAt   int64  `json:"last_seen_at"`
+	Current      bool   `json:"current"`
+	Impersonated bool   `json:"impersonated"`
+}
+
+type SessionRepository interface {
+	InsertSession(ctx context.Context, session SessionEntity) (err error)
+	GetSessionByID(ctx context.Context, id string) (res *SessionEntity, err error)
+	// RotateRefreshToken swaps the refresh token id only if currentTokenID is still the latest one
+	RotateRefreshToken(ctx context.Context, id string, currentTokenID string, newTokenID string, now int64) (rotated bool, err error)
+	RevokeSession(ctx context.Context, id string, reason string, now int64) (err error)
+	// RevokeUserSessions revokes every active session of the user except exceptSessionID (may be empty)
+	RevokeUserSessions(ctx context.Context, userID string, exceptSessionID string, reason string, now int64) (err error)
+	GetActiveSessionsByUserID(ctx context.Context, userID string, now int64) (res []SessionEntity, err error)
+	TouchSession(ctx context.Context, id string, clientInfo ClientInfo, now int64) (err error)
+	// MarkClaimsChanged makes every active session of the user refresh its access token
+	MarkClaimsChanged(ctx context.Context, userID string, now int64) (err error)
+	// SetCsrfSecret only sets the secret of a session that has none yet
+	SetCsrfSecret(ctx context.Context, id string, secret string) (err error)
+}
@@ -1,42 +1,105 @@
 package auth
 
 import (
-	"fmt"
+	"errors"
 	"mini-wallet/domain/user"
-	"mini-wallet/utils"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 const (
+	TOKEN_TYPE_ACCESS   = "ACCESS"
 	TOKEN_TYPE_REFRESH  = "REFRESH"
 	ERROR_EXPIRED_TOKEN = 1
 	ERROR_INVALID_TOKEN = 2
+
+	// issued after the password step when 2FA is enabled, only accepted by /auth/login/2fa
+	TOKEN_TYPE_TWO_FACTOR_CHALLENGE = "2FA_CHALLENGE"
 )
 
-var secretKey = []byte("your-256-bit-secret")
+const (
+	ACCESS_TOKEN_LIFETIME  = 1 * time.Hour
+	REFRESH_TOKEN_LIFETIME = 30 * 24 * time.Hour
+)
 
-func GenerateJWT(user user.UserEntity, tokenType string) (string, error) {
-	expirationTime := time.Now().Add(1 * time.Hour)
-	if tokenType == TOKEN_TYPE_REFRESH {
-		expirationTime = time.Now().Add(30 * 24 * time.Hour)
+// tokenIssuer is the iss of every token, it is also the OpenID Connect issuer
+var tokenIssuer = "https://dev.sebia.id"
+
+func SetIssuer(issuer string) {
+	tokenIssuer = issuer
+}
+
+func GetIssuer() string {
+	return tokenIssuer
+}
+
+// GenerateJWT issues a token bound to a session, tokenID becomes the jti
+func GenerateJWT(user user.UserEntity, tokenType string, sessionID string, tokenID string) (string, error) {
+	expirationTime := time.Now().Add(ACCESS_TOKEN_LIFETIME)
+	switch tokenType {
+	case TOKEN_TYPE_REFRESH:
+		expirationTime = time.Now().Add(REFRESH_TOKEN_LIFETIME)
+	case TOKEN_TYPE_TWO_FACTOR_CHALLENGE:
+		expirationTime = time.Now().Add(TWO_FACTOR_CHALLENGE_LIFETIME)
 	}
 
 	claims := AcessTokenClaims{
-		Name:   user.Name,
-		UserID: user.UID,
+		Name:      user.Name,
+		UserID:    user.UID,
+		SessionID: sessionID,
+		TokenType: tokenType,
+		Roles:     GetUserRoles(user),
 		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "https://dev.sebia.id",
+			ID:        tokenID,
+			Issuer:    tokenIssuer,
 			Subject:   user.UID,
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return SignClaims(claims)
+}
 
-	tokenString, err := token.SignedString(secretKey)
+// GenerateImpersonationJWT issues an access token of the subject that names the
+// actor in the act claim, it expires with the impersonation session
+func GenerateImpersonationJWT(subject user.UserEntity, actor user.UserEntity, sessionID string, tokenID string, expiresAt time.Time) (string, error) {
+	claims := AcessTokenClaims{
+		Name:      subject.Name,
+		UserID:    subject.UID,
+		SessionID: sessionID,
+		TokenType: TOKEN_TYPE_ACCESS,
+		Roles:     GetUserRoles(subject),
+		Actor: &TokenActor{
+			Subject: actor.UID,
+			Name:    actor.Name,
+		},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Issuer:    tokenIssuer,
+			Subject:   subject.UID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return SignClaims(claims)
+}
+
+// SignClaims signs any claims with the active key, the kid header lets
+// verifiers pick the right public key from the JWKS
+func SignClaims(claims jwt.Claims) (string, error) {
+	ks := getKeySet()
+	if ks == nil {
+		return "", errors.New("token key set is not initialized")
+	}
+
+	signingKey := ks.ActiveKey()
+	token := jwt.NewWithClaims(signingKey.Method, claims)
+	token.Header["kid"] = signingKey.ID
+
+	tokenString, err := token.SignedString(signingKey.PrivateKey)
 	if err != nil {
 		return "", err
 	}
@@ -45,50 +108,42 @@ func GenerateJWT(user user.UserEntity, tokenType string) (string, error) {
 }
 
 func ExtractUserIDFromToken(tokenString string) (string, int) {
-	// Parse the token
-	token, _ := jwt.ParseWithClaims(tokenString, &AcessTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return secretKey, nil
-	})
-
-	// Validate the token and check the claims
-	if token == nil || token.Claims.(*AcessTokenClaims) == nil {
+	claims, status := ValidateToken(tokenString)
+	if status == ERROR_INVALID_TOKEN || claims.TokenType != TOKEN_TYPE_ACCESS {
 		return "", ERROR_INVALID_TOKEN
 	}
 
-	if claims, ok := token.Claims.(*AcessTokenClaims); ok {
-		now, _ := utils.GetJktTime()
-		if claims.ExpiresAt.Time.Before(*now) {
-			return claims.Subject, ERROR_EXPIRED_TOKEN
-		}
-		return claims.Subject, 0
-	} else {
-		return "", ERROR_INVALID_TOKEN
-	}
+	return claims.Subject, status
 }
 
 func ValidateToken(tokenString string) (*AcessTokenClaims, int) {
-	token, _ := jwt.ParseWithClaims(tokenString, &AcessTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return secretKey, nil
-	})
-
-	if token == nil || token.Claims.(*AcessTokenClaims) == nil {
+	claims := &AcessTokenClaims{}
+	status := ParseClaims(tokenString, claims)
+	if status == ERROR_INVALID_TOKEN {
 		return nil, ERROR_INVALID_TOKEN
 	}
 
-	if claims, ok := token.Claims.(*AcessTokenClaims); ok {
-		now, _ := utils.GetJktTime()
-		if claims.ExpiresAt.Time.Before(*now) {
-			return claims, ERROR_EXPIRED_TOKEN
+	return claims, status
+}
+
+// ParseClaims verifies a token issued by this service and decodes it into claims.
+// The signature is verified before expiry, so expired claims are still trustworthy.
+func ParseClaims(tokenString string, claims jwt.Claims) int {
+	ks := getKeySet()
+	if ks == nil {
+		return ERROR_INVALID_TOKEN
+	}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, ks.keyFunc,
+		jwt.WithValidMethods(ks.validMethods()),
+		jwt.WithIssuer(tokenIssuer),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return ERROR_EXPIRED_TOKEN
 		}
-		return claims, 0
+		return ERROR_INVALID_TOKEN
 	}
 
-	return nil, ERROR_INVALID_TOKEN
+	return 0
 }
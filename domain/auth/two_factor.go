@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"errors"
+	"mini-wallet/utils"
+	"time"
+)
+
+const (
+	TWO_FACTOR_ISSUER              = "Sebia"
+	TWO_FACTOR_RECOVERY_CODE_COUNT = 10
+
+	TWO_FACTOR_CHALLENGE_LIFETIME = 5 * time.Minute
+)
+
+var (
+	ErrInvalidChallengeToken = errors.New("invalid two factor challenge token")
+)
+
+type TwoFactorEnrollmentDTO struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TwoFactorRecoveryCodesDTO struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorConfirmationDTO struct {
+	Code string `json:"code"`
+}
+
+func (p *TwoFactorConfirmationDTO) Validate() (err error) {
+	err = utils.ValidateRequired(p.Code)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type TwoFactorDisableDTO struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (p *TwoFactorDisableDTO) Validate() (err error) {
+	err = utils.ValidateRequired(p.Password)
+	if err != nil {
+		return err
+	}
+
+	if p.Code == "" && p.RecoveryCode == "" {
+		return errors.New("code or recovery_code is required")
+	}
+
+	return nil
+}
+
+// TwoFactorAuthenticationDTO is the second login step, either Code or RecoveryCode must be filled
+type TwoFactorAuthenticationDTO struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+func (p *TwoFactorAuthenticationDTO) Validate() (err error) {
+	err = utils.ValidateRequired(p.ChallengeToken)
+	if err != nil {
+		return err
+	}
+
+	if p.Code == "" && p.RecoveryCode == "" {
+		return errors.New("code or recovery_code is required")
+	}
+
+	return nil
+}
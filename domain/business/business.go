@@ -11,6 +11,12 @@ import (
 	"github.com/oklog/ulid/v2"
 )
 
+const (
+	BUSINESS_STATUS_PENDING  = 1
+	BUSINESS_STATUS_APPROVED = 2
+	BUSINESS_STATUS_REJECTED = 3
+)
+
 type BusinessEntity struct {
 	ID                 string `bson:"id"`
 	Name               string `bson:"name"`
@@ -26,6 +32,24 @@ type BusinessEntity struct {
 	Status    int64  `bson:"status"`
 	CreatedAt int64  `bson:"created_at"`
 	UpdatedAt int64  `bson:"updated_at"`
+
+	// set by the moderation decision, see moderation.ModerationDecisionEntity
+	RejectionReason *string `bson:"rejection_reason,omitempty"`
+	ReviewedAt      *int64  `bson:"reviewed_at,omitempty"`
+}
+
+// GetOwnerStatus is what the owner is shown: pending, rejected or, once approved,
+// the business id
+func (p *BusinessEntity) GetOwnerStatus() string {
+	if p.Status == BUSINESS_STATUS_PENDING {
+		return "pending"
+	}
+
+	if p.Status == BUSINESS_STATUS_REJECTED {
+		return "rejected"
+	}
+
+	return p.ID
 }
 
 func (p *BusinessCreationDTO) ToBusinessEntity() BusinessEntity {
@@ -52,7 +76,7 @@ func (p *BusinessCreationDTO) ToBusinessEntity() BusinessEntity {
 		ProvinceID:         p.ProvinceID,
 		DistrictID:         p.DistrictID,
 		UserID:             p.UserID,
-		Status:             1,
+		Status:             BUSINESS_STATUS_PENDING,
 		CreatedAt:          now.Unix(),
 		UpdatedAt:          now.Unix(),
 	}
@@ -161,4 +185,16 @@ type BusinessRepository interface {
 	GetBusinessByUserId(ctx context.Context, userID string) (res *BusinessEntity, err error)
 	GetBusinessById(ctx context.Context, id string) (res *BusinessEntity, err error)
 	GetBusinessByHandle(ctx context.Context, slug string) (res *BusinessEntity, err error)
+	GetBusinesses(ctx context.Context, filter BusinessFilter) (res []BusinessEntity, total int64, err error)
+	// UpdateBusinessStatus only moves a business that is still in fromStatus, false means it was decided meanwhile
+	UpdateBusinessStatus(ctx context.Context, id string, fromStatus int64, toStatus int64, rejectionReason *string, now int64) (updated bool, err error)
+}
+
+// BusinessFilter lists businesses newest first, zero values are not filtered on
+type BusinessFilter struct {
+	Status     int64
+	ProvinceID int64
+	CityID     int64
+	Page       int
+	Size       int
 }
@@ -3,6 +3,7 @@ package response
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 )
 
 const (
@@ -15,6 +16,8 @@ const (
 	STATUS_FORBIDDEN    = "forbidden"
 	STATUS_UNAUTHORIZED = "unauthorized"
 
+	STATUS_TOO_MANY_REQUESTS = "too many requests"
+
 	ERROR_WALLET_DISABLED       = "wallet disabled"
 	ERROR_WALLET_NOT_FOUND      = "wallet not found"
 	ERROR_INSSUFICIENT_FUND     = "insufficient fund"
@@ -33,6 +36,7 @@ type Response[T any] struct {
 	StatusCode int                 `json:"-"`
 	Writer     http.ResponseWriter `json:"-"`
 	Cookies    []*http.Cookie      `json:"-"`
+	RetryAfter int                 `json:"-"`
 }
 
 func (res *Response[T]) Success(data T) {
@@ -96,6 +100,14 @@ func (res *Response[T]) Unauthorized(msg string) {
 	res.Message = &msg
 }
 
+// TooManyRequests also sends a Retry-After header, in seconds
+func (res *Response[T]) TooManyRequests(msg string, retryAfter int) {
+	res.Status = STATUS_TOO_MANY_REQUESTS
+	res.StatusCode = http.StatusTooManyRequests
+	res.Message = &msg
+	res.RetryAfter = retryAfter
+}
+
 func (res *Response[T]) Redirect(msg string) {
 	res.Status = STATUS_REDIRECT
 	res.StatusCode = http.StatusTemporaryRedirect
@@ -108,6 +120,10 @@ func (res *Response[T]) WriteResponse() {
 		}
 	}
 
+	if res.RetryAfter > 0 {
+		res.Writer.Header().Set("Retry-After", strconv.Itoa(res.RetryAfter))
+	}
+
 	res.Writer.Header().Set("Content-Type", "application/json")
 	res.Writer.WriteHeader(res.StatusCode)
 	json.NewEncoder(res.Writer).Encode(res)
@@ -2,15 +2,20 @@ package domain
 
 import (
 	"mini-wallet/domain/affiliate"
+	"mini-wallet/domain/audit"
 	"mini-wallet/domain/auth"
 	"mini-wallet/domain/booking"
 	"mini-wallet/domain/business"
 	"mini-wallet/domain/file"
 	"mini-wallet/domain/inquiry"
 	"mini-wallet/domain/locations"
+	"mini-wallet/domain/moderation"
+	"mini-wallet/domain/oauth"
 	"mini-wallet/domain/payment"
+	"mini-wallet/domain/privacy"
 	"mini-wallet/domain/review"
 	"mini-wallet/domain/seo"
+	"mini-wallet/domain/serviceaccount"
 	"mini-wallet/domain/services"
 	"mini-wallet/domain/user"
 
@@ -24,9 +29,18 @@ import (
 type Repositories struct {
 	BaseRepository           BaseRepository
 	UserRepository           user.UserRepository
+	SessionRepository        auth.SessionRepository
+	PasskeyRepository        auth.PasskeyRepository
+	OneTimeCodeRepository    auth.OneTimeCodeRepository
+	KnownDeviceRepository    auth.KnownDeviceRepository
+	OAuthRepository          oauth.OAuthRepository
 	LocationRepository       locations.LocationRepository
 	BusinessRepository       business.BusinessRepository
 	AffiliateRepository      affiliate.AffiliateRepository
+	ModerationRepository     moderation.ModerationRepository
+	AuditRepository          audit.AuditRepository
+	PrivacyRepository        privacy.PrivacyRepository
+	ServiceAccountRepository serviceaccount.ServiceAccountRepository
 	ServicesRepository       services.ServicesRepository
 	ServicesSearchRepository services.ServicesSearchRepository
 
@@ -37,17 +51,23 @@ type Repositories struct {
 }
 
 type Usecases struct {
-	AuthUsecase      auth.AuthUsecase
-	BusinessUsecase  business.BusinessUsecase
-	AffiliateUsecase affiliate.AffiliateUsecase
-	FileUsecase      file.FileUsecase
-	LocationUsecase  locations.LocationUsecase
-	ServicesUsecase  services.ServicesUsecase
-	InquiryUsecase   inquiry.InquiryUsecase
-	PaymentUsecase   payment.PaymentUsecase
-	BookingUsecase   booking.BookingUsecase
-	ReviewUsecase    review.ReviewUsecase
-	SEOUsecase       seo.SEOUsecase
+	AuthUsecase           auth.AuthUsecase
+	UserUsecase           user.UserUsecase
+	OAuthUsecase          oauth.OAuthUsecase
+	BusinessUsecase       business.BusinessUsecase
+	AffiliateUsecase      affiliate.AffiliateUsecase
+	ModerationUsecase     moderation.ModerationUsecase
+	AuditUsecase          audit.AuditUsecase
+	PrivacyUsecase        privacy.PrivacyUsecase
+	ServiceAccountUsecase serviceaccount.ServiceAccountUsecase
+	FileUsecase           file.FileUsecase
+	LocationUsecase       locations.LocationUsecase
+	ServicesUsecase       services.ServicesUsecase
+	InquiryUsecase        inquiry.InquiryUsecase
+	PaymentUsecase        payment.PaymentUsecase
+	BookingUsecase        booking.BookingUsecase
+	ReviewUsecase         review.ReviewUsecase
+	SEOUsecase            seo.SEOUsecase
 }
 
 type Infrastructure struct {
@@ -55,6 +75,8 @@ type Infrastructure struct {
 	NotificationService integration.NotificationService
 	PaymentService      infrastructure.Payment
 	MesageProducer      infrastructure.MessagingProducer
+	Cache               infrastructure.Cache
+	IdentityProviders   map[string]integration.IdentityProvider
 }
 
 type RepositoryParam struct {
@@ -210,4 +210,8 @@ type InquiryRepository interface {
 	UpdateInquiryWithTx(ctx context.Context, txSession *mongo.SessionContext, req InquiryEntity) (err error)
 
 	GetInquiryById(ctx context.Context, id string) (res *InquiryEntity, err error)
+	// GetInquiriesOfUser returns the inquiries made by the user or with their email as a guest
+	GetInquiriesOfUser(ctx context.Context, userID string, email string) (res []InquiryEntity, err error)
+	// AnonymizeInquiries clears the contact of the inquiries of GetInquiriesOfUser, the bookings themselves stay
+	AnonymizeInquiries(ctx context.Context, userID string, email string, anonymizedName string) (err error)
 }
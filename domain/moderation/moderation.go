@@ -0,0 +1,153 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/utils"
+)
+
+const (
+	APPLICATION_TYPE_BUSINESS  = "business"
+	APPLICATION_TYPE_AFFILIATE = "affiliate"
+
+	APPLICATION_STATUS_PENDING  = "pending"
+	APPLICATION_STATUS_APPROVED = "approved"
+	APPLICATION_STATUS_REJECTED = "rejected"
+
+	DECISION_APPROVE = "approve"
+	DECISION_REJECT  = "reject"
+
+	MAX_PAGE_SIZE = 100
+	// the requirement file lives in a private bucket, admins get a short lived link
+	REQUIREMENT_FILE_URL_LIFETIME_MINUTES = 15
+)
+
+var (
+	ErrUnknownApplicationType   = errors.New("jenis pengajuan tidak dikenal")
+	ErrUnknownApplicationStatus = errors.New("status pengajuan tidak dikenal")
+	ErrRejectionReasonRequired  = errors.New("alasan penolakan harus diisi")
+)
+
+// ModerationDecisionEntity records every approval and rejection, it is never updated
+type ModerationDecisionEntity struct {
+	ID              string  `bson:"id"`
+	ApplicationType string  `bson:"application_type"`
+	ApplicationID   string  `bson:"application_id"`
+	ApplicantID     string  `bson:"applicant_id"`
+	ModeratorID     string  `bson:"moderator_id"`
+	Decision        string  `bson:"decision"`
+	Reason          *string `bson:"reason"`
+	CreatedAt       int64   `bson:"created_at"`
+}
+
+func (p *ModerationDecisionEntity) ToModerationDecisionDTO() ModerationDecisionDTO {
+	return ModerationDecisionDTO{
+		ModeratorID: p.ModeratorID,
+		Decision:    p.Decision,
+		Reason:      p.Reason,
+		CreatedAt:   p.CreatedAt,
+	}
+}
+
+type ModerationDecisionDTO struct {
+	ModeratorID string  `json:"moderator_id"`
+	Decision    string  `json:"decision"`
+	Reason      *string `json:"reason,omitempty"`
+	CreatedAt   int64   `json:"created_at"`
+}
+
+// GetApplicationsRequest is decoded from the query string
+type GetApplicationsRequest struct {
+	Type       string `json:"type"`
+	Status     string `json:"status"`
+	ProvinceID int64  `json:"province_id" schema:"province_id"`
+	CityID     int64  `json:"city_id" schema:"city_id"`
+	Page       int    `json:"page"`
+	Size       int    `json:"size"`
+}
+
+func (p *GetApplicationsRequest) Validate() error {
+	if p.Type == "" {
+		p.Type = APPLICATION_TYPE_BUSINESS
+	}
+
+	if !IsValidApplicationType(p.Type) {
+		return ErrUnknownApplicationType
+	}
+
+	if p.Status == "" {
+		p.Status = APPLICATION_STATUS_PENDING
+	}
+
+	if p.Status != APPLICATION_STATUS_PENDING && p.Status != APPLICATION_STATUS_APPROVED && p.Status != APPLICATION_STATUS_REJECTED {
+		return ErrUnknownApplicationStatus
+	}
+
+	err := utils.ValidateRequiredInt(p.Page)
+	if err != nil {
+		return err
+	}
+
+	err = utils.ValidateRequiredInt(p.Size)
+	if err != nil {
+		return err
+	}
+
+	if p.Size > MAX_PAGE_SIZE {
+		p.Size = MAX_PAGE_SIZE
+	}
+
+	return nil
+}
+
+func IsValidApplicationType(applicationType string) bool {
+	return applicationType == APPLICATION_TYPE_BUSINESS || applicationType == APPLICATION_TYPE_AFFILIATE
+}
+
+// ApplicationDTO is a business or an affiliate application, Details holds the
+// submitted form of either kind
+type ApplicationDTO struct {
+	Type               string                  `json:"type"`
+	ID                 string                  `json:"id"`
+	ApplicantID        string                  `json:"applicant_id"`
+	ApplicantName      string                  `json:"applicant_name,omitempty"`
+	Status             string                  `json:"status"`
+	RequirementFileUrl *string                 `json:"requirement_file_url,omitempty"`
+	RejectionReason    *string                 `json:"rejection_reason,omitempty"`
+	Details            interface{}             `json:"details"`
+	CreatedAt          int64                   `json:"created_at"`
+	ReviewedAt         *int64                  `json:"reviewed_at,omitempty"`
+	Decisions          []ModerationDecisionDTO `json:"decisions,omitempty"`
+}
+
+type ApplicationPageDTO struct {
+	Applications []ApplicationDTO `json:"applications"`
+	Total        int64            `json:"total"`
+	Page         int              `json:"page"`
+	Size         int              `json:"size"`
+}
+
+type DecisionDTO struct {
+	Reason *string `json:"reason"`
+}
+
+// Validate requires a reason for rejections, it is sent to the applicant
+func (p *DecisionDTO) Validate(decision string) error {
+	if decision == DECISION_REJECT && (p.Reason == nil || utils.ValidateRequired(*p.Reason) != nil) {
+		return ErrRejectionReasonRequired
+	}
+
+	return nil
+}
+
+type ModerationUsecase interface {
+	GetApplications(ctx context.Context, req GetApplicationsRequest) (res response.Response[ApplicationPageDTO])
+	GetApplication(ctx context.Context, applicationType string, id string) (res response.Response[ApplicationDTO])
+	DecideApplication(ctx context.Context, moderatorID string, applicationType string, id string, decision string, req DecisionDTO) (res response.Response[ApplicationDTO])
+}
+
+type ModerationRepository interface {
+	InsertDecision(ctx context.Context, entity ModerationDecisionEntity) (err error)
+	GetDecisions(ctx context.Context, applicationType string, applicationID string) (res []ModerationDecisionEntity, err error)
+}
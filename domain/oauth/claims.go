@@ -0,0 +1,72 @@
+package oauth
+
+import (
+	"mini-wallet/domain/user"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// UserClaims are the standard OpenID Connect claims of a user, only the ones
+// covered by the granted scopes are set
+type UserClaims struct {
+	Name                *string `json:"name,omitempty"`
+	Gender              *string `json:"gender,omitempty"`
+	Email               *string `json:"email,omitempty"`
+	EmailVerified       *bool   `json:"email_verified,omitempty"`
+	PhoneNumber         *string `json:"phone_number,omitempty"`
+	PhoneNumberVerified *bool   `json:"phone_number_verified,omitempty"`
+}
+
+func NewUserClaims(userEntity user.UserEntity, scopes []string) UserClaims {
+	claims := UserClaims{}
+
+	if slices.Contains(scopes, SCOPE_PROFILE) {
+		claims.Name = &userEntity.Name
+		claims.Gender = userEntity.Gender
+	}
+
+	if slices.Contains(scopes, SCOPE_EMAIL) {
+		emailVerified := userEntity.EmailVerifiedAt != ""
+		claims.Email = &userEntity.Email
+		claims.EmailVerified = &emailVerified
+	}
+
+	if slices.Contains(scopes, SCOPE_PHONE) && userEntity.PhoneNumber != nil {
+		// stored as 62xxx, the claim is E.164
+		phoneNumber := *userEntity.PhoneNumber
+		if !strings.HasPrefix(phoneNumber, "+") {
+			phoneNumber = "+" + phoneNumber
+		}
+
+		phoneNumberVerified := userEntity.PhoneNumberVerifiedAt != nil
+		claims.PhoneNumber = &phoneNumber
+		claims.PhoneNumberVerified = &phoneNumberVerified
+	}
+
+	return claims
+}
+
+type UserInfoResponse struct {
+	Subject string `json:"sub"`
+	UserClaims
+}
+
+type IDTokenClaims struct {
+	jwt.RegisteredClaims
+	UserClaims
+	Nonce           string `json:"nonce,omitempty"`
+	AuthTime        int64  `json:"auth_time"`
+	AuthorizedParty string `json:"azp"`
+}
+
+// AccessTokenClaims are the claims of tokens given to OAuth clients, bound to
+// the session the user authorized from
+type AccessTokenClaims struct {
+	jwt.RegisteredClaims
+	Scope     string `json:"scope"`
+	ClientID  string `json:"client_id"`
+	SessionID string `json:"sid,omitempty"`
+	TokenType string `json:"token_type"`
+}
@@ -0,0 +1,262 @@
+package oauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/utils"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	SCOPE_OPENID  = "openid"
+	SCOPE_PROFILE = "profile"
+	SCOPE_EMAIL   = "email"
+	SCOPE_PHONE   = "phone"
+
+	RESPONSE_TYPE_CODE         = "code"
+	GRANT_TYPE_AUTHORIZATION   = "authorization_code"
+	CODE_CHALLENGE_METHOD_S256 = "S256"
+
+	PROMPT_NONE    = "none"
+	PROMPT_LOGIN   = "login"
+	PROMPT_CONSENT = "consent"
+
+	// access tokens given to OAuth clients, they are not accepted by AuthMiddleware
+	TOKEN_TYPE_OAUTH_ACCESS = "OAUTH_ACCESS"
+
+	ERROR_INVALID_REQUEST      = "invalid_request"
+	ERROR_UNAUTHORIZED_CLIENT  = "unauthorized_client"
+	ERROR_ACCESS_DENIED        = "access_denied"
+	ERROR_UNSUPPORTED_RESPONSE = "unsupported_response_type"
+	ERROR_INVALID_SCOPE        = "invalid_scope"
+	ERROR_LOGIN_REQUIRED       = "login_required"
+	ERROR_CONSENT_REQUIRED     = "consent_required"
+	ERROR_INVALID_CLIENT       = "invalid_client"
+	ERROR_INVALID_GRANT        = "invalid_grant"
+	ERROR_UNSUPPORTED_GRANT    = "unsupported_grant_type"
+	ERROR_INVALID_TOKEN        = "invalid_token"
+	ERROR_SERVER_ERROR         = "server_error"
+
+	AUTHORIZATION_CODE_LIFETIME = 2 * time.Minute
+	OAUTH_ACCESS_TOKEN_LIFETIME = 1 * time.Hour
+	ID_TOKEN_LIFETIME           = 1 * time.Hour
+)
+
+var SupportedScopes = []string{SCOPE_OPENID, SCOPE_PROFILE, SCOPE_EMAIL, SCOPE_PHONE}
+
+// ClientEntity is an application allowed to sign users in through this service.
+// Public clients (no secret) rely on PKCE alone, which is required for every client.
+type ClientEntity struct {
+	ClientID     string   `bson:"client_id" json:"client_id"`
+	HashedSecret *string  `bson:"hashed_secret" json:"client_secret_hash"` // sha256 hex
+	Name         string   `bson:"name" json:"name"`
+	RedirectURIs []string `bson:"redirect_uris" json:"redirect_uris"`
+	Scopes       []string `bson:"scopes" json:"scopes"`
+	// first party clients are Sebia apps, the user is not asked for consent
+	FirstParty bool  `bson:"first_party" json:"first_party"`
+	CreatedAt  int64 `bson:"created_at" json:"-"`
+	UpdatedAt  int64 `bson:"updated_at" json:"-"`
+}
+
+func (p *ClientEntity) HasRedirectURI(redirectURI string) bool {
+	for _, uri := range p.RedirectURIs {
+		if uri == redirectURI {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *ClientEntity) AllowsScopes(scopes []string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(p.Scopes, scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *ClientEntity) VerifySecret(secret string) bool {
+	if p.HashedSecret == nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(*p.HashedSecret)) == 1
+}
+
+func (p *ClientEntity) ToClientDTO() ClientDTO {
+	return ClientDTO{
+		ClientID: p.ClientID,
+		Name:     p.Name,
+	}
+}
+
+// AuthorizationCodeEntity is stored under the hash of the code and deleted on first use
+type AuthorizationCodeEntity struct {
+	CodeHash            string   `bson:"code_hash"`
+	ClientID            string   `bson:"client_id"`
+	UserID              string   `bson:"user_id"`
+	SessionID           string   `bson:"session_id"`
+	RedirectURI         string   `bson:"redirect_uri"`
+	Scopes              []string `bson:"scopes"`
+	Nonce               string   `bson:"nonce"`
+	CodeChallenge       string   `bson:"code_challenge"`
+	CodeChallengeMethod string   `bson:"code_challenge_method"`
+	AuthTime            int64    `bson:"auth_time"`
+	ExpiredAt           int64    `bson:"expired_at"`
+}
+
+// ConsentEntity records the scopes a user granted to a client
+type ConsentEntity struct {
+	UserID    string   `bson:"user_id"`
+	ClientID  string   `bson:"client_id"`
+	Scopes    []string `bson:"scopes"`
+	CreatedAt int64    `bson:"created_at"`
+	UpdatedAt int64    `bson:"updated_at"`
+}
+
+func (p *ConsentEntity) Covers(scopes []string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(p.Scopes, scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type ClientDTO struct {
+	ClientID string `json:"client_id"`
+	Name     string `json:"name"`
+}
+
+// AuthorizationRequestDTO holds the parameters of /oauth/authorize
+type AuthorizationRequestDTO struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	Nonce               string `json:"nonce"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Prompt              string `json:"prompt"`
+}
+
+func (p *AuthorizationRequestDTO) Scopes() []string {
+	return strings.Fields(p.Scope)
+}
+
+// ConsentDTO is sent by the consent page with the original authorization request
+type ConsentDTO struct {
+	AuthorizationRequestDTO
+	Approved bool `json:"approved"`
+}
+
+type ConsentResultDTO struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+type TokenRequestDTO struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	ClientID     string
+	ClientSecret string
+	CodeVerifier string
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+// IntrospectionRequestDTO holds the parameters of /oauth/introspect (RFC 7662),
+// token_type_hint is not needed, only access tokens can be active
+type IntrospectionRequestDTO struct {
+	Token        string
+	ClientID     string
+	ClientSecret string
+}
+
+type DiscoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// Error is an OAuth 2.0 error (RFC 6749 section 5.2), written as is instead of response.Response
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	StatusCode  int    `json:"-"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func NewError(code string, description string) *Error {
+	statusCode := http.StatusBadRequest
+	switch code {
+	case ERROR_INVALID_CLIENT, ERROR_INVALID_TOKEN:
+		statusCode = http.StatusUnauthorized
+	case ERROR_SERVER_ERROR:
+		statusCode = http.StatusInternalServerError
+	}
+
+	return &Error{
+		Code:        code,
+		Description: description,
+		StatusCode:  statusCode,
+	}
+}
+
+type OAuthUsecase interface {
+	// Authorize answers /oauth/authorize with where the browser goes next: the
+	// login page, the consent page or back to the client. userID is nil when
+	// nobody is signed in. An *Error means the request can not be redirected back.
+	Authorize(ctx context.Context, userID *string, sessionID string, req AuthorizationRequestDTO) (redirectTo string, err *Error)
+	SubmitConsent(ctx context.Context, userID string, sessionID string, req ConsentDTO) (res response.Response[ConsentResultDTO])
+	GetClient(ctx context.Context, clientID string) (res response.Response[ClientDTO])
+	Token(ctx context.Context, req TokenRequestDTO) (res *TokenResponse, err *Error)
+	UserInfo(ctx context.Context, accessToken string) (res *UserInfoResponse, err *Error)
+	// AuthenticateIntrospectionClient only lets confidential clients introspect tokens
+	AuthenticateIntrospectionClient(ctx context.Context, clientID string, clientSecret string) (err *Error)
+	GetDiscoveryDocument() DiscoveryDocument
+}
+
+type OAuthRepository interface {
+	UpsertClient(ctx context.Context, client ClientEntity) (err error)
+	GetClientByID(ctx context.Context, clientID string) (res *ClientEntity, err error)
+
+	InsertAuthorizationCode(ctx context.Context, code AuthorizationCodeEntity) (err error)
+	// TakeAuthorizationCode returns and deletes an unexpired code
+	TakeAuthorizationCode(ctx context.Context, codeHash string, now int64) (res *AuthorizationCodeEntity, err error)
+
+	GetConsent(ctx context.Context, userID string, clientID string) (res *ConsentEntity, err error)
+	UpsertConsent(ctx context.Context, consent ConsentEntity) (err error)
+
+	// DeleteUserGrants removes the consents and the unused authorization codes of the user
+	DeleteUserGrants(ctx context.Context, userID string) (err error)
+}
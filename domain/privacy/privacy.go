@@ -0,0 +1,267 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"mini-wallet/domain/affiliate"
+	"mini-wallet/domain/business"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/domain/inquiry"
+	"mini-wallet/domain/review"
+	"mini-wallet/domain/user"
+	"mini-wallet/utils"
+	"time"
+)
+
+const (
+	// a requested deletion can still be cancelled by the user during this period
+	ACCOUNT_DELETION_GRACE_PERIOD = 14 * 24 * time.Hour
+	// due deletions are looked for this often
+	ACCOUNT_DELETION_INTERVAL   = time.Hour
+	ACCOUNT_DELETION_BATCH_SIZE = 50
+	// a claimed deletion is left to its replica this long, after that a replica
+	// that died halfway is taken over by the next run
+	ACCOUNT_DELETION_LOCK = 10 * time.Minute
+
+	DELETION_STATUS_PENDING   = "pending"
+	DELETION_STATUS_CANCELLED = "cancelled"
+	DELETION_STATUS_COMPLETED = "completed"
+
+	EXPORT_FORMAT_JSON = "json"
+	EXPORT_FORMAT_ZIP  = "zip"
+
+	// replaces the name on the inquiries of deleted accounts
+	ANONYMIZED_NAME = "Pengguna Terhapus"
+)
+
+var (
+	ErrUnknownExportFormat = errors.New("format ekspor tidak dikenal")
+	ErrReasonRequired      = errors.New("alasan penghapusan wajib diisi")
+)
+
+// AccountDeletionEntity is kept once the account is gone as the record that it
+// was deleted, it holds no personal data besides the user id
+type AccountDeletionEntity struct {
+	ID     string `bson:"id"`
+	UserID string `bson:"user_id"`
+	// the user themselves, or the admin who deleted the account right away
+	RequestedBy string `bson:"requested_by"`
+	Reason      string `bson:"reason,omitempty"`
+	Status      string `bson:"status"`
+	RequestedAt int64  `bson:"requested_at"`
+	ScheduledAt int64  `bson:"scheduled_at"`
+	CancelledAt *int64 `bson:"cancelled_at"`
+	CompletedAt *int64 `bson:"completed_at"`
+	// set by the replica deleting the account, see ACCOUNT_DELETION_LOCK
+	LockedUntil int64 `bson:"locked_until"`
+}
+
+func (p *AccountDeletionEntity) ToAccountDeletionDTO() AccountDeletionDTO {
+	return AccountDeletionDTO{
+		Status:      p.Status,
+		RequestedAt: p.RequestedAt,
+		ScheduledAt: p.ScheduledAt,
+	}
+}
+
+type AccountDeletionDTO struct {
+	Status      string `json:"status"`
+	RequestedAt int64  `json:"requested_at"`
+	ScheduledAt int64  `json:"scheduled_at"`
+}
+
+// AccountDeletionRequestDTO confirms the deletion with the password, accounts
+// without a password (identity providers only) leave it empty
+type AccountDeletionRequestDTO struct {
+	Password string `json:"password"`
+	Reason   string `json:"reason"`
+}
+
+type AdminAccountDeletionDTO struct {
+	Reason string `json:"reason"`
+}
+
+func (p *AdminAccountDeletionDTO) Validate() error {
+	err := utils.ValidateRequired(p.Reason)
+	if err != nil {
+		return ErrReasonRequired
+	}
+
+	return nil
+}
+
+// DataExportDTO is everything stored about the user, secrets (password hash,
+// 2FA secret, passkey keys) excluded
+type DataExportDTO struct {
+	ExportedAt int64               `json:"exported_at"`
+	Profile    ProfileExportDTO    `json:"profile"`
+	Inquiries  []InquiryExportDTO  `json:"inquiries"`
+	Reviews    []ReviewExportDTO   `json:"reviews"`
+	Business   *BusinessExportDTO  `json:"business"`
+	Affiliate  *AffiliateExportDTO `json:"affiliate"`
+	Deletion   *AccountDeletionDTO `json:"deletion,omitempty"`
+	Identities []IdentityExportDTO `json:"identities"`
+	Roles      []string            `json:"roles"`
+}
+
+type ProfileExportDTO struct {
+	UserID                string  `json:"user_id"`
+	Name                  string  `json:"name"`
+	Email                 string  `json:"email"`
+	PhoneNumber           *string `json:"phone_number"`
+	Gender                *string `json:"gender"`
+	CreatedAt             string  `json:"created_at"`
+	UpdatedAt             string  `json:"updated_at"`
+	EmailVerifiedAt       string  `json:"email_verified_at"`
+	PhoneNumberVerifiedAt *string `json:"phone_number_verified_at"`
+	HasPassword           bool    `json:"has_password"`
+	TwoFactorEnabled      bool    `json:"two_factor_enabled"`
+}
+
+type IdentityExportDTO struct {
+	Provider string `json:"provider"`
+	Email    string `json:"email"`
+	LinkedAt int64  `json:"linked_at"`
+}
+
+type InquiryExportDTO struct {
+	ID            string   `json:"id"`
+	ServiceID     string   `json:"service_id"`
+	SelectedDates []string `json:"selected_dates"`
+	SelectedHour  string   `json:"selected_hour"`
+	FullName      string   `json:"full_name"`
+	PhoneNumber   string   `json:"phone_number"`
+	Email         string   `json:"email"`
+	Status        int      `json:"status"`
+	TotalPayment  int      `json:"total_payment"`
+	CreatedAt     string   `json:"created_at"`
+}
+
+type ReviewExportDTO struct {
+	ID        string `json:"id"`
+	ServiceID string `json:"service_id"`
+	InquiryID string `json:"inquiry_id"`
+	Content   string `json:"content"`
+	Score     int    `json:"score"`
+	CreatedAt string `json:"created_at"`
+}
+
+type BusinessExportDTO struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Handle      string `json:"handle"`
+	PhoneNumber string `json:"phone_number"`
+	Address     string `json:"address"`
+	Status      int64  `json:"status"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+type AffiliateExportDTO struct {
+	InstagramUsername *string `json:"instagram_username"`
+	TiktokUsername    *string `json:"tiktok_username"`
+	Age               int     `json:"age"`
+	GenderID          int     `json:"gender_id"`
+	Address           string  `json:"address"`
+	Status            int     `json:"status"`
+	CreatedAt         int64   `json:"created_at"`
+}
+
+func NewProfileExportDTO(userEntity user.UserEntity) ProfileExportDTO {
+	return ProfileExportDTO{
+		UserID:                userEntity.UID,
+		Name:                  userEntity.Name,
+		Email:                 userEntity.Email,
+		PhoneNumber:           userEntity.PhoneNumber,
+		Gender:                userEntity.Gender,
+		CreatedAt:             userEntity.CreatedAt,
+		UpdatedAt:             userEntity.UpdatedAt,
+		EmailVerifiedAt:       userEntity.EmailVerifiedAt,
+		PhoneNumberVerifiedAt: userEntity.PhoneNumberVerifiedAt,
+		HasPassword:           userEntity.HashedPassword != nil,
+		TwoFactorEnabled:      userEntity.IsTwoFactorEnabled(),
+	}
+}
+
+func NewIdentityExportDTO(identity user.LinkedIdentityEntity) IdentityExportDTO {
+	return IdentityExportDTO{
+		Provider: identity.Provider,
+		Email:    identity.Email,
+		LinkedAt: identity.LinkedAt,
+	}
+}
+
+func NewInquiryExportDTO(entity inquiry.InquiryEntity) InquiryExportDTO {
+	return InquiryExportDTO{
+		ID:            entity.ID,
+		ServiceID:     entity.ServiceID,
+		SelectedDates: entity.SelectedDates,
+		SelectedHour:  entity.SelectedHour,
+		FullName:      entity.FullName,
+		PhoneNumber:   entity.PhoneNumber,
+		Email:         entity.Email,
+		Status:        entity.Status,
+		TotalPayment:  entity.TotalPayment,
+		CreatedAt:     entity.CreatedDate,
+	}
+}
+
+func NewReviewExportDTO(entity review.ReviewEntity) ReviewExportDTO {
+	return ReviewExportDTO{
+		ID:        entity.ID,
+		ServiceID: entity.ServiceID,
+		InquiryID: entity.InquiryID,
+		Content:   entity.Content,
+		Score:     entity.Score,
+		CreatedAt: entity.CreatedAt,
+	}
+}
+
+func NewBusinessExportDTO(entity business.BusinessEntity) *BusinessExportDTO {
+	return &BusinessExportDTO{
+		ID:          entity.ID,
+		Name:        entity.Name,
+		Handle:      entity.Handle,
+		PhoneNumber: entity.PhoneNumber,
+		Address:     entity.Address,
+		Status:      entity.Status,
+		CreatedAt:   entity.CreatedAt,
+	}
+}
+
+func NewAffiliateExportDTO(entity affiliate.AffiliateEntity) *AffiliateExportDTO {
+	return &AffiliateExportDTO{
+		InstagramUsername: entity.InstagramUsername,
+		TiktokUsername:    entity.TiktokUsername,
+		Age:               entity.Age,
+		GenderID:          entity.GenderID,
+		Address:           entity.Address,
+		Status:            entity.Status,
+		CreatedAt:         entity.CreatedAt,
+	}
+}
+
+type PrivacyUsecase interface {
+	ExportUserData(ctx context.Context, userID string) (res response.Response[DataExportDTO])
+	GetAccountDeletion(ctx context.Context, userID string) (res response.Response[AccountDeletionDTO])
+	// RequestAccountDeletion schedules the deletion after ACCOUNT_DELETION_GRACE_PERIOD
+	RequestAccountDeletion(ctx context.Context, userID string, req AccountDeletionRequestDTO) (res response.Response[AccountDeletionDTO])
+	CancelAccountDeletion(ctx context.Context, userID string) (res response.Response[string])
+	// DeleteAccount is the admin override, the account is deleted right away
+	DeleteAccount(ctx context.Context, adminID string, userID string, req AdminAccountDeletionDTO) (res response.Response[string])
+	// ProcessDueDeletions deletes the accounts whose grace period is over
+	ProcessDueDeletions(ctx context.Context) (err error)
+}
+
+type PrivacyRepository interface {
+	InsertAccountDeletion(ctx context.Context, entity AccountDeletionEntity) (err error)
+	GetPendingAccountDeletion(ctx context.Context, userID string) (res *AccountDeletionEntity, err error)
+	// ClaimDueAccountDeletion locks the oldest unclaimed pending deletion scheduled
+	// at or before now until lockedUntil, nil when there is none
+	ClaimDueAccountDeletion(ctx context.Context, now int64, lockedUntil int64) (res *AccountDeletionEntity, err error)
+	// ClaimAccountDeletion locks a pending deletion until lockedUntil, false when
+	// another replica holds it
+	ClaimAccountDeletion(ctx context.Context, id string, now int64, lockedUntil int64) (claimed bool, err error)
+	// CancelAccountDeletion is false when the user has no pending deletion
+	CancelAccountDeletion(ctx context.Context, userID string, now int64) (cancelled bool, err error)
+	CompleteAccountDeletion(ctx context.Context, id string, now int64) (err error)
+}
@@ -47,6 +47,9 @@ type ReviewUsecase interface {
 type ReviewRepository interface {
 	GetServiceTopReview(ctx context.Context, serviceId string) (res *ReviewEntity, err error)
 	InsertReview(ctx context.Context, review ReviewEntity) (err error)
+	GetReviewsByUserID(ctx context.Context, userID string) (res []ReviewEntity, err error)
+	// AnonymizeReviews unlinks the reviews from the user, the score and content stay on the service
+	AnonymizeReviews(ctx context.Context, userID string) (err error)
 }
 
 func (p *ReviewDTO) ToReviewEntity() ReviewEntity {
@@ -0,0 +1,251 @@
+package serviceaccount
+
+import (
+	"context"
+	"errors"
+	"mini-wallet/domain/auth"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/utils"
+	"slices"
+	"strings"
+)
+
+const (
+	// keys look like sbk_<key id>_<secret>, the key id is a ULID and never contains "_"
+	API_KEY_PREFIX = "sbk_"
+	// only this many trailing characters of a key are ever shown again
+	API_KEY_VISIBLE_CHARACTERS = 4
+	API_KEY_SECRET_LENGTH      = 40
+	MAX_API_KEY_LIFETIME_DAYS  = 365
+
+	// last_used_at is only written when older than this, not on every request
+	API_KEY_LAST_USED_INTERVAL = 5 * 60
+)
+
+// BusinessScopes are the permissions a key of a business account may be given,
+// on top of them the owner of the business must still hold each permission
+var BusinessScopes = []string{
+	auth.PERMISSION_BUSINESS_READ,
+	auth.PERMISSION_SERVICE_READ,
+	auth.PERMISSION_SERVICE_WRITE,
+	auth.PERMISSION_INQUIRY_READ,
+}
+
+var (
+	ErrInvalidApiKey        = errors.New("invalid api key")
+	ErrUnknownScope         = errors.New("scope tidak dikenal")
+	ErrScopesRequired       = errors.New("pilih minimal satu scope")
+	ErrInvalidKeyLifetime   = errors.New("masa berlaku kunci maksimal 365 hari")
+	ErrAdminRoleNotAllowed  = errors.New("akun layanan tidak dapat menjadi admin")
+	ErrInternalRoleRequired = errors.New("akun layanan internal membutuhkan minimal satu role")
+)
+
+// ServiceAccountEntity is a machine user. An account of a business acts as the
+// owner of the business, so the ownership checks of the usecases keep working.
+// Internal accounts (cron jobs) have no business, are created by admins and act
+// with Roles under their own id.
+type ServiceAccountEntity struct {
+	ID         string   `bson:"id"`
+	Name       string   `bson:"name"`
+	BusinessID *string  `bson:"business_id"`
+	Roles      []string `bson:"roles,omitempty"`
+	CreatedBy  string   `bson:"created_by"`
+	CreatedAt  int64    `bson:"created_at"`
+}
+
+func (p *ServiceAccountEntity) IsInternal() bool {
+	return p.BusinessID == nil
+}
+
+func (p *ServiceAccountEntity) ToServiceAccountDTO() ServiceAccountDTO {
+	return ServiceAccountDTO{
+		ID:         p.ID,
+		Name:       p.Name,
+		BusinessID: p.BusinessID,
+		Roles:      p.Roles,
+		CreatedAt:  p.CreatedAt,
+	}
+}
+
+// ApiKeyEntity stores the sha256 of the whole key, the key itself is only
+// returned once when it is created
+type ApiKeyEntity struct {
+	ID               string   `bson:"id"`
+	ServiceAccountID string   `bson:"service_account_id"`
+	Name             string   `bson:"name"`
+	KeyHash          string   `bson:"key_hash"`
+	LastCharacters   string   `bson:"last_characters"`
+	Scopes           []string `bson:"scopes"`
+	CreatedBy        string   `bson:"created_by"`
+	CreatedAt        int64    `bson:"created_at"`
+	// nil never expires
+	ExpiresAt  *int64  `bson:"expires_at"`
+	LastUsedAt *int64  `bson:"last_used_at"`
+	LastUsedIP *string `bson:"last_used_ip"`
+	RevokedAt  *int64  `bson:"revoked_at"`
+}
+
+func (p *ApiKeyEntity) IsActive(now int64) bool {
+	return p.RevokedAt == nil && (p.ExpiresAt == nil || *p.ExpiresAt > now)
+}
+
+func (p *ApiKeyEntity) ToApiKeyDTO() ApiKeyDTO {
+	return ApiKeyDTO{
+		ID:             p.ID,
+		Name:           p.Name,
+		Prefix:         API_KEY_PREFIX + p.ID,
+		LastCharacters: p.LastCharacters,
+		Scopes:         p.Scopes,
+		CreatedAt:      p.CreatedAt,
+		ExpiresAt:      p.ExpiresAt,
+		LastUsedAt:     p.LastUsedAt,
+		LastUsedIP:     p.LastUsedIP,
+		RevokedAt:      p.RevokedAt,
+	}
+}
+
+// ParseApiKey splits a key into its id and secret, ok is false for anything
+// that is not shaped like a key of this service
+func ParseApiKey(key string) (id string, secret string, ok bool) {
+	rest, found := strings.CutPrefix(key, API_KEY_PREFIX)
+	if !found {
+		return "", "", false
+	}
+
+	id, secret, found = strings.Cut(rest, "_")
+	if !found || id == "" || secret == "" {
+		return "", "", false
+	}
+
+	return id, secret, true
+}
+
+type ServiceAccountDTO struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	BusinessID *string  `json:"business_id,omitempty"`
+	Roles      []string `json:"roles,omitempty"`
+	CreatedAt  int64    `json:"created_at"`
+}
+
+type ApiKeyDTO struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	Prefix         string   `json:"prefix"`
+	LastCharacters string   `json:"last_characters"`
+	Scopes         []string `json:"scopes"`
+	CreatedAt      int64    `json:"created_at"`
+	ExpiresAt      *int64   `json:"expires_at"`
+	LastUsedAt     *int64   `json:"last_used_at"`
+	LastUsedIP     *string  `json:"last_used_ip,omitempty"`
+	RevokedAt      *int64   `json:"revoked_at,omitempty"`
+}
+
+// CreatedApiKeyDTO is the only response that carries the key
+type CreatedApiKeyDTO struct {
+	ApiKeyDTO
+	Key string `json:"key"`
+}
+
+// ServiceAccountCreationDTO creates an account of BusinessID, or an internal
+// account with Roles when BusinessID is empty
+type ServiceAccountCreationDTO struct {
+	Name       string   `json:"name"`
+	BusinessID *string  `json:"business_id"`
+	Roles      []string `json:"roles"`
+}
+
+func (p *ServiceAccountCreationDTO) Validate() error {
+	err := utils.ValidateRequired(p.Name)
+	if err != nil {
+		return err
+	}
+
+	if p.BusinessID != nil && *p.BusinessID == "" {
+		p.BusinessID = nil
+	}
+
+	// business accounts take the roles of the owner
+	if p.BusinessID != nil {
+		p.Roles = nil
+		return nil
+	}
+
+	if len(p.Roles) == 0 {
+		return ErrInternalRoleRequired
+	}
+
+	for _, role := range p.Roles {
+		if !auth.IsValidRole(role) {
+			return auth.ErrUnknownRole
+		}
+
+		if role == auth.ROLE_ADMIN {
+			return ErrAdminRoleNotAllowed
+		}
+	}
+
+	return nil
+}
+
+type ApiKeyCreationDTO struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// 0 never expires
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+func (p *ApiKeyCreationDTO) Validate() error {
+	err := utils.ValidateRequired(p.Name)
+	if err != nil {
+		return err
+	}
+
+	if len(p.Scopes) == 0 {
+		return ErrScopesRequired
+	}
+
+	if p.ExpiresInDays < 0 || p.ExpiresInDays > MAX_API_KEY_LIFETIME_DAYS {
+		return ErrInvalidKeyLifetime
+	}
+
+	return nil
+}
+
+// AllowsScopes tells whether keys of the account may be given the scopes
+func (p *ServiceAccountEntity) AllowsScopes(scopes []string) bool {
+	for _, scope := range scopes {
+		if p.IsInternal() && !auth.HasPermission(p.Roles, scope) {
+			return false
+		}
+
+		if !p.IsInternal() && !slices.Contains(BusinessScopes, scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type ServiceAccountUsecase interface {
+	CreateServiceAccount(ctx context.Context, userID string, req ServiceAccountCreationDTO) (res response.Response[ServiceAccountDTO])
+	// GetServiceAccounts lists the accounts of the business, the internal ones when businessID is empty
+	GetServiceAccounts(ctx context.Context, userID string, businessID string) (res response.Response[[]ServiceAccountDTO])
+	CreateApiKey(ctx context.Context, userID string, serviceAccountID string, req ApiKeyCreationDTO) (res response.Response[CreatedApiKeyDTO])
+	GetApiKeys(ctx context.Context, userID string, serviceAccountID string) (res response.Response[[]ApiKeyDTO])
+	RevokeApiKey(ctx context.Context, userID string, serviceAccountID string, apiKeyID string) (res response.Response[string])
+}
+
+type ServiceAccountRepository interface {
+	InsertServiceAccount(ctx context.Context, entity ServiceAccountEntity) (err error)
+	GetServiceAccountByID(ctx context.Context, id string) (res *ServiceAccountEntity, err error)
+	// GetServiceAccounts returns the accounts of the business, the internal ones when businessID is nil
+	GetServiceAccounts(ctx context.Context, businessID *string) (res []ServiceAccountEntity, err error)
+
+	InsertApiKey(ctx context.Context, entity ApiKeyEntity) (err error)
+	GetApiKeyByID(ctx context.Context, id string) (res *ApiKeyEntity, err error)
+	GetApiKeys(ctx context.Context, serviceAccountID string) (res []ApiKeyEntity, err error)
+	// RevokeApiKey is false when the key does not belong to the account or was already revoked
+	RevokeApiKey(ctx context.Context, serviceAccountID string, id string, now int64) (revoked bool, err error)
+	TouchApiKey(ctx context.Context, id string, ipAddress string, now int64) (err error)
+}
@@ -1,7 +1,129 @@
 package user
 
+import (
+	"context"
+	"errors"
+	"mini-wallet/domain/common/response"
+	"mini-wallet/utils"
+	"strings"
+	"time"
+)
+
+const (
+	GENDER_MALE   = "male"
+	GENDER_FEMALE = "female"
+
+	// a new code for the same change can be asked once this is over
+	CONTACT_CHANGE_RESEND_COOLDOWN = time.Minute
+)
+
+var ErrUnknownGender = errors.New("jenis kelamin tidak dikenal")
+
+// UserProfileDTO is the settings page view of the user
+type UserProfileDTO struct {
+	UserID                string   `json:"user_id"`
+	Name                  string   `json:"name"`
+	Email                 string   `json:"email"`
+	PhoneNumber           *string  `json:"phone_number"`
+	Gender                *string  `json:"gender"`
+	EmailVerifiedAt       string   `json:"email_verified_at"`
+	PhoneNumberVerifiedAt *string  `json:"phone_number_verified_at"`
+	HasPassword           bool     `json:"has_password"`
+	TwoFactorEnabled      bool     `json:"two_factor_enabled"`
+	Identities            []string `json:"identities"`
+}
+
+func (p *UserEntity) ToUserProfileDTO() UserProfileDTO {
+	profile := UserProfileDTO{
+		UserID:                p.UID,
+		Name:                  p.Name,
+		Email:                 p.Email,
+		PhoneNumber:           p.PhoneNumber,
+		Gender:                p.Gender,
+		EmailVerifiedAt:       p.EmailVerifiedAt,
+		PhoneNumberVerifiedAt: p.PhoneNumberVerifiedAt,
+		HasPassword:           p.HashedPassword != nil,
+		TwoFactorEnabled:      p.IsTwoFactorEnabled(),
+		Identities:            []string{},
+	}
+
+	for _, identity := range p.Identities {
+		profile.Identities = append(profile.Identities, identity.Provider)
+	}
+
+	return profile
+}
+
+// UserMeDTO gathers what the app needs right after signing in. BusinessStatus is
+// null without a business, pending, rejected or the business id; AffiliateStatus
+// is 0 without an application.
+type UserMeDTO struct {
+	Profile         UserProfileDTO `json:"profile"`
+	Roles           []string       `json:"roles"`
+	BusinessStatus  *string        `json:"business_status"`
+	AffiliateStatus int            `json:"affiliate_status"`
+}
+
+type UpdateProfileDTO struct {
+	Name   string  `json:"name"`
+	Gender *string `json:"gender"`
+}
+
+func (p *UpdateProfileDTO) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	err := utils.ValidateFullName(p.Name)
+	if err != nil {
+		return err
+	}
+
+	if p.Gender != nil && *p.Gender != GENDER_MALE && *p.Gender != GENDER_FEMALE {
+		return ErrUnknownGender
+	}
+
+	return nil
+}
+
+// EmailChangeRequestDTO asks for a code at the new email, the email of the account
+// is only replaced once the code is confirmed
+type EmailChangeRequestDTO struct {
+	Email string `json:"email"`
+}
+
+func (p *EmailChangeRequestDTO) Validate() error {
+	p.Email = strings.ToLower(strings.TrimSpace(p.Email))
+	return utils.ValidateEmail(p.Email)
+}
+
+// PhoneNumberChangeRequestDTO asks for a code on WhatsApp at the new number, the
+// number of the account is only replaced once the code is confirmed
+type PhoneNumberChangeRequestDTO struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+func (p *PhoneNumberChangeRequestDTO) Validate() error {
+	phoneNumber, err := utils.ValidatePhoneNumber(p.PhoneNumber)
+	if err != nil {
+		return err
+	}
+
+	p.PhoneNumber = *phoneNumber
+	return nil
+}
+
+type ContactChangeConfirmationDTO struct {
+	Code string `json:"code"`
+}
+
+func (p *ContactChangeConfirmationDTO) Validate() error {
+	return utils.ValidateRequired(p.Code)
+}
+
 type UserUsecase interface {
-	GetUserBasicInformation() // for settings page
-	GetUserBusinessEntity()
-	GetUserAffiliateStatus()
+	GetMe(ctx context.Context, userID string) (res response.Response[UserMeDTO])
+	GetProfile(ctx context.Context, userID string) (res response.Response[UserProfileDTO])
+	UpdateProfile(ctx context.Context, userID string, req UpdateProfileDTO) (res response.Response[UserProfileDTO])
+	RequestEmailChange(ctx context.Context, userID string, req EmailChangeRequestDTO) (res response.Response[string])
+	ConfirmEmailChange(ctx context.Context, userID string, req ContactChangeConfirmationDTO) (res response.Response[UserProfileDTO])
+	RequestPhoneNumberChange(ctx context.Context, userID string, req PhoneNumberChangeRequestDTO) (res response.Response[string])
+	ConfirmPhoneNumberChange(ctx context.Context, userID string, req ContactChangeConfirmationDTO) (res response.Response[UserProfileDTO])
 }
@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type UserEntity struct {
@@ -23,35 +22,79 @@ type UserEntity struct {
 	EmailVerifiedAt       string  `bson:"email_verified_at"`
 	PhoneNumberVerifiedAt *string `bson:"phone_number_verified_at"`
 	PasswordSalt          *string `bson:"password_salt"`
+
+	TwoFactor  *TwoFactorEntity       `bson:"two_factor,omitempty"`
+	Identities []LinkedIdentityEntity `bson:"identities,omitempty"`
+	// granted roles, guest is implicit and never stored, see auth.GetUserRoles
+	Roles []string `bson:"roles,omitempty"`
+	// set when a login was reported as not made by the user, password logins are
+	// refused until the password is reset
+	PasswordResetRequired bool `bson:"password_reset_required,omitempty"`
 }
 
-func (p *UserEntity) ChangePassword(newPassword string) error {
-	if p.PasswordSalt == nil {
-		salt, err := utils.GenerateSalt(16)
-		if err != nil {
-			return err
+// LinkedIdentityEntity is an identity provider account the user can sign in with,
+// at most one per provider
+type LinkedIdentityEntity struct {
+	Provider string `bson:"provider"`
+	Subject  string `bson:"subject"`
+	Email    string `bson:"email"`
+	LinkedAt int64  `bson:"linked_at"`
+}
+
+// TwoFactorEntity holds the TOTP state. PendingSecret is set on enrollment and
+// only becomes Secret once the user proves their authenticator has it.
+type TwoFactorEntity struct {
+	Enabled       bool    `bson:"enabled"`
+	Secret        string  `bson:"secret"`
+	PendingSecret *string `bson:"pending_secret"`
+	// sha256 of the unused recovery codes, each one is removed when used
+	RecoveryCodes []string `bson:"recovery_codes"`
+	// last accepted TOTP time step, a code can not be used twice
+	LastUsedStep int64  `bson:"last_used_step"`
+	EnabledAt    *int64 `bson:"enabled_at"`
+}
+
+func (p *UserEntity) IsTwoFactorEnabled() bool {
+	return p.TwoFactor != nil && p.TwoFactor.Enabled
+}
+
+func (p *UserEntity) GetIdentity(provider string) *LinkedIdentityEntity {
+	for i := range p.Identities {
+		if p.Identities[i].Provider == provider {
+			return &p.Identities[i]
 		}
-		p.PasswordSalt = &salt
 	}
 
-	saltedPassword := newPassword + *p.PasswordSalt
-	hash, err := bcrypt.GenerateFromPassword([]byte(saltedPassword), bcrypt.DefaultCost)
+	return nil
+}
+
+// ChangePassword stores a hash of the current scheme, the legacy separate salt is dropped
+func (p *UserEntity) ChangePassword(newPassword string) error {
+	hash, err := utils.HashPassword(newPassword)
 	if err != nil {
 		return err
 	}
 
-	stringHashedPassword := string(hash)
-	p.HashedPassword = &stringHashedPassword
+	p.HashedPassword = &hash
+	p.PasswordSalt = nil
 
 	return nil
 }
 
+const (
+	// a pending registration can be verified for this long, every resent link restarts it
+	TEMPORARY_USER_LIFETIME = 15 * time.Minute
+	PASSWORD_RESET_LIFETIME = 15 * time.Minute
+)
+
 type UserPasswordResetEntity struct {
 	UID string `json:"uid"`
 
 	Email              string `bson:"email"`
 	ExpiredAt          int64  `bson:"expired_at"`
 	PasswordResetToken string `bson:"password_reset_token"`
+	// read by the TTL index, mongo deletes the link once it is passed
+	PurgeAt time.Time `bson:"purge_at"`
 }
 
 type TemporaryUserEntity struct {
@@ -67,6 +110,10 @@ type TemporaryUserEntity struct {
 	ExpiredAt         int     `bson:"expired_at"`
 	PasswordSalt      *string `bson:"password_salt"`
 	VerificationToken string  `bson:"verification_token"`
+	// set when registering from an inquiry, the link is then sent on WhatsApp
+	InquiryID *string `bson:"inquiry_id,omitempty"`
+	// read by the TTL index, mongo deletes the pending registration once it is passed
+	PurgeAt time.Time `bson:"purge_at"`
 }
 
 func (p *TemporaryUserEntity) ToUserEntity() (*UserEntity, error) {
@@ -98,10 +145,21 @@ type UserDto struct {
 }
 
 type UserRepository interface {
+	// EnsureExpiryIndexes creates the TTL indexes of the pending registrations and reset links
+	// and sets purge_at on the documents stored without one
+	EnsureExpiryIndexes(ctx context.Context) (err error)
+	// EnsureUniqueIndexes makes the email and the phone number, the login
+	// identifiers, unique among the users
+	EnsureUniqueIndexes(ctx context.Context) (err error)
 	InsertUser(ctx context.Context, user UserEntity) (err error)
 	UpsertUser(ctx context.Context, user UserEntity) (err error)
-	InsertTemporaryUser(ctx context.Context, user TemporaryUserEntity) (err error)
+	DeleteUser(ctx context.Context, userID string) (err error)
+	// ReplaceTemporaryUser inserts the pending registration, superseding the pending
+	// ones of the same email or phone number
+	ReplaceTemporaryUser(ctx context.Context, user TemporaryUserEntity) (err error)
 	DeleteTemporaryUser(ctx context.Context, email string) (err error)
+	// RenewTemporaryUserVerification replaces the verification token and restarts the lifetime
+	RenewTemporaryUserVerification(ctx context.Context, userID string, token string, expiredAt int, purgeAt time.Time) (err error)
 	GetTemporaryUserByVerificationToken(ctx context.Context, token string, now int64) (user *TemporaryUserEntity, err error)
 	GetTemporaryUserByIdentifier(ctx context.Context, identifier string, now int64) (user *TemporaryUserEntity, err error)
 
@@ -109,16 +167,51 @@ type UserRepository interface {
 	GetUserByPhoneNumber(ctx context.Context, phoneNumber string) (user *UserEntity, err error)
 	GetUserByIdentifier(ctx context.Context, identifier string) (user *UserEntity, err error)
 	GetUserByUserID(ctx context.Context, userID string) (user *UserEntity, err error)
+	GetUserByIdentity(ctx context.Context, provider string, subject string) (user *UserEntity, err error)
+
+	// LinkIdentity adds the identity, false means the user already has one of that provider
+	LinkIdentity(ctx context.Context, userID string, identity LinkedIdentityEntity) (linked bool, err error)
+	UnlinkIdentity(ctx context.Context, userID string, provider string) (unlinked bool, err error)
+	SetPhoneNumberVerified(ctx context.Context, userID string, now string) (err error)
+	UpdateProfile(ctx context.Context, userID string, name string, gender *string, now string) (err error)
+	// UpdateEmail replaces the email with a verified one, taken is true when another
+	// user holds the email
+	UpdateEmail(ctx context.Context, userID string, email string, now string) (taken bool, err error)
+	// UpdatePhoneNumber replaces the phone number with a verified one, taken is true
+	// when another user holds the number
+	UpdatePhoneNumber(ctx context.Context, userID string, phoneNumber string, now string) (taken bool, err error)
+	SetRoles(ctx context.Context, userID string, roles []string) (err error)
+	// AddRole grants the role, false means the user already had it
+	AddRole(ctx context.Context, userID string, role string) (added bool, err error)
+	// SetFirstPassword only succeeds for users without a password yet
+	SetFirstPassword(ctx context.Context, userID string, hashedPassword string, now string) (set bool, err error)
+	// UpdatePasswordHash replaces the hash only if it is still currentHash, false means the password changed meanwhile
+	UpdatePasswordHash(ctx context.Context, userID string, currentHash string, newHash string) (updated bool, err error)
+	// RequirePasswordReset refuses password logins until the password is reset
+	RequirePasswordReset(ctx context.Context, userID string) (err error)
 
 	InsertUserPasswordResetEntity(ctx context.Context, entity UserPasswordResetEntity) (err error)
+	// DeleteUserPasswordResetEntity deletes every pending reset link of the email
 	DeleteUserPasswordResetEntity(ctx context.Context, email string) (err error)
 	GetUserPasswordResetEntity(ctx context.Context, token string, now int64) (res *UserPasswordResetEntity, err error)
+
+	SetTwoFactorPendingSecret(ctx context.Context, userID string, secret string) (err error)
+	// EnableTwoFactor promotes the pending secret, false means the pending secret changed meanwhile
+	EnableTwoFactor(ctx context.Context, userID string, secret string, recoveryCodes []string, usedStep int64, now int64) (enabled bool, err error)
+	DisableTwoFactor(ctx context.Context, userID string) (err error)
+	SetTwoFactorRecoveryCodes(ctx context.Context, userID string, recoveryCodes []string) (err error)
+	// UseTwoFactorStep records the TOTP time step, false means a code of this step or a later one was already used
+	UseTwoFactorStep(ctx context.Context, userID string, step int64) (used bool, err error)
+	// UseTwoFactorRecoveryCode removes the hashed recovery code, false means it was not there
+	UseTwoFactorRecoveryCode(ctx context.Context, userID string, hashedCode string) (used bool, err error)
 }
 
-func (p *UserEntity) VerifyPassword(providedPassword string) error {
-	// Combine the provided password with the stored salt
-	saltedPassword := providedPassword + *p.PasswordSalt
+// VerifyPassword checks the password against the stored hash of any scheme,
+// needsRehash means it matched but the hash should be upgraded with ChangePassword
+func (p *UserEntity) VerifyPassword(providedPassword string) (needsRehash bool, err error) {
+	if p.HashedPassword == nil {
+		return false, utils.ErrPasswordMismatch
+	}
 
-	// Compare the hashed version of the provided password with the stored hash
-	return bcrypt.CompareHashAndPassword([]byte(*p.HashedPassword), []byte(saltedPassword))
+	return utils.VerifyPassword(providedPassword, *p.HashedPassword, p.PasswordSalt)
 }
@@ -0,0 +1,32 @@
+package emailtemplates
+
+import (
+	"fmt"
+	"html"
+)
+
+// param
+// 0 -> user full name
+// 1 -> lockout duration in minutes
+func BuildAccountLockedEmailTemplate(userFullName string, lockedForMinutes int) string {
+	return fmt.Sprintf(`
+	<!doctype html>
+	<html lang="en">
+
+	<head>
+		<meta name="viewport" content="width=device-width, initial-scale=1.0">
+		<meta http-equiv="Content-Type" content="text/html; charset=UTF-8">
+		<title>Aktivitas Masuk Mencurigakan</title>
+	</head>
+
+	<body style="font-family: Helvetica, sans-serif; font-size: 16px; color: #0f172a;">
+		<p>Halo %s,</p>
+		<p>Kami mendeteksi beberapa percobaan masuk ke akun Anda dengan kata sandi atau kode verifikasi yang salah.
+			Untuk melindungi akun Anda, proses masuk dikunci sementara selama %d menit.</p>
+		<p>Jika ini bukan Anda, segera atur ulang kata sandi Anda melalui menu "Lupa kata sandi" di halaman masuk.</p>
+		<p>Jika ini memang Anda, silakan coba kembali setelah waktu tersebut.</p>
+	</body>
+
+	</html>
+	`, html.EscapeString(userFullName), lockedForMinutes)
+}
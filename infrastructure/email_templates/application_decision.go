@@ -0,0 +1,37 @@
+package emailtemplates
+
+import (
+	"fmt"
+	"html"
+)
+
+// param
+// 0 -> user full name
+// 1 -> title, e.g. "Pengajuan Bisnis Disetujui"
+// 2 -> decision message
+// 3 -> rejection reason, empty for approvals
+func BuildApplicationDecisionEmailTemplate(userFullName string, title string, message string, reason string) string {
+	reasonParagraph := ""
+	if reason != "" {
+		reasonParagraph = fmt.Sprintf("<p>Alasan: %s</p>", html.EscapeString(reason))
+	}
+
+	return fmt.Sprintf(`
+	<!doctype html>
+	<html lang="en">
+
+	<head>
+		<meta name="viewport" content="width=device-width, initial-scale=1.0">
+		<meta http-equiv="Content-Type" content="text/html; charset=UTF-8">
+		<title>%s</title>
+	</head>
+
+	<body style="font-family: Helvetica, sans-serif; font-size: 16px; color: #0f172a;">
+		<p>Halo %s,</p>
+		<p>%s</p>
+		%s
+	</body>
+
+	</html>
+	`, html.EscapeString(title), html.EscapeString(userFullName), html.EscapeString(message), reasonParagraph)
+}
@@ -0,0 +1,34 @@
+package emailtemplates
+
+import (
+	"fmt"
+	"html"
+)
+
+// param
+// 0 -> user full name
+// 1 -> sign in link
+// 2 -> link lifetime in minutes
+func BuildMagicLinkEmailTemplate(userFullName string, link string, lifetimeMinutes int) string {
+	return fmt.Sprintf(`
+	<!doctype html>
+	<html lang="en">
+
+	<head>
+		<meta name="viewport" content="width=device-width, initial-scale=1.0">
+		<meta http-equiv="Content-Type" content="text/html; charset=UTF-8">
+		<title>Masuk ke Akun Anda</title>
+	</head>
+
+	<body style="font-family: Helvetica, sans-serif; font-size: 16px; color: #0f172a;">
+		<p>Halo %s,</p>
+		<p>Klik tombol di bawah untuk masuk ke akun Anda. Link hanya dapat digunakan sekali, berlaku selama %d menit
+			dan harus dibuka di browser yang sama dengan tempat Anda memintanya.</p>
+		<p><a href="%s" target="_blank"
+				style="display: inline-block; padding: 12px 24px; background-color: #0867ec; color: #ffffff; text-decoration: none; border-radius: 4px;">Masuk</a></p>
+		<p>Jika Anda tidak meminta link ini, abaikan email ini.</p>
+	</body>
+
+	</html>
+	`, html.EscapeString(userFullName), lifetimeMinutes, html.EscapeString(link))
+}
@@ -0,0 +1,38 @@
+package emailtemplates
+
+import (
+	"fmt"
+	"html"
+)
+
+// param
+// 0 -> user full name
+// 1 -> title, a new device or a suspicious login
+// 2 -> user agent of the device
+// 3 -> ip address of the device
+// 4 -> time of the login
+// 5 -> "this wasn't me" link
+func BuildNewDeviceLoginEmailTemplate(userFullName string, title string, userAgent string, ipAddress string, loginAt string, reportLink string) string {
+	return fmt.Sprintf(`
+	<!doctype html>
+	<html lang="en">
+
+	<head>
+		<meta name="viewport" content="width=device-width, initial-scale=1.0">
+		<meta http-equiv="Content-Type" content="text/html; charset=UTF-8">
+		<title>%s</title>
+	</head>
+
+	<body style="font-family: Helvetica, sans-serif; font-size: 16px; color: #0f172a;">
+		<p>Halo %s,</p>
+		<p>Akun Anda baru saja masuk dari perangkat yang belum pernah digunakan sebelumnya.</p>
+		<p>Perangkat: %s<br>Alamat IP: %s<br>Waktu: %s</p>
+		<p>Jika ini memang Anda, abaikan email ini.</p>
+		<p>Jika ini bukan Anda, klik tombol di bawah ini. Semua sesi akan dikeluarkan dan Anda perlu mengatur ulang kata sandi.</p>
+		<p><a href="%s" target="_blank"
+				style="display: inline-block; padding: 12px 24px; background-color: #dc2626; color: #ffffff; text-decoration: none; border-radius: 4px;">Ini bukan saya</a></p>
+	</body>
+
+	</html>
+	`, html.EscapeString(title), html.EscapeString(userFullName), html.EscapeString(userAgent), html.EscapeString(ipAddress), html.EscapeString(loginAt), html.EscapeString(reportLink))
+}
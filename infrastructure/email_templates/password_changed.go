@@ -0,0 +1,31 @@
+package emailtemplates
+
+import (
+	"fmt"
+	"html"
+)
+
+// param
+// 0 -> user full name
+// 1 -> time of the change
+func BuildPasswordChangedEmailTemplate(userFullName string, changedAt string) string {
+	return fmt.Sprintf(`
+	<!doctype html>
+	<html lang="en">
+
+	<head>
+		<meta name="viewport" content="width=device-width, initial-scale=1.0">
+		<meta http-equiv="Content-Type" content="text/html; charset=UTF-8">
+		<title>Kata Sandi Diubah</title>
+	</head>
+
+	<body style="font-family: Helvetica, sans-serif; font-size: 16px; color: #0f172a;">
+		<p>Halo %s,</p>
+		<p>Kata sandi akun Anda telah diubah pada %s.</p>
+		<p>Jika ini bukan Anda, segera atur ulang kata sandi Anda melalui menu "Lupa kata sandi" di halaman masuk.</p>
+		<p>Jika ini memang Anda, abaikan email ini.</p>
+	</body>
+
+	</html>
+	`, html.EscapeString(userFullName), html.EscapeString(changedAt))
+}
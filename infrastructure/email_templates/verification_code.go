@@ -0,0 +1,32 @@
+package emailtemplates
+
+import (
+	"fmt"
+	"html"
+)
+
+// param
+// 0 -> user full name
+// 1 -> verification code
+// 2 -> code lifetime in minutes
+func BuildVerificationCodeEmailTemplate(userFullName string, code string, lifetimeMinutes int) string {
+	return fmt.Sprintf(`
+	<!doctype html>
+	<html lang="en">
+
+	<head>
+		<meta name="viewport" content="width=device-width, initial-scale=1.0">
+		<meta http-equiv="Content-Type" content="text/html; charset=UTF-8">
+		<title>Verifikasi Email Baru</title>
+	</head>
+
+	<body style="font-family: Helvetica, sans-serif; font-size: 16px; color: #0f172a;">
+		<p>Halo %s,</p>
+		<p>Masukkan kode di bawah untuk menggunakan email ini pada akun Anda. Kode berlaku selama %d menit.</p>
+		<p style="font-size: 28px; font-weight: bold; letter-spacing: 6px;">%s</p>
+		<p>Jika Anda tidak meminta perubahan email, abaikan email ini.</p>
+	</body>
+
+	</html>
+	`, html.EscapeString(userFullName), lifetimeMinutes, html.EscapeString(code))
+}
@@ -0,0 +1,144 @@
+package infrastructure
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// expired entries that are never read again are removed this often
+const memoryCacheSweepInterval = time.Minute
+
+type memoryCacheEntry struct {
+	value     string
+	expiredAt time.Time
+}
+
+// memoryCache is an in-process Cache for local development, nothing is shared
+// between instances and Publish has no subscribers
+type memoryCache struct {
+	mutex   sync.Mutex
+	entries map[string]memoryCacheEntry
+}
+
+func NewMemoryCache() Cache {
+	cache := &memoryCache{
+		entries: map[string]memoryCacheEntry{},
+	}
+
+	go cache.sweep()
+
+	return cache
+}
+
+// sweep drops the expired entries every memoryCacheSweepInterval, the cache lives
+// as long as the process so it never stops
+func (cache *memoryCache) sweep() {
+	ticker := time.NewTicker(memoryCacheSweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cache.mutex.Lock()
+		for key := range cache.entries {
+			cache.get(key)
+		}
+		cache.mutex.Unlock()
+	}
+}
+
+// get returns the live entry at key, expired entries are dropped on read and by
+// sweep. The caller holds the lock.
+func (cache *memoryCache) get(key string) (memoryCacheEntry, bool) {
+	entry, ok := cache.entries[key]
+	if !ok {
+		return entry, false
+	}
+
+	if !entry.expiredAt.IsZero() && !time.Now().Before(entry.expiredAt) {
+		delete(cache.entries, key)
+		return entry, false
+	}
+
+	return entry, true
+}
+
+func (cache *memoryCache) SetString(ctx context.Context, key string, obj string, ttlInSec int) (err error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry := memoryCacheEntry{
+		value: obj,
+	}
+
+	if ttlInSec > 0 {
+		entry.expiredAt = time.Now().Add(time.Second * time.Duration(ttlInSec))
+	}
+
+	cache.entries[key] = entry
+	return nil
+}
+
+func (cache *memoryCache) GetString(ctx context.Context, key string) (result string, err error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry, ok := cache.get(key)
+	if !ok {
+		return "", ErrCacheMiss
+	}
+
+	return entry.value, nil
+}
+
+func (cache *memoryCache) Del(ctx context.Context, key string) (err error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	delete(cache.entries, key)
+	return nil
+}
+
+func (cache *memoryCache) Publish(ctx context.Context, channel string, payload interface{}) (err error) {
+	return nil
+}
+
+func (cache *memoryCache) Incr(ctx context.Context, key string, ttlInSec int) (count int64, err error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry, ok := cache.get(key)
+	if !ok {
+		entry = memoryCacheEntry{
+			value: "0",
+		}
+
+		if ttlInSec > 0 {
+			entry.expiredAt = time.Now().Add(time.Second * time.Duration(ttlInSec))
+		}
+	}
+
+	count, err = strconv.ParseInt(entry.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	count++
+	entry.value = strconv.FormatInt(count, 10)
+	cache.entries[key] = entry
+
+	return count, nil
+}
+
+func (cache *memoryCache) TTL(ctx context.Context, key string) (ttlInSec int, err error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry, ok := cache.get(key)
+	if !ok || entry.expiredAt.IsZero() {
+		return 0, nil
+	}
+
+	return int(math.Ceil(time.Until(entry.expiredAt).Seconds())), nil
+}
@@ -3,6 +3,7 @@ package infrastructure
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -19,11 +20,18 @@ func NewRedisClient(ctx context.Context, config Config) redis.Client {
 	return *rdb
 }
 
+// ErrCacheMiss is returned by GetString when the key does not exist
+var ErrCacheMiss = errors.New("cache: key not found")
+
 type Cache interface {
 	SetString(ctx context.Context, key string, obj string, ttlInSec int) (err error)
 	GetString(ctx context.Context, key string) (result string, err error)
 	Del(ctx context.Context, key string) (err error)
 	Publish(ctx context.Context, channel string, payload interface{}) (err error)
+	// Incr increments the counter at key, ttlInSec is only applied when the key is created
+	Incr(ctx context.Context, key string, ttlInSec int) (count int64, err error)
+	// TTL returns the remaining lifetime of key in seconds, 0 if it does not exist
+	TTL(ctx context.Context, key string) (ttlInSec int, err error)
 }
 
 type redisCache struct {
@@ -52,6 +60,10 @@ func (cache *redisCache) SetString(ctx context.Context, key string, obj string,
 
 func (cache *redisCache) GetString(ctx context.Context, key string) (result string, err error) {
 	res, err := cache.client.Get(key).Result()
+	if err == redis.Nil {
+		return "", ErrCacheMiss
+	}
+
 	if err != nil {
 		return "", err
 	}
@@ -60,6 +72,35 @@ func (cache *redisCache) GetString(ctx context.Context, key string) (result stri
 }
 
 func (cache *redisCache) Del(ctx context.Context, key string) (err error) {
-	// TODO: not used
-	return
+	return cache.client.Del(key).Err()
+}
+
+// Incr creates the counter with its expiry and increments it in one transaction,
+// a counter is never left without a TTL
+func (cache *redisCache) Incr(ctx context.Context, key string, ttlInSec int) (count int64, err error) {
+	var incr *redis.IntCmd
+	_, err = cache.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.SetNX(key, 0, time.Second*time.Duration(ttlInSec))
+		incr = pipe.Incr(key)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
+
+func (cache *redisCache) TTL(ctx context.Context, key string) (ttlInSec int, err error) {
+	ttl, err := cache.client.TTL(key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// -2s when the key does not exist, -1s when it has no expiry
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return int(ttl / time.Second), nil
 }
@@ -108,3 +108,86 @@ func SendPasswordResetLink(email string, userFullName string, token string, doma
 	}
 
 }
+
+// SendEmail sends a plain html email without a sendgrid template
+func SendEmail(email string, userFullName string, subject string, htmlContent string) (err error) {
+	from := mail.NewEmail("Namulaki", "corporation@namulaki.id")
+	to := mail.NewEmail(userFullName, email)
+
+	content := mail.NewContent("text/html", htmlContent)
+	m := mail.NewV3MailInit(from, subject, to, content)
+
+	request := sendgrid.GetRequest(os.Getenv("SENDGRID_API_KEY"), "/v3/mail/send", "")
+	request.Method = "POST"
+	request.Body = mail.GetRequestBody(m)
+	client := &rest.Client{
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   30 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ExpectContinueTimeout: 1 * time.Second,
+				MaxIdleConns:          2,
+				MaxIdleConnsPerHost:   2,
+				IdleConnTimeout:       90 * time.Millisecond,
+			},
+			Timeout: 5 * time.Second,
+		},
+	}
+
+	response, err := client.Send(request)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("unexpected sendgrid status %d: %s", response.StatusCode, response.Body)
+	}
+
+	return nil
+}
+
+func SendAccountLockedNotice(email string, userFullName string, lockedFor time.Duration, domain string) {
+	err := SendEmail(email, userFullName, "Aktivitas Masuk Mencurigakan "+domain, emailtemplates.BuildAccountLockedEmailTemplate(userFullName, int(lockedFor.Minutes())))
+	if err != nil {
+		fmt.Println("error sending email:", err.Error())
+	}
+}
+
+func SendPasswordChangedNotice(email string, userFullName string, changedAt string, domain string) {
+	err := SendEmail(email, userFullName, "Kata Sandi Diubah "+domain, emailtemplates.BuildPasswordChangedEmailTemplate(userFullName, changedAt))
+	if err != nil {
+		fmt.Println("error sending email:", err.Error())
+	}
+}
+
+func SendNewDeviceAlert(email string, userFullName string, title string, userAgent string, ipAddress string, loginAt string, reportLink string, domain string) {
+	err := SendEmail(email, userFullName, title+" "+domain, emailtemplates.BuildNewDeviceLoginEmailTemplate(userFullName, title, userAgent, ipAddress, loginAt, reportLink))
+	if err != nil {
+		fmt.Println("error sending email:", err.Error())
+	}
+}
+
+func SendMagicLink(email string, userFullName string, link string, lifetime time.Duration, domain string) {
+	err := SendEmail(email, userFullName, "Masuk ke "+domain, emailtemplates.BuildMagicLinkEmailTemplate(userFullName, link, int(lifetime.Minutes())))
+	if err != nil {
+		fmt.Println("error sending email:", err.Error())
+	}
+}
+
+func SendVerificationCode(email string, userFullName string, code string, lifetime time.Duration, domain string) {
+	err := SendEmail(email, userFullName, "Verifikasi Email "+domain, emailtemplates.BuildVerificationCodeEmailTemplate(userFullName, code, int(lifetime.Minutes())))
+	if err != nil {
+		fmt.Println("error sending email:", err.Error())
+	}
+}
+
+func SendApplicationDecision(email string, userFullName string, title string, message string, reason string, domain string) {
+	err := SendEmail(email, userFullName, title+" "+domain, emailtemplates.BuildApplicationDecisionEmailTemplate(userFullName, title, message, reason))
+	if err != nil {
+		fmt.Println("error sending email:", err.Error())
+	}
+}
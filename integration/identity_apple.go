@@ -0,0 +1,63 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	APPLE_ISSUER   = "https://appleid.apple.com"
+	APPLE_JWKS_URL = "https://appleid.apple.com/auth/keys"
+)
+
+type appleIdentityProvider struct {
+	verifier *idTokenVerifier
+}
+
+type appleClaims struct {
+	jwt.RegisteredClaims
+	Email string `json:"email"`
+	// apple sends "true" as a string on some platforms and a boolean on others
+	EmailVerified json.RawMessage `json:"email_verified"`
+	Nonce         string          `json:"nonce"`
+}
+
+// NewAppleIdentityProvider verifies Sign in with Apple ID tokens. clientIDs are
+// the bundle id of the ios app and the services id used on the web.
+func NewAppleIdentityProvider(httpClient *http.Client, clientIDs []string) IdentityProvider {
+	return &appleIdentityProvider{
+		verifier: &idTokenVerifier{
+			issuers:   []string{APPLE_ISSUER},
+			audiences: clientIDs,
+			keySet:    newRemoteKeySet(httpClient, APPLE_JWKS_URL),
+		},
+	}
+}
+
+func (provider *appleIdentityProvider) Name() string {
+	return PROVIDER_APPLE
+}
+
+// Verify never returns a name, apple only hands it to the client on the very first sign in
+func (provider *appleIdentityProvider) Verify(ctx context.Context, credential ProviderCredential) (*ExternalIdentity, error) {
+	claims := &appleClaims{}
+	err := provider.verifier.verify(ctx, credential.IDToken, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if credential.Nonce != "" && claims.Nonce != credential.Nonce {
+		return nil, ErrInvalidProviderCredential
+	}
+
+	emailVerified := string(claims.EmailVerified)
+	return &ExternalIdentity{
+		Provider:      PROVIDER_APPLE,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: emailVerified == "true" || emailVerified == `"true"`,
+	}, nil
+}
@@ -0,0 +1,107 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	FACEBOOK_GRAPH_URL = "https://graph.facebook.com/v19.0"
+)
+
+type facebookIdentityProvider struct {
+	httpClient *http.Client
+	appID      string
+	appSecret  string
+}
+
+// NewFacebookIdentityProvider verifies Facebook Login user access tokens through the Graph API
+func NewFacebookIdentityProvider(httpClient *http.Client, appID string, appSecret string) IdentityProvider {
+	return &facebookIdentityProvider{
+		httpClient: httpClient,
+		appID:      appID,
+		appSecret:  appSecret,
+	}
+}
+
+func (provider *facebookIdentityProvider) Name() string {
+	return PROVIDER_FACEBOOK
+}
+
+func (provider *facebookIdentityProvider) Verify(ctx context.Context, credential ProviderCredential) (*ExternalIdentity, error) {
+	if credential.AccessToken == "" {
+		return nil, ErrInvalidProviderCredential
+	}
+
+	// the token must have been issued to our app, not just be valid for some app
+	debug := struct {
+		Data struct {
+			AppID   string `json:"app_id"`
+			UserID  string `json:"user_id"`
+			IsValid bool   `json:"is_valid"`
+		} `json:"data"`
+	}{}
+	err := provider.get(ctx, "/debug_token", url.Values{
+		"input_token":  {credential.AccessToken},
+		"access_token": {provider.appID + "|" + provider.appSecret},
+	}, &debug)
+	if err != nil {
+		return nil, err
+	}
+
+	if !debug.Data.IsValid || debug.Data.AppID != provider.appID || debug.Data.UserID == "" {
+		return nil, ErrInvalidProviderCredential
+	}
+
+	profile := struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}{}
+	err = provider.get(ctx, "/me", url.Values{
+		"fields":       {"id,name,email"},
+		"access_token": {credential.AccessToken},
+	}, &profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile.ID != debug.Data.UserID {
+		return nil, ErrInvalidProviderCredential
+	}
+
+	// facebook only exposes an email address once the user confirmed it
+	return &ExternalIdentity{
+		Provider:      PROVIDER_FACEBOOK,
+		Subject:       profile.ID,
+		Email:         profile.Email,
+		EmailVerified: profile.Email != "",
+		Name:          profile.Name,
+	}, nil
+}
+
+func (provider *facebookIdentityProvider) get(ctx context.Context, path string, query url.Values, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, FACEBOOK_GRAPH_URL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := provider.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusBadRequest || res.StatusCode == http.StatusUnauthorized {
+		return ErrInvalidProviderCredential
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("facebook graph %s: status %d", path, res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(result)
+}
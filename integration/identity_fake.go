@@ -0,0 +1,34 @@
+package integration
+
+import (
+	"context"
+	"mini-wallet/utils"
+	"strings"
+)
+
+type fakeIdentityProvider struct{}
+
+// NewFakeIdentityProvider accepts any email address as the ID token, for local
+// development and end to end tests. Never registered outside development.
+func NewFakeIdentityProvider() IdentityProvider {
+	return &fakeIdentityProvider{}
+}
+
+func (provider *fakeIdentityProvider) Name() string {
+	return PROVIDER_FAKE
+}
+
+func (provider *fakeIdentityProvider) Verify(ctx context.Context, credential ProviderCredential) (*ExternalIdentity, error) {
+	email := strings.ToLower(strings.TrimSpace(credential.IDToken))
+	if utils.ValidateEmail(email) != nil {
+		return nil, ErrInvalidProviderCredential
+	}
+
+	return &ExternalIdentity{
+		Provider:      PROVIDER_FAKE,
+		Subject:       "fake-" + utils.HashToken(email)[:16],
+		Email:         email,
+		EmailVerified: true,
+		Name:          strings.Split(email, "@")[0],
+	}, nil
+}
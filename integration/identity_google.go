@@ -0,0 +1,60 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	GOOGLE_JWKS_URL = "https://www.googleapis.com/oauth2/v3/certs"
+)
+
+type googleIdentityProvider struct {
+	verifier *idTokenVerifier
+}
+
+type googleClaims struct {
+	jwt.RegisteredClaims
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Nonce         string `json:"nonce"`
+}
+
+// NewGoogleIdentityProvider verifies Google Sign-In ID tokens. clientIDs are the
+// OAuth client ids of every Sebia app (web, android, ios) a token may be issued to.
+func NewGoogleIdentityProvider(httpClient *http.Client, clientIDs []string) IdentityProvider {
+	return &googleIdentityProvider{
+		verifier: &idTokenVerifier{
+			issuers:   []string{"https://accounts.google.com", "accounts.google.com"},
+			audiences: clientIDs,
+			keySet:    newRemoteKeySet(httpClient, GOOGLE_JWKS_URL),
+		},
+	}
+}
+
+func (provider *googleIdentityProvider) Name() string {
+	return PROVIDER_GOOGLE
+}
+
+func (provider *googleIdentityProvider) Verify(ctx context.Context, credential ProviderCredential) (*ExternalIdentity, error) {
+	claims := &googleClaims{}
+	err := provider.verifier.verify(ctx, credential.IDToken, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if credential.Nonce != "" && claims.Nonce != credential.Nonce {
+		return nil, ErrInvalidProviderCredential
+	}
+
+	return &ExternalIdentity{
+		Provider:      PROVIDER_GOOGLE,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
@@ -0,0 +1,237 @@
+package integration
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"mini-wallet/utils"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	PROVIDER_GOOGLE   = "google"
+	PROVIDER_APPLE    = "apple"
+	PROVIDER_FACEBOOK = "facebook"
+	PROVIDER_FAKE     = "fake"
+
+	// remote key sets are refetched after this, or earlier when a token names an unknown kid
+	REMOTE_JWKS_LIFETIME       = time.Hour
+	REMOTE_JWKS_REFRESH_PERIOD = time.Minute
+)
+
+var (
+	ErrInvalidProviderCredential = errors.New("invalid provider credential")
+)
+
+// ExternalIdentity is what a provider vouches for once a credential is verified
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// ProviderCredential is what the client got from the provider SDK: an ID token
+// for Google and Apple, an access token for Facebook
+type ProviderCredential struct {
+	IDToken     string
+	AccessToken string
+	Nonce       string
+}
+
+type IdentityProvider interface {
+	Name() string
+	// Verify checks the credential with the provider, any rejection is ErrInvalidProviderCredential
+	Verify(ctx context.Context, credential ProviderCredential) (*ExternalIdentity, error)
+}
+
+// NewIdentityProviders returns the providers that are configured, keyed by name.
+// The fake provider is only available in development.
+func NewIdentityProviders(config *utils.AppConfig) map[string]IdentityProvider {
+	providers := map[string]IdentityProvider{}
+	httpClient := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	if config.GoogleClientIDs != "" {
+		providers[PROVIDER_GOOGLE] = NewGoogleIdentityProvider(httpClient, splitList(config.GoogleClientIDs))
+	}
+
+	if config.AppleClientIDs != "" {
+		providers[PROVIDER_APPLE] = NewAppleIdentityProvider(httpClient, splitList(config.AppleClientIDs))
+	}
+
+	if config.FacebookAppID != "" && config.FacebookAppSecret != "" {
+		providers[PROVIDER_FACEBOOK] = NewFacebookIdentityProvider(httpClient, config.FacebookAppID, config.FacebookAppSecret)
+	}
+
+	if config.AppEnvironment == "development" {
+		providers[PROVIDER_FAKE] = NewFakeIdentityProvider()
+	}
+
+	return providers
+}
+
+// idTokenVerifier checks OpenID Connect ID tokens against the issuer's published keys
+type idTokenVerifier struct {
+	issuers   []string
+	audiences []string
+	keySet    *remoteKeySet
+}
+
+func (verifier *idTokenVerifier) verify(ctx context.Context, idToken string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(
+		idToken,
+		claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return verifier.keySet.getKey(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return ErrInvalidProviderCredential
+	}
+
+	issuer, err := claims.GetIssuer()
+	if err != nil || !slices.Contains(verifier.issuers, issuer) {
+		return ErrInvalidProviderCredential
+	}
+
+	audiences, err := claims.GetAudience()
+	if err != nil {
+		return ErrInvalidProviderCredential
+	}
+
+	for _, audience := range audiences {
+		if slices.Contains(verifier.audiences, audience) {
+			return nil
+		}
+	}
+
+	return ErrInvalidProviderCredential
+}
+
+// remoteKeySet caches the RSA keys of a JWKS endpoint
+type remoteKeySet struct {
+	url        string
+	httpClient *http.Client
+
+	mutex     sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func newRemoteKeySet(httpClient *http.Client, url string) *remoteKeySet {
+	return &remoteKeySet{
+		url:        url,
+		httpClient: httpClient,
+		keys:       map[string]*rsa.PublicKey{},
+	}
+}
+
+func (keySet *remoteKeySet) getKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	keySet.mutex.Lock()
+	defer keySet.mutex.Unlock()
+
+	key, ok := keySet.keys[kid]
+	stale := time.Since(keySet.fetchedAt) > REMOTE_JWKS_LIFETIME
+	if ok && !stale {
+		return key, nil
+	}
+
+	// providers rotate keys, an unknown kid triggers a refetch but not more than once per period
+	if stale || time.Since(keySet.fetchedAt) > REMOTE_JWKS_REFRESH_PERIOD {
+		err := keySet.fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key, ok = keySet.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid: %s", kid)
+	}
+
+	return key, nil
+}
+
+func (keySet *remoteKeySet) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, keySet.url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := keySet.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching %s: status %d", keySet.url, res.StatusCode)
+	}
+
+	body := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		return err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range body.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	keySet.keys = keys
+	keySet.fetchedAt = time.Now()
+	return nil
+}
+
+func splitList(value string) []string {
+	res := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			res = append(res, item)
+		}
+	}
+
+	return res
+}
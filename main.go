@@ -2,14 +2,34 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"mini-wallet/presentation"
+	"net"
 	"net/http"
 )
 
 func main() {
-	router, port := presentation.InitServer()
+	server := presentation.InitServer()
 
-	if err := http.ListenAndServe(fmt.Sprintf(":%s", port), router); err == nil {
-		fmt.Println("server listening on port " + port)
+	// the first listener to fail stops the process
+	errs := make(chan error, 2)
+	if server.HttpPort != "" {
+		go func() {
+			errs <- http.ListenAndServe(fmt.Sprintf(":%s", server.HttpPort), server.Router)
+		}()
 	}
+
+	if server.GrpcPort != "" {
+		go func() {
+			listener, err := net.Listen("tcp", net.JoinHostPort(server.GrpcHost, server.GrpcPort))
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			errs <- server.GrpcServer.Serve(listener)
+		}()
+	}
+
+	log.Fatal(<-errs)
 }
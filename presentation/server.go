@@ -4,14 +4,20 @@ import (
 	"context"
 	"fmt"
 	"mini-wallet/app/affiliate"
+	"mini-wallet/app/audit"
 	"mini-wallet/app/auth"
 	"mini-wallet/app/booking"
 	"mini-wallet/app/business"
 	"mini-wallet/app/file"
 	"mini-wallet/app/inquiry"
+	"mini-wallet/app/moderation"
+	"mini-wallet/app/oauth"
+	"mini-wallet/app/privacy"
 	"mini-wallet/app/review"
 	"mini-wallet/app/seo"
+	"mini-wallet/app/serviceaccount"
 	"mini-wallet/utils"
+	"net"
 
 	"mini-wallet/app/location"
 	"mini-wallet/app/payment"
@@ -21,18 +27,30 @@ import (
 	"mini-wallet/app/user"
 
 	"mini-wallet/domain"
+	_auth "mini-wallet/domain/auth"
 
 	"mini-wallet/infrastructure"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"google.golang.org/grpc"
 )
 
 type Temporary struct {
 	Message string `json:"message"`
 }
 
-func InitServer() (chi.Router, string) {
+// Server is served by main, a listener is off when its port is empty
+type Server struct {
+	Router     chi.Router
+	HttpPort   string
+	GrpcServer *grpc.Server
+	// empty listens on every interface
+	GrpcHost string
+	GrpcPort string
+}
+
+func InitServer() Server {
 	ctx := context.Background()
 	router := chi.NewRouter()
 	router.Use(middleware.Logger)
@@ -44,9 +62,37 @@ func InitServer() (chi.Router, string) {
 		panic("error getting config")
 	}
 
+	// the caller IP is only taken from X-Forwarded-For behind TRUSTED_PROXIES
+	clientInfoMiddleware, err := auth.ClientInfoMiddleware(config)
+	if err != nil {
+		panic(err)
+	}
+
+	router.Use(clientInfoMiddleware)
+
 	// config := infrastructure.GetConfig()
 	//
 
+	keySet, err := loadTokenKeySet(config)
+	if err != nil {
+		panic(err)
+	}
+	_auth.SetKeySet(keySet)
+
+	if config.OIDCIssuer != "" {
+		_auth.SetIssuer(config.OIDCIssuer)
+	}
+
+	utils.SetArgon2Params(utils.Argon2Params{
+		MemoryKiB:   config.Argon2MemoryKiB,
+		Iterations:  config.Argon2Iterations,
+		Parallelism: config.Argon2Parallelism,
+	})
+	utils.SetPasswordPolicy(utils.PasswordPolicy{
+		MinLength: config.PasswordMinLength,
+		MinScore:  config.PasswordMinScore,
+	})
+
 	grpcConn, err := infrastructure.NewGrpcConn()
 	notificationService := integration.NewNotificationService(&grpcConn.NotificationService)
 
@@ -65,9 +111,18 @@ func InitServer() (chi.Router, string) {
 	repositories := domain.Repositories{
 		BaseRepository:           domain.NewBaseRepository(*mongoDb.Client()),
 		UserRepository:           user.NewUserRepository(repositoryParam),
+		SessionRepository:        auth.NewSessionRepository(repositoryParam),
+		PasskeyRepository:        auth.NewPasskeyRepository(repositoryParam),
+		OneTimeCodeRepository:    auth.NewOneTimeCodeRepository(repositoryParam),
+		KnownDeviceRepository:    auth.NewKnownDeviceRepository(repositoryParam),
+		OAuthRepository:          oauth.NewOAuthRepository(repositoryParam),
 		LocationRepository:       location.NewLocationRepository(repositoryParam),
 		BusinessRepository:       business.NewBusinessRepository(repositoryParam),
 		AffiliateRepository:      affiliate.NewAffiliatesRepository(repositoryParam),
+		ModerationRepository:     moderation.NewModerationRepository(repositoryParam),
+		AuditRepository:          audit.NewAuditRepository(repositoryParam),
+		PrivacyRepository:        privacy.NewPrivacyRepository(repositoryParam),
+		ServiceAccountRepository: serviceaccount.NewServiceAccountRepository(repositoryParam),
 		ServicesRepository:       services.NewServicesRepository(repositoryParam),
 		InquiryRepository:        inquiry.NewInquiryRepository(repositoryParam),
 		BookingRepository:        booking.NewBookingRepository(repositoryParam),
@@ -76,6 +131,38 @@ func InitServer() (chi.Router, string) {
 		SEORepository:            seo.NewSEORepository(repositoryParam),
 	}
 
+	if config.OAuthClientsPath != "" {
+		err = oauth.SyncClients(ctx, repositories.OAuthRepository, config.OAuthClientsPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	err = moderation.SyncApprovedRoles(ctx, repositories)
+	if err != nil {
+		panic(err)
+	}
+
+	err = repositories.UserRepository.EnsureExpiryIndexes(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	err = repositories.UserRepository.EnsureUniqueIndexes(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	now, err := utils.GetJktTime()
+	if err != nil {
+		panic(err)
+	}
+
+	err = repositories.OneTimeCodeRepository.EnsureExpiryIndex(ctx, now.Unix())
+	if err != nil {
+		panic(err)
+	}
+
 	s3, err := infrastructure.NewS3Service()
 	if err != nil {
 		panic(err.Error())
@@ -89,23 +176,33 @@ func InitServer() (chi.Router, string) {
 		NotificationService: notificationService,
 		PaymentService:      infrastructure.NewPayment(snapClient, config.MidtransServerKey),
 		MesageProducer:      messagingProducer,
+		Cache:               newCache(ctx, config),
+		IdentityProviders:   integration.NewIdentityProviders(config),
 	}
 
 	usecases := domain.Usecases{
-		AuthUsecase:      auth.NewAuthUsecase(repositories, infra, config),
-		FileUsecase:      file.NewFileUsecase(infra),
-		LocationUsecase:  location.NewLocationUsecase(repositories),
-		BusinessUsecase:  business.NewBusinessUsecase(repositories),
-		AffiliateUsecase: affiliate.NewAffiliatesUsecase(repositories),
-		ServicesUsecase:  services.NewServicesUsecase(repositories),
-		InquiryUsecase:   inquiry.NewInquiryUsecase(repositories, infra),
-		PaymentUsecase:   payment.NewPaymentUsecase(repositories, infra, config),
-		BookingUsecase:   booking.NewBookingUsecase(repositories, infra),
-		ReviewUsecase:    review.NewReviewUsecase(repositories),
-		SEOUsecase:       seo.NewSEOUsecase(repositories),
+		AuthUsecase:           auth.NewAuthUsecase(repositories, infra, config),
+		UserUsecase:           user.NewUserUsecase(repositories, infra, config),
+		OAuthUsecase:          oauth.NewOAuthUsecase(repositories, config),
+		FileUsecase:           file.NewFileUsecase(infra),
+		LocationUsecase:       location.NewLocationUsecase(repositories),
+		BusinessUsecase:       business.NewBusinessUsecase(repositories),
+		AffiliateUsecase:      affiliate.NewAffiliatesUsecase(repositories),
+		ModerationUsecase:     moderation.NewModerationUsecase(repositories, infra, config),
+		AuditUsecase:          audit.NewAuditUsecase(repositories),
+		PrivacyUsecase:        privacy.NewPrivacyUsecase(repositories),
+		ServiceAccountUsecase: serviceaccount.NewServiceAccountUsecase(repositories),
+		ServicesUsecase:       services.NewServicesUsecase(repositories),
+		InquiryUsecase:        inquiry.NewInquiryUsecase(repositories, infra),
+		PaymentUsecase:        payment.NewPaymentUsecase(repositories, infra, config),
+		BookingUsecase:        booking.NewBookingUsecase(repositories, infra),
+		ReviewUsecase:         review.NewReviewUsecase(repositories),
+		SEOUsecase:            seo.NewSEOUsecase(repositories),
 	}
 
 	middlewares := auth.NewAuthMiddleware(repositories, config)
+	router.Use(middlewares.CsrfMiddleware)
+	router.Use(middlewares.DeviceMiddleware)
 
 	// messaging
 	go infrastructure.RegisterConsumers([]infrastructure.RegisterListenersParam{
@@ -116,22 +213,74 @@ func InitServer() (chi.Router, string) {
 		},
 	})
 
+	// accounts are deleted once their grace period is over
+	go privacy.StartDeletionWorker(usecases)
+
 	// in terms of authorization, a token should not be a forever-lived value
 	// provided a /refresh endpoint to get fresh token
 	auth.SetAuthHandler(router, usecases, middlewares, config)
+	user.SetUserHandler(router, usecases, middlewares)
+	oauth.SetOAuthHandler(router, usecases, middlewares)
 	file.SetFileHandler(router, usecases)
 	location.SetLocationHandler(router, usecases)
 	business.SetBusinessHandler(router, usecases, middlewares)
 	affiliate.SetAffiliatesHandler(router, usecases, middlewares)
+	moderation.SetModerationHandler(router, usecases, middlewares)
+	audit.SetAuditHandler(router, usecases, middlewares)
+	privacy.SetPrivacyHandler(router, usecases, middlewares)
+	serviceaccount.SetServiceAccountHandler(router, usecases, middlewares)
 	services.SetServicesHandler(router, usecases, middlewares)
 	inquiry.SetInquiryHandler(router, usecases, middlewares)
 	payment.SetPaymentHandler(router, usecases)
 	review.SetReviewHandler(router, usecases, middlewares)
 	seo.SetSeoHandler(router, usecases)
 
-	fmt.Println("[" + config.AppEnvironment + "] server listening on port " + config.AppPort)
+	// internal services
+	if config.GrpcPort != "" && config.GrpcAuthToken == "" {
+		panic("GRPC_AUTH_TOKEN is required to serve gRPC")
+	}
+
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(auth.GrpcAuthInterceptor(config.GrpcAuthToken)))
+	auth.SetAuthGrpcServer(grpcServer, usecases)
+
+	if config.AppPort == "" && config.GrpcPort == "" {
+		panic("APP_PORT and GRPC_PORT are both empty, nothing to serve")
+	}
+
+	if config.AppPort != "" {
+		fmt.Println("[" + config.AppEnvironment + "] server listening on port " + config.AppPort)
+	}
+
+	if config.GrpcPort != "" {
+		fmt.Println("[" + config.AppEnvironment + "] grpc server listening on " + net.JoinHostPort(config.GrpcHost, config.GrpcPort))
+	}
+
+	return Server{
+		Router:     router,
+		HttpPort:   config.AppPort,
+		GrpcServer: grpcServer,
+		GrpcHost:   config.GrpcHost,
+		GrpcPort:   config.GrpcPort,
+	}
+}
+
+func loadTokenKeySet(config *utils.AppConfig) (*_auth.KeySet, error) {
+	if config.JwtKeysDir == "" && config.AppEnvironment == "development" {
+		fmt.Println("JWT_KEYS_DIR is not set, signing tokens with an ephemeral key")
+		return _auth.NewEphemeralKeySet("dev-" + utils.GenerateUniqueId())
+	}
+
+	return _auth.LoadKeySet(config.JwtKeysDir, config.JwtActiveKeyID)
+}
+
+// newCache keeps development runnable without a redis instance
+func newCache(ctx context.Context, config *utils.AppConfig) infrastructure.Cache {
+	if config.AppEnvironment == "development" {
+		fmt.Println("using in-memory cache")
+		return infrastructure.NewMemoryCache()
+	}
 
-	return router, config.AppPort
+	return infrastructure.NewCache(infrastructure.NewRedisClient(ctx, infrastructure.GetConfig()))
 }
 
 func StopServer() {
@@ -3,15 +3,68 @@ package utils
 import "github.com/spf13/viper"
 
 type AppConfig struct {
-	AppPort               string `mapstructure:"APP_PORT"`
-	AppEnvironment        string `mapstructure:"APP_ENV"`
-	AppDomain             string `mapstructure:"APP_DOMAIN"`
-	AccessTokenKey        string `mapstructure:"ACCESS_TOKEN_KEY"`
-	RefreshTokenKey       string `mapstructure:"REFRESH_TOKEN_KEY"`
-	DatabaseName          string `mapstructure:"DATABASE_NAME"`
-	BookingTopic          string `mapstructure:"BOOKING_TOPIC"`
-	GoogleCredentialsPath string `mapstructure:"GOOGLE_CREDENTIALS_PATH"`
-	MidtransServerKey     string `mapstructure:"MIDTRANS_SERVER_KEY"`
+	// listeners, one is off when its port is empty. The gRPC AuthService listens on
+	// GRPC_HOST (all interfaces when empty) and callers must send GRPC_AUTH_TOKEN as
+	// a bearer token in the authorization metadata, it is required with GRPC_PORT.
+	AppPort           string `mapstructure:"APP_PORT"`
+	GrpcPort          string `mapstructure:"GRPC_PORT"`
+	GrpcHost          string `mapstructure:"GRPC_HOST"`
+	GrpcAuthToken     string `mapstructure:"GRPC_AUTH_TOKEN"`
+	AppEnvironment    string `mapstructure:"APP_ENV"`
+	AppDomain         string `mapstructure:"APP_DOMAIN"`
+	AccessTokenKey    string `mapstructure:"ACCESS_TOKEN_KEY"`
+	RefreshTokenKey   string `mapstructure:"REFRESH_TOKEN_KEY"`
+	DatabaseName      string `mapstructure:"DATABASE_NAME"`
+	BookingTopic      string `mapstructure:"BOOKING_TOPIC"`
+	MidtransServerKey string `mapstructure:"MIDTRANS_SERVER_KEY"`
+
+	// session cookies. An empty COOKIE_DOMAIN is a host only cookie, COOKIE_SAME_SITE is
+	// lax (default), strict or none. COOKIE_INSECURE drops the Secure flag for local
+	// development over http, COOKIE_PREFIX (e.g. __Host-) goes in front of every name.
+	CookieDomain   string `mapstructure:"COOKIE_DOMAIN"`
+	CookieSameSite string `mapstructure:"COOKIE_SAME_SITE"`
+	CookieInsecure bool   `mapstructure:"COOKIE_INSECURE"`
+	CookiePrefix   string `mapstructure:"COOKIE_PREFIX"`
+
+	// CSRF, origins allowed to send cookie authenticated requests (default
+	// https://APP_DOMAIN) and paths never checked (default /payments/callback),
+	// both comma separated. Bearer requests are never checked.
+	CsrfTrustedOrigins string `mapstructure:"CSRF_TRUSTED_ORIGINS"`
+	CsrfExemptPaths    string `mapstructure:"CSRF_EXEMPT_PATHS"`
+
+	// reverse proxies whose X-Forwarded-For is believed, comma separated IPs or
+	// CIDRs. Empty trusts none and the caller IP is the address of the connection.
+	TrustedProxies string `mapstructure:"TRUSTED_PROXIES"`
+
+	// token signing, one <kid>.pem per key, see auth.LoadKeySet
+	JwtKeysDir     string `mapstructure:"JWT_KEYS_DIR"`
+	JwtActiveKeyID string `mapstructure:"JWT_ACTIVE_KEY_ID"`
+
+	// passkeys, default to APP_DOMAIN and https://APP_DOMAIN. Origins are comma separated.
+	WebAuthnRPID      string `mapstructure:"WEBAUTHN_RP_ID"`
+	WebAuthnRPOrigins string `mapstructure:"WEBAUTHN_RP_ORIGINS"`
+
+	// OpenID Connect provider. The issuer is the public base url of this service,
+	// the login & consent pages default to https://APP_DOMAIN/login and /oauth/consent
+	OIDCIssuer       string `mapstructure:"OIDC_ISSUER"`
+	OAuthClientsPath string `mapstructure:"OAUTH_CLIENTS_PATH"`
+	OAuthLoginURL    string `mapstructure:"OAUTH_LOGIN_URL"`
+	OAuthConsentURL  string `mapstructure:"OAUTH_CONSENT_URL"`
+
+	// external identity providers, a provider is enabled once configured.
+	// Client ids are comma separated, one per app the tokens are issued to.
+	GoogleClientIDs   string `mapstructure:"GOOGLE_CLIENT_IDS"`
+	AppleClientIDs    string `mapstructure:"APPLE_CLIENT_IDS"`
+	FacebookAppID     string `mapstructure:"FACEBOOK_APP_ID"`
+	FacebookAppSecret string `mapstructure:"FACEBOOK_APP_SECRET"`
+
+	// passwords, unset values keep the defaults of utils.PasswordPolicy and utils.Argon2Params.
+	// Changing the argon2id parameters rehashes each password on its next successful sign in.
+	PasswordMinLength int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMinScore  int    `mapstructure:"PASSWORD_MIN_SCORE"`
+	Argon2MemoryKiB   uint32 `mapstructure:"ARGON2_MEMORY_KIB"`
+	Argon2Iterations  uint32 `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism uint8  `mapstructure:"ARGON2_PARALLELISM"`
 }
 
 func GetConfig() (config *AppConfig, err error) {
@@ -1,8 +1,13 @@
 package utils
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"strings"
 )
 
 func GenerateSalt(size int) (string, error) {
@@ -25,3 +30,31 @@ func GenerateRandomString(n int) (string, error) {
 	// Encode the bytes to a URL-safe base64 string
 	return base64.URLEncoding.EncodeToString(b)[:n], nil
 }
+
+// HashToken returns the hex sha256 of a random token, for storing one-time secrets
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SignToken returns the hex HMAC-SHA256 of message under secret
+func SignToken(secret string, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateNumericCode returns a uniformly random code of n digits, leading zeros included
+func GenerateNumericCode(n int) (string, error) {
+	var code strings.Builder
+	for i := 0; i < n; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+
+		code.WriteString(digit.String())
+	}
+
+	return code.String(), nil
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PASSWORD_SCHEME_ARGON2ID = "argon2id"
+
+	ARGON2_SALT_LENGTH = 16
+	ARGON2_KEY_LENGTH  = 32
+)
+
+var (
+	ErrPasswordMismatch      = errors.New("password mismatch")
+	ErrUnknownPasswordHash   = errors.New("unknown password hash scheme")
+	ErrMalformedPasswordHash = errors.New("malformed password hash")
+)
+
+// Argon2Params are the cost parameters of new hashes. Hashes made with other
+// parameters still verify and are flagged for rehashing.
+type Argon2Params struct {
+	MemoryKiB   uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+var (
+	argon2Params = Argon2Params{
+		MemoryKiB:   64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+	}
+	argon2ParamsMutex sync.RWMutex
+)
+
+// SetArgon2Params replaces the parameters used by HashPassword, zero values keep the default
+func SetArgon2Params(params Argon2Params) {
+	argon2ParamsMutex.Lock()
+	defer argon2ParamsMutex.Unlock()
+
+	if params.MemoryKiB != 0 {
+		argon2Params.MemoryKiB = params.MemoryKiB
+	}
+
+	if params.Iterations != 0 {
+		argon2Params.Iterations = params.Iterations
+	}
+
+	if params.Parallelism != 0 {
+		argon2Params.Parallelism = params.Parallelism
+	}
+}
+
+func getArgon2Params() Argon2Params {
+	argon2ParamsMutex.RLock()
+	defer argon2ParamsMutex.RUnlock()
+
+	return argon2Params
+}
+
+// HashPassword returns an argon2id hash in the PHC string format,
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+func HashPassword(password string) (string, error) {
+	params := getArgon2Params()
+
+	salt := make([]byte, ARGON2_SALT_LENGTH)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	hash := argon2.IDKey([]byte(password), salt, params.Iterations, params.MemoryKiB, params.Parallelism, ARGON2_KEY_LENGTH)
+
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		PASSWORD_SCHEME_ARGON2ID,
+		argon2.Version,
+		params.MemoryKiB,
+		params.Iterations,
+		params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
+}
+
+// VerifyPassword checks password against a stored hash of any known scheme.
+// legacySalt is the separate salt of the former bcrypt(password + salt) hashes.
+// needsRehash tells the caller to store a fresh HashPassword once it matched.
+func VerifyPassword(password string, encodedHash string, legacySalt *string) (needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encodedHash, "$"+PASSWORD_SCHEME_ARGON2ID+"$"):
+		return verifyArgon2id(password, encodedHash)
+	case strings.HasPrefix(encodedHash, "$2"):
+		salt := ""
+		if legacySalt != nil {
+			salt = *legacySalt
+		}
+
+		err = bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password+salt))
+		if err != nil {
+			return false, ErrPasswordMismatch
+		}
+
+		return true, nil
+	default:
+		return false, ErrUnknownPasswordHash
+	}
+}
+
+func verifyArgon2id(password string, encodedHash string) (needsRehash bool, err error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 {
+		return false, ErrMalformedPasswordHash
+	}
+
+	var version int
+	_, err = fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return false, ErrMalformedPasswordHash
+	}
+
+	params := Argon2Params{}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.MemoryKiB, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return false, ErrMalformedPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrMalformedPasswordHash
+	}
+
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, ErrMalformedPasswordHash
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.MemoryKiB, params.Parallelism, uint32(len(hash)))
+	if subtle.ConstantTimeCompare(hash, computed) != 1 {
+		return false, ErrPasswordMismatch
+	}
+
+	return params != getArgon2Params(), nil
+}
@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+var commonPasswords = loadCommonPasswords()
+
+// PasswordPolicy is checked whenever a password is set, never on sign in.
+// MinScore is on a 0 (trivial) to 4 (strong) scale, see EstimatePasswordScore.
+type PasswordPolicy struct {
+	MinLength int
+	MinScore  int
+}
+
+var (
+	passwordPolicy = PasswordPolicy{
+		MinLength: 8,
+		MinScore:  2,
+	}
+	passwordPolicyMutex sync.RWMutex
+)
+
+// SetPasswordPolicy replaces the policy used by CheckPasswordPolicy, zero values keep the default
+func SetPasswordPolicy(policy PasswordPolicy) {
+	passwordPolicyMutex.Lock()
+	defer passwordPolicyMutex.Unlock()
+
+	if policy.MinLength != 0 {
+		passwordPolicy.MinLength = policy.MinLength
+	}
+
+	if policy.MinScore != 0 {
+		passwordPolicy.MinScore = policy.MinScore
+	}
+}
+
+func getPasswordPolicy() PasswordPolicy {
+	passwordPolicyMutex.RLock()
+	defer passwordPolicyMutex.RUnlock()
+
+	return passwordPolicy
+}
+
+// CheckPasswordPolicy validates a new password. userInputs are the name, email
+// and phone number of its owner, none of them may appear in the password.
+func CheckPasswordPolicy(password string, userInputs ...string) error {
+	policy := getPasswordPolicy()
+
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("Kata sandi minimal %d karakter", policy.MinLength)
+	}
+
+	lowerPassword := strings.ToLower(password)
+	for _, input := range userInputTokens(userInputs) {
+		if strings.Contains(lowerPassword, input) {
+			return fmt.Errorf("Kata sandi tidak boleh mengandung nama, email atau nomor handphone Anda")
+		}
+	}
+
+	if IsCommonPassword(password) {
+		return fmt.Errorf("Kata sandi terlalu umum atau pernah bocor, gunakan kata sandi lain")
+	}
+
+	if EstimatePasswordScore(password) < policy.MinScore {
+		return fmt.Errorf("Kata sandi terlalu lemah, gunakan kombinasi huruf besar, huruf kecil, angka dan simbol")
+	}
+
+	return nil
+}
+
+// IsCommonPassword looks the password up in the embedded list, also once its
+// trailing digits and symbols are removed ("Password123!" is "password")
+func IsCommonPassword(password string) bool {
+	lowerPassword := strings.ToLower(strings.TrimSpace(password))
+	if _, ok := commonPasswords[lowerPassword]; ok {
+		return true
+	}
+
+	base := strings.TrimRightFunc(lowerPassword, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(base) < 4 {
+		return false
+	}
+
+	_, ok := commonPasswords[base]
+	return ok
+}
+
+// EstimatePasswordScore maps the estimated entropy to 0..4. Repeated and
+// sequential characters ("aaaa", "abcd", "1234") only count for a quarter.
+func EstimatePasswordScore(password string) int {
+	var hasLower, hasUpper, hasDigit, hasSymbol, hasOther bool
+	for _, r := range password {
+		switch {
+		case r > unicode.MaxASCII:
+			hasOther = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+
+	charsetSize := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{
+		{hasLower, 26},
+		{hasUpper, 26},
+		{hasDigit, 10},
+		{hasSymbol, 33},
+		{hasOther, 100},
+	} {
+		if class.present {
+			charsetSize += class.size
+		}
+	}
+
+	if charsetSize == 0 {
+		return 0
+	}
+
+	effectiveLength := 0.0
+	runes := []rune(password)
+	for i, r := range runes {
+		if i > 0 && (r == runes[i-1] || r == runes[i-1]+1 || r == runes[i-1]-1) {
+			effectiveLength += 0.25
+			continue
+		}
+
+		effectiveLength++
+	}
+
+	bits := effectiveLength * math.Log2(float64(charsetSize))
+	switch {
+	case bits < 28:
+		return 0
+	case bits < 36:
+		return 1
+	case bits < 50:
+		return 2
+	case bits < 64:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// userInputTokens splits names and emails into the parts worth checking
+func userInputTokens(userInputs []string) []string {
+	tokens := []string{}
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if local, _, found := strings.Cut(input, "@"); found {
+			input = local
+		}
+
+		for _, token := range strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len(token) >= 3 {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+
+	return tokens
+}
+
+func loadCommonPasswords() map[string]struct{} {
+	passwords := map[string]struct{}{}
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswordsFile))
+	for scanner.Scan() {
+		password := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if password != "" {
+			passwords[password] = struct{}{}
+		}
+	}
+
+	return passwords
+}